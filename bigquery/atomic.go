@@ -0,0 +1,135 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/bigquery"
+
+	"ddbt/compilerInterface"
+	"ddbt/config"
+	"ddbt/fs"
+)
+
+// A Validator checks the output of a model, which has been built into the given relation, before it replaces
+// the model's table
+type Validator func(ctx context.Context, f *fs.File, relation string) error
+
+// The suffix of the temporary table atomic models are built into
+const atomicTableSuffix = "__dbt_tmp"
+
+// Should the model be built into a temporary table and swapped in once it has been validated
+func isAtomic(f *fs.File) bool {
+	atomic := f.GetConfig("atomic")
+	return atomic.Type() == compilerInterface.BooleanValue && atomic.BooleanValue
+}
+
+// The minimum number of rows an atomic build must have, from the `min_rows` config
+func atomicMinRows(f *fs.File) (uint64, error) {
+	value := f.GetConfig("min_rows")
+
+	switch value.Type() {
+	case compilerInterface.Undefined, compilerInterface.NullVal:
+		return 0, nil
+
+	case compilerInterface.NumberVal:
+		if value.NumberValue < 0 {
+			return 0, fmt.Errorf("min_rows for model %s must not be negative, got %v", f.Name, value.NumberValue)
+		}
+
+		return uint64(value.NumberValue), nil
+
+	default:
+		return 0, fmt.Errorf("min_rows for model %s must be a number, got %s", f.Name, value.Type())
+	}
+}
+
+// Builds the model into a temporary table, validates it and then copies it over the model's table so
+// readers never see a partially written table. The temporary table is always removed afterwards
+func runAtomic(ctx context.Context, client *bigquery.Client, f *fs.File, query string, target *config.Target, validate Validator) error {
+	minRows, err := atomicMinRows(f)
+	if err != nil {
+		return err
+	}
+
+	dataset := client.DatasetInProject(target.ProjectID, target.DataSet)
+	tmpName := f.Name + atomicTableSuffix
+	tmp := dataset.Table(tmpName)
+
+	defer func() {
+		// Use a fresh context, so we clean up even if the build was cancelled
+		if err := tmp.Delete(context.Background()); err != nil && !isErrTableNotFound(err) {
+			fmt.Printf("⚠️ Unable to remove temporary table %s: %s\n", tmpName, err)
+		}
+	}()
+
+	q := client.Query(query)
+	q.Location = target.Location
+
+	// Default read information
+	q.DefaultProjectID = target.ProjectID
+	q.DefaultDatasetID = target.DataSet
+	q.DisableQueryCache = true
+
+	// Output write information
+	q.Dst = tmp
+	q.CreateDisposition = bigquery.CreateIfNeeded
+	q.WriteDisposition = bigquery.WriteTruncate
+
+	if err := waitForJob(ctx, q.Run); err != nil {
+		if err == context.Canceled {
+			return err
+		}
+		return fmt.Errorf("Unable to build model %s into %s: %s", f.Name, tmpName, err)
+	}
+
+	metadata, err := tmp.Metadata(ctx)
+	if err != nil {
+		return fmt.Errorf("Cannot get table metadata %s: %s", tmpName, err)
+	}
+
+	if metadata.NumRows < minRows {
+		return fmt.Errorf("Model %s built %d rows, expected at least %d; the existing table has been left unchanged", f.Name, metadata.NumRows, minRows)
+	}
+
+	if validate != nil {
+		relation := "`" + target.ProjectID + "`.`" + target.DataSet + "`.`" + tmpName + "`"
+
+		if err := validate(ctx, f, relation); err != nil {
+			return fmt.Errorf("Model %s failed validation, the existing table has been left unchanged:\n%s", f.Name, err)
+		}
+	}
+
+	copier := dataset.Table(f.Name).CopierFrom(tmp)
+	copier.Location = target.Location
+	copier.CreateDisposition = bigquery.CreateIfNeeded
+	copier.WriteDisposition = bigquery.WriteTruncate
+
+	if err := waitForJob(ctx, copier.Run); err != nil {
+		if err == context.Canceled {
+			return err
+		}
+		return fmt.Errorf("Unable to copy %s over model %s: %s", tmpName, f.Name, err)
+	}
+
+	return nil
+}
+
+// Starts a job and waits for it to complete
+func waitForJob(ctx context.Context, run func(ctx context.Context) (*bigquery.Job, error)) error {
+	job, err := run(ctx)
+	if err != nil {
+		return err
+	}
+
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	if status.State != bigquery.Done {
+		return fmt.Errorf("job %s in state %d", job.ID(), status.State)
+	}
+
+	return status.Err()
+}
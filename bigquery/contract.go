@@ -0,0 +1,201 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+
+	"ddbt/compilerInterface"
+	"ddbt/config"
+	"ddbt/fs"
+	"ddbt/properties"
+)
+
+// Aliases for the data types BigQuery reports in a schema
+var dataTypeAliases = map[string]string{
+	"INT64":      "INTEGER",
+	"INT":        "INTEGER",
+	"SMALLINT":   "INTEGER",
+	"BIGINT":     "INTEGER",
+	"TINYINT":    "INTEGER",
+	"BYTEINT":    "INTEGER",
+	"FLOAT64":    "FLOAT",
+	"BOOL":       "BOOLEAN",
+	"DECIMAL":    "NUMERIC",
+	"BIGDECIMAL": "BIGNUMERIC",
+	"STRUCT":     "RECORD",
+}
+
+// The SQL names of the data types which have a different name in a table's schema
+var sqlDataTypes = map[string]string{
+	"INTEGER": "INT64",
+	"FLOAT":   "FLOAT64",
+	"BOOLEAN": "BOOL",
+}
+
+// Is the contract of this model enforced, either via `config(contract={'enforced': true})` or the schema
+func isContractEnforced(f *fs.File) bool {
+	if contract := f.GetConfig("contract"); contract.Type() == compilerInterface.MapVal {
+		if enforced, found := contract.MapValue["enforced"]; found {
+			return enforced.Unwrap().Type() == compilerInterface.BooleanValue && enforced.Unwrap().BooleanValue
+		}
+	}
+
+	return f.Schema != nil && f.Schema.ContractEnforced()
+}
+
+// Checks the query will output the columns declared in the schema of the model, returning an error
+// describing every difference if not
+func checkContract(ctx context.Context, f *fs.File, query string, target *config.Target) error {
+	if f.Schema == nil || len(f.Schema.Columns) == 0 {
+		return fmt.Errorf("Model %s has an enforced contract, but no columns declared in its schema", f.Name)
+	}
+
+	schema, err := DryRunSchema(ctx, query, target)
+	if err != nil {
+		return fmt.Errorf("Unable to check contract of model %s: %s", f.Name, err)
+	}
+
+	if differences := diffContract(f.Schema.Columns, schema); len(differences) > 0 {
+		return fmt.Errorf("Model %s does not match its contract:\n\t- %s", f.Name, strings.Join(differences, "\n\t- "))
+	}
+
+	return nil
+}
+
+// DryRunSchema returns the schema the query would output, without running it
+func DryRunSchema(ctx context.Context, query string, target *config.Target) (Schema, error) {
+	switch {
+	case target.ProjectID == "":
+		return nil, errors.New("no project ID defined to run query against")
+	case target.DataSet == "":
+		return nil, errors.New("no dataset defined to run query against")
+	}
+
+	client, err := GetClientFor(target.RandExecutionProject())
+	if err != nil {
+		return nil, err
+	}
+
+	q := client.Query(query)
+	q.Location = target.Location
+	q.DryRun = true
+
+	// Default read information
+	q.DefaultProjectID = target.ProjectID
+	q.DefaultDatasetID = target.DataSet
+
+	job, err := q.Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to dry run query: %s", err)
+	}
+
+	status := job.LastStatus()
+	if status == nil || status.Statistics == nil {
+		return nil, errors.New("Dry run did not return any statistics")
+	}
+
+	if err := status.Err(); err != nil {
+		return nil, fmt.Errorf("Dry run result in an error: %s", err)
+	}
+
+	stats, ok := status.Statistics.Details.(*bigquery.QueryStatistics)
+	if !ok {
+		return nil, errors.New("Dry run did not return query statistics")
+	}
+
+	return stats.Schema, nil
+}
+
+// Lists the differences between the declared columns and the schema
+func diffContract(columns properties.Columns, schema Schema) []string {
+	differences := make([]string, 0)
+
+	fields := make(map[string]*bigquery.FieldSchema, len(schema))
+	for _, field := range schema {
+		fields[strings.ToLower(field.Name)] = field
+	}
+
+	declared := make(map[string]struct{}, len(columns))
+	for _, column := range columns {
+		declared[strings.ToLower(column.Name)] = struct{}{}
+
+		field, found := fields[strings.ToLower(column.Name)]
+		if !found {
+			differences = append(differences, fmt.Sprintf("missing column `%s`", column.Name))
+			continue
+		}
+
+		if column.DataType == "" {
+			differences = append(differences, fmt.Sprintf("column `%s` has no data_type declared", column.Name))
+			continue
+		}
+
+		if expected, actual := normaliseDataType(column.DataType), fieldDataType(field); expected != actual {
+			differences = append(differences, fmt.Sprintf("column `%s` has type %s, expected %s", column.Name, actual, expected))
+		}
+	}
+
+	for _, field := range schema {
+		if _, found := declared[strings.ToLower(field.Name)]; !found {
+			differences = append(differences, fmt.Sprintf("unexpected column `%s` of type %s", field.Name, fieldDataType(field)))
+		}
+	}
+
+	return differences
+}
+
+// ColumnTypes returns the SQL type to cast a value to for each column of the schema, keyed by the lower case
+// column name; repeated and RECORD columns are left out, as they can't be cast to
+func ColumnTypes(schema Schema) map[string]string {
+	types := make(map[string]string, len(schema))
+
+	for _, field := range schema {
+		if field.Repeated || field.Type == bigquery.RecordFieldType {
+			continue
+		}
+
+		dataType := string(field.Type)
+		if sqlType, found := sqlDataTypes[dataType]; found {
+			dataType = sqlType
+		}
+
+		types[strings.ToLower(field.Name)] = dataType
+	}
+
+	return types
+}
+
+// The data type of a field in the same form as normaliseDataType
+func fieldDataType(field *bigquery.FieldSchema) string {
+	dataType := normaliseDataType(string(field.Type))
+
+	if field.Repeated {
+		return "ARRAY<" + dataType + ">"
+	}
+
+	return dataType
+}
+
+// Converts a declared data type into the name BigQuery uses for it in a schema, ignoring
+// any parameters (such as the precision of a NUMERIC) or the fields of a STRUCT
+func normaliseDataType(dataType string) string {
+	dataType = strings.ToUpper(strings.TrimSpace(dataType))
+
+	if strings.HasPrefix(dataType, "ARRAY<") && strings.HasSuffix(dataType, ">") {
+		return "ARRAY<" + normaliseDataType(dataType[len("ARRAY<"):len(dataType)-1]) + ">"
+	}
+
+	if i := strings.IndexAny(dataType, "<("); i >= 0 {
+		dataType = strings.TrimSpace(dataType[:i])
+	}
+
+	if alias, found := dataTypeAliases[dataType]; found {
+		return alias
+	}
+
+	return dataType
+}
@@ -0,0 +1,81 @@
+package bigquery
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+
+	"ddbt/properties"
+)
+
+func TestNormaliseDataType(t *testing.T) {
+	cases := map[string]string{
+		"int64":                     "INTEGER",
+		"STRING":                    "STRING",
+		"numeric(10, 2)":            "NUMERIC",
+		"bool":                      "BOOLEAN",
+		"STRUCT<a INT64, b STRING>": "RECORD",
+		"array<int64>":              "ARRAY<INTEGER>",
+		"ARRAY<STRUCT<a INT64>>":    "ARRAY<RECORD>",
+		" timestamp ":               "TIMESTAMP",
+	}
+
+	for declared, expected := range cases {
+		assert.Equal(t, expected, normaliseDataType(declared), declared)
+	}
+}
+
+func TestDiffContract(t *testing.T) {
+	columns := properties.Columns{
+		{Name: "id", DataType: "int64"},
+		{Name: "name", DataType: "string"},
+		{Name: "tags", DataType: "array<string>"},
+		{Name: "created_at", DataType: "timestamp"},
+	}
+
+	schema := Schema{
+		{Name: "ID", Type: bigquery.IntegerFieldType},
+		{Name: "name", Type: bigquery.StringFieldType},
+		{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+		{Name: "created_at", Type: bigquery.TimestampFieldType},
+	}
+
+	assert.Empty(t, diffContract(columns, schema))
+
+	schema = Schema{
+		{Name: "id", Type: bigquery.StringFieldType},
+		{Name: "tags", Type: bigquery.StringFieldType},
+		{Name: "created_at", Type: bigquery.TimestampFieldType},
+		{Name: "extra", Type: bigquery.BooleanFieldType},
+	}
+
+	assert.Equal(
+		t,
+		[]string{
+			"column `id` has type STRING, expected INTEGER",
+			"missing column `name`",
+			"column `tags` has type STRING, expected ARRAY<STRING>",
+			"unexpected column `extra` of type BOOLEAN",
+		},
+		diffContract(columns, schema),
+	)
+}
+
+func TestColumnTypes(t *testing.T) {
+	types := ColumnTypes(Schema{
+		{Name: "ID", Type: bigquery.IntegerFieldType},
+		{Name: "day", Type: bigquery.DateFieldType},
+		{Name: "amount", Type: bigquery.NumericFieldType},
+		{Name: "enabled", Type: bigquery.BooleanFieldType},
+		{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+		{Name: "nested", Type: bigquery.RecordFieldType},
+	})
+
+	assert.Equal(t, map[string]string{
+		"id":      "INT64",
+		"day":     "DATE",
+		"amount":  "NUMERIC",
+		"enabled": "BOOL",
+	}, types)
+}
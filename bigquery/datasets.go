@@ -0,0 +1,135 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+
+	"ddbt/config"
+)
+
+// DatasetExists checks if the target's dataset exists
+func DatasetExists(ctx context.Context, target *config.Target) (bool, error) {
+	client, err := GetClientFor(target.RandExecutionProject())
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := client.DatasetInProject(target.ProjectID, target.DataSet).Metadata(ctx); err != nil {
+		if isErrTableNotFound(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("Unable to get dataset metadata %s.%s: %s", target.ProjectID, target.DataSet, err)
+	}
+
+	return true, nil
+}
+
+// CreateDatasetIfMissing creates the target's dataset if it doesn't exist yet, in the configured location and with
+// the configured default table expiration, returning true if it was created
+func CreateDatasetIfMissing(ctx context.Context, target *config.Target) (bool, error) {
+	switch {
+	case target.ProjectID == "":
+		return false, errors.New("no project ID defined to create the dataset in")
+	case target.DataSet == "":
+		return false, errors.New("no dataset defined to create")
+	}
+
+	exists, err := DatasetExists(ctx, target)
+	if err != nil || exists {
+		return false, err
+	}
+
+	client, err := GetClientFor(target.RandExecutionProject())
+	if err != nil {
+		return false, err
+	}
+
+	location := config.GlobalCfg.DatasetLocation
+	if location == "" {
+		location = target.Location
+	}
+
+	err = client.DatasetInProject(target.ProjectID, target.DataSet).Create(ctx, &bigquery.DatasetMetadata{
+		Location:               location,
+		DefaultTableExpiration: config.GlobalCfg.DefaultTableExpiration,
+	})
+	if err != nil {
+		// Another run may have created it since we checked
+		if isErrAlreadyExists(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("Unable to create dataset %s.%s: %s", target.ProjectID, target.DataSet, err)
+	}
+
+	return true, nil
+}
+
+// DropDataset deletes the target's dataset and every table within it
+func DropDataset(ctx context.Context, target *config.Target) error {
+	client, err := GetClientFor(target.RandExecutionProject())
+	if err != nil {
+		return err
+	}
+
+	if err := client.DatasetInProject(target.ProjectID, target.DataSet).DeleteWithContents(ctx); err != nil && !isErrTableNotFound(err) {
+		return fmt.Errorf("Unable to drop dataset %s.%s: %s", target.ProjectID, target.DataSet, err)
+	}
+
+	return nil
+}
+
+// ListTables returns the names of the tables and views in the target's dataset, which is empty if the
+// dataset doesn't exist
+func ListTables(ctx context.Context, target *config.Target) ([]string, error) {
+	client, err := GetClientFor(target.RandExecutionProject())
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]string, 0)
+
+	itr := client.DatasetInProject(target.ProjectID, target.DataSet).Tables(ctx)
+	for {
+		table, err := itr.Next()
+		if err == iterator.Done {
+			break
+		}
+		if isErrTableNotFound(err) {
+			return tables, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Unable to list the tables in %s.%s: %s", target.ProjectID, target.DataSet, err)
+		}
+
+		tables = append(tables, table.TableID)
+	}
+
+	return tables, nil
+}
+
+// DropTable deletes a table or view from the target's dataset
+func DropTable(ctx context.Context, target *config.Target, name string) error {
+	client, err := GetClientFor(target.RandExecutionProject())
+	if err != nil {
+		return err
+	}
+
+	if err := client.DatasetInProject(target.ProjectID, target.DataSet).Table(name).Delete(ctx); err != nil && !isErrTableNotFound(err) {
+		return fmt.Errorf("Unable to drop %s.%s.%s: %s", target.ProjectID, target.DataSet, name, err)
+	}
+
+	return nil
+}
+
+func isErrAlreadyExists(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"reflect"
 	"strings"
@@ -51,7 +52,19 @@ func GetClientFor(project string) (*bigquery.Client, error) {
 	return client, nil
 }
 
-func Run(ctx context.Context, f *fs.File) (string, error) {
+// Run builds the model in BigQuery; if the model is atomic, the validator (if given) is called before the
+// model's table is replaced
+func Run(ctx context.Context, f *fs.File, validate Validator) (string, error) {
+	return run(ctx, f, validate, isAtomic(f))
+}
+
+// RunStaged builds the model like Run, however any model which is built as a new table is always built atomically,
+// so the validator is called against the staged table before it is published
+func RunStaged(ctx context.Context, f *fs.File, validate Validator) (string, error) {
+	return run(ctx, f, validate, true)
+}
+
+func run(ctx context.Context, f *fs.File, validate Validator, atomic bool) (string, error) {
 	query := BuildQuery(f)
 
 	if strings.TrimSpace(query) == "" {
@@ -77,35 +90,57 @@ func Run(ctx context.Context, f *fs.File) (string, error) {
 
 	dataset := client.DatasetInProject(target.ProjectID, target.DataSet)
 
-	if f.IsView {
-		// Try to see if view exists.
-		table := client.
-			DatasetInProject(target.ProjectID, target.DataSet).
-			Table(f.Name)
-		metadata, err := table.Metadata(ctx)
-		if err != nil && !isErrTableNotFound(err) {
-			return query, fmt.Errorf("Cannot get table metadata %s: %+v", f.Name, err)
+	// Check the model's output matches its contract before we write anything
+	if isContractEnforced(f) {
+		if err := checkContract(ctx, f, query, target); err != nil {
+			return query, err
+		}
+	}
+
+	table := dataset.Table(f.Name)
+
+	// Make sure what already exists in BigQuery is the same type of object as the model's materialization
+	existing, replaced, err := checkMaterialization(ctx, table, f, query, target)
+	if err != nil {
+		return query, err
+	}
+
+	switch materialization := f.GetMaterialization(); {
+	case materialization == "view":
+		if err := runView(ctx, table, f, query, existing, replaced); err != nil {
+			return query, err
 		}
-		if isErrTableNotFound(err) {
-			// If the materialization is specified as a view, populate
-			// the BQ metadata that converts the table to a view.
-			if err := table.Create(ctx, &bigquery.TableMetadata{
-				ViewQuery: query,
-			}); err != nil {
-				return query, fmt.Errorf("Unable to create view: %s %s", f.Name, err)
+
+	case materialization == "materialized_view":
+		if err := runMaterializedView(ctx, table, f, query, existing, replaced); err != nil {
+			return query, err
+		}
+
+	case materialization == "incremental" && existing != nil && !f.IsFullRefresh():
+		// Incremental models with an existing table are loaded into it, rather than the table being rebuilt
+		statement, err := runIncremental(ctx, client, f, target)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				return "", context.Canceled
 			}
-		} else if metadata.ViewQuery != "" {
-			// We need to update the view
-			if _, err := table.Update(ctx, bigquery.TableMetadataToUpdate{
-				ViewQuery: query,
-			}, metadata.ETag); err != nil {
-				return query, fmt.Errorf("Cannot update view metadata %s: %s", f.Name, err)
+			return statement, err
+		}
+
+		query = statement
+
+	case atomic:
+		if err := runAtomic(ctx, client, f, query, target, validate); err != nil {
+			if err == context.Canceled {
+				return "", err
 			}
-		} else {
-			// Table exists and doesn't have a view query
-			return query, fmt.Errorf("Existing table is not a view %s: %s", f.Name, err)
+			return query, err
 		}
-	} else {
+
+		if err := restoreMetadata(ctx, table, f, replaced); err != nil {
+			return query, err
+		}
+
+	default:
 		q := client.Query(query)
 		q.Location = target.Location
 
@@ -115,7 +150,7 @@ func Run(ctx context.Context, f *fs.File) (string, error) {
 		q.DisableQueryCache = true
 
 		// Output write information
-		q.Dst = dataset.Table(f.Name)
+		q.Dst = table
 		q.CreateDisposition = bigquery.CreateIfNeeded
 		q.WriteDisposition = bigquery.WriteTruncate
 
@@ -148,6 +183,15 @@ func Run(ctx context.Context, f *fs.File) (string, error) {
 			}
 			return query, fmt.Errorf("Model %s's job result in an error: %s", f.Name, err)
 		}
+
+		if err := restoreMetadata(ctx, table, f, replaced); err != nil {
+			return query, err
+		}
+	}
+
+	// Grants are lost when a table is recreated, so they're reapplied after every run
+	if err := applyGrants(ctx, table, f); err != nil {
+		return query, err
 	}
 
 	return query, nil
@@ -257,6 +301,88 @@ func NumberRows(query string, target *config.Target) (uint64, error) {
 	return itr.TotalRows, nil
 }
 
+// StoreTestFailures materializes the failing rows returned by a test query into a table named after the test
+// in the audit dataset of the target, returning the number of failing rows stored
+func StoreTestFailures(ctx context.Context, testName string, query string, target *config.Target) (uint64, error) {
+	switch {
+	case target.ProjectID == "":
+		return 0, errors.New("no project ID defined to run query against")
+	case target.DataSet == "":
+		return 0, errors.New("no dataset defined to run query against")
+	}
+
+	client, err := GetClientFor(target.RandExecutionProject())
+	if err != nil {
+		return 0, err
+	}
+
+	dataset := client.DatasetInProject(target.ProjectID, AuditDataset(target))
+
+	if _, err := dataset.Metadata(ctx); err != nil {
+		if !isErrTableNotFound(err) {
+			return 0, fmt.Errorf("Unable to get audit dataset metadata: %s", err)
+		}
+
+		if err := dataset.Create(ctx, &bigquery.DatasetMetadata{Location: target.Location}); err != nil {
+			return 0, fmt.Errorf("Unable to create audit dataset: %s", err)
+		}
+	}
+
+	q := client.Query(query)
+	q.Location = target.Location
+
+	// Default read information
+	q.DefaultProjectID = target.ProjectID
+	q.DefaultDatasetID = target.DataSet
+	q.DisableQueryCache = true
+
+	// Output write information
+	table := dataset.Table(testName)
+	q.Dst = table
+	q.CreateDisposition = bigquery.CreateIfNeeded
+	q.WriteDisposition = bigquery.WriteTruncate
+
+	job, err := q.Run(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to run query: %s", err)
+	}
+
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("Error executing: %s", err)
+	}
+
+	if status.State != bigquery.Done {
+		return 0, fmt.Errorf("Execution job %s in state %d", job.ID(), status.State)
+	}
+
+	if err := status.Err(); err != nil {
+		return 0, fmt.Errorf("Job result in an error: %s", err)
+	}
+
+	metadata, err := table.Metadata(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to get stored failures metadata: %s", err)
+	}
+
+	return metadata.NumRows, nil
+}
+
+// AuditDataset is the dataset which failing test rows are stored in for the given target
+func AuditDataset(target *config.Target) string {
+	return target.DataSet + "_dbt_test__audit"
+}
+
+// ConsoleURL returns a link to the table in the BigQuery web console
+func ConsoleURL(project, dataset, table string) string {
+	return fmt.Sprintf(
+		"https://console.cloud.google.com/bigquery?p=%s&d=%s&t=%s&page=table",
+		url.QueryEscape(project),
+		url.QueryEscape(dataset),
+		url.QueryEscape(table),
+	)
+}
+
 func GetRows(ctx context.Context, query string, target *config.Target) ([][]Value, Schema, error) {
 	switch {
 	case target.ProjectID == "":
@@ -394,6 +520,19 @@ func LoadSeedFile(ctx context.Context, seed *fs.SeedFile) error {
 		return fmt.Errorf("Seed file %s's loading job has an error: %w", seed.Path, err)
 	}
 
+	// Record what we loaded, so we only reload the seed when it changes
+	hash, err := seed.Hash()
+	if err != nil {
+		return fmt.Errorf("Unable to hash seed file %s: %w", seed.Path, err)
+	}
+
+	update := bigquery.TableMetadataToUpdate{}
+	update.SetLabel(seedHashLabel, hash)
+
+	if _, err := dataset.Table(seed.Name).Update(ctx, update, ""); err != nil {
+		return fmt.Errorf("Unable to label seed table %s: %w", seed.Name, err)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,127 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/iam"
+
+	"ddbt/compilerInterface"
+	"ddbt/config"
+	"ddbt/fs"
+)
+
+// Reads the model's `grants` config, a map of IAM roles to the member, or list of members, who should have them
+func modelGrants(f *fs.File) (map[string][]string, error) {
+	value := f.GetConfig("grants")
+
+	switch value.Type() {
+	case compilerInterface.Undefined, compilerInterface.NullVal:
+		return nil, nil
+
+	case compilerInterface.MapVal:
+		grants := make(map[string][]string, len(value.MapValue))
+
+		for role, members := range value.MapValue {
+			switch members.Type() {
+			case compilerInterface.StringVal:
+				grants[role] = []string{members.StringValue}
+
+			case compilerInterface.ListVal:
+				list := make([]string, 0, len(members.ListValue))
+				for _, member := range members.ListValue {
+					list = append(list, member.AsStringValue())
+				}
+				grants[role] = list
+
+			case compilerInterface.Undefined, compilerInterface.NullVal:
+				grants[role] = []string{}
+
+			default:
+				return nil, fmt.Errorf("grants of %s for model %s must be a member or list of members, got %s", role, f.Name, members.Type())
+			}
+		}
+
+		return grants, nil
+
+	default:
+		return nil, fmt.Errorf("grants for model %s must be a map of roles to members, got %s", f.Name, value.Type())
+	}
+}
+
+// Updates the policy so the roles in the grants have exactly the members listed, returning the number of
+// members added and revoked. Roles which aren't in the grants are left untouched
+func updatePolicy(policy *iam.Policy, grants map[string][]string) (added int, revoked int) {
+	roles := make([]string, 0, len(grants))
+	for role := range grants {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	for _, role := range roles {
+		roleName := iam.RoleName(role)
+		wanted := grants[role]
+
+		// Copy the current members, as removing members modifies the policy's list
+		current := append([]string(nil), policy.Members(roleName)...)
+
+		for _, member := range current {
+			if !containsMember(wanted, member) {
+				policy.Remove(member, roleName)
+				revoked++
+			}
+		}
+
+		for _, member := range wanted {
+			if !containsMember(policy.Members(roleName), member) {
+				policy.Add(member, roleName)
+				added++
+			}
+		}
+	}
+
+	return added, revoked
+}
+
+// IAM treats the email addresses in members as case insensitive
+func containsMember(members []string, member string) bool {
+	for _, m := range members {
+		if strings.EqualFold(m, member) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Applies the model's grants to the IAM policy of its table, only changing the members which differ
+func applyGrants(ctx context.Context, table *bigquery.Table, f *fs.File) error {
+	if config.GlobalCfg.NoGrants {
+		return nil
+	}
+
+	grants, err := modelGrants(f)
+	if err != nil || len(grants) == 0 {
+		return err
+	}
+
+	handle := table.IAM()
+
+	policy, err := handle.Policy(ctx)
+	if err != nil {
+		return fmt.Errorf("Unable to get the IAM policy of model %s: %s", f.Name, err)
+	}
+
+	if added, revoked := updatePolicy(policy, grants); added == 0 && revoked == 0 {
+		return nil
+	}
+
+	if err := handle.SetPolicy(ctx, policy); err != nil {
+		return fmt.Errorf("Unable to apply the grants of model %s: %s", f.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package bigquery
+
+import (
+	"testing"
+
+	"cloud.google.com/go/iam"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdatePolicy(t *testing.T) {
+	policy := &iam.Policy{}
+	policy.Add("user:owner@example.com", iam.Owner)
+	policy.Add("group:Analysts@example.com", "roles/bigquery.dataViewer")
+	policy.Add("user:leaver@example.com", "roles/bigquery.dataViewer")
+
+	added, revoked := updatePolicy(policy, map[string][]string{
+		"roles/bigquery.dataViewer": {"group:analysts@example.com", "user:joiner@example.com"},
+		"roles/bigquery.dataEditor": {"serviceAccount:loader@example.iam.gserviceaccount.com"},
+	})
+	assert.Equal(t, 2, added)
+	assert.Equal(t, 1, revoked)
+
+	assert.ElementsMatch(t, []string{"group:Analysts@example.com", "user:joiner@example.com"}, policy.Members("roles/bigquery.dataViewer"))
+	assert.Equal(t, []string{"serviceAccount:loader@example.iam.gserviceaccount.com"}, policy.Members("roles/bigquery.dataEditor"))
+	assert.Equal(t, []string{"user:owner@example.com"}, policy.Members(iam.Owner), "roles without grants should be left untouched")
+
+	// Applying the same grants again should change nothing
+	added, revoked = updatePolicy(policy, map[string][]string{
+		"roles/bigquery.dataViewer": {"group:analysts@example.com", "user:joiner@example.com"},
+	})
+	assert.Zero(t, added)
+	assert.Zero(t, revoked)
+
+	// An empty list of members revokes the role from everyone
+	_, revoked = updatePolicy(policy, map[string][]string{"roles/bigquery.dataEditor": {}})
+	assert.Equal(t, 1, revoked)
+	assert.Empty(t, policy.Members("roles/bigquery.dataEditor"))
+}
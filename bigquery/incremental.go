@@ -0,0 +1,348 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+
+	"ddbt/compilerInterface"
+	"ddbt/config"
+	"ddbt/fs"
+)
+
+// The ways an incremental model can react to the columns of its query changing
+const (
+	onSchemaChangeIgnore         = "ignore"
+	onSchemaChangeFail           = "fail"
+	onSchemaChangeAppendColumns  = "append_new_columns"
+	onSchemaChangeSyncAllColumns = "sync_all_columns"
+)
+
+// The differences between the schema of an existing table and the schema of the query which will be loaded into it
+type schemaChanges struct {
+	Added   []*bigquery.FieldSchema // columns in the query which are not in the table
+	Removed []*bigquery.FieldSchema // columns in the table which are not in the query
+	Changed []string                // descriptions of the columns whose data type has changed
+}
+
+func (c schemaChanges) hasChanges() bool {
+	return len(c.Added) > 0 || len(c.Removed) > 0 || len(c.Changed) > 0
+}
+
+func (c schemaChanges) describe() []string {
+	descriptions := make([]string, 0, len(c.Added)+len(c.Removed)+len(c.Changed))
+
+	for _, field := range c.Added {
+		descriptions = append(descriptions, fmt.Sprintf("new column `%s` of type %s", field.Name, fieldDataType(field)))
+	}
+
+	for _, field := range c.Removed {
+		descriptions = append(descriptions, fmt.Sprintf("removed column `%s` of type %s", field.Name, fieldDataType(field)))
+	}
+
+	return append(descriptions, c.Changed...)
+}
+
+// IsIncremental returns true if the model should be loaded incrementally into its existing table
+// rather than having the table rebuilt (i.e. what `is_incremental()` returns when executing)
+func IsIncremental(ctx context.Context, f *fs.File) (bool, error) {
+	if f.GetMaterialization() != "incremental" || f.IsFullRefresh() {
+		return false, nil
+	}
+
+	target, err := f.GetTarget()
+	if err != nil {
+		return false, err
+	}
+
+	client, err := GetClientFor(target.RandExecutionProject())
+	if err != nil {
+		return false, err
+	}
+
+	metadata, err := client.DatasetInProject(target.ProjectID, target.DataSet).Table(f.Name).Metadata(ctx)
+	if err != nil {
+		if isErrTableNotFound(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("Cannot get table metadata %s: %s", f.Name, err)
+	}
+
+	return metadata.Type == bigquery.RegularTable, nil
+}
+
+// The on_schema_change config of the model, defaulting to ignore
+func onSchemaChange(f *fs.File) (string, error) {
+	value := f.GetConfig("on_schema_change")
+	if value.Type() != compilerInterface.StringVal || value.StringValue == "" {
+		return onSchemaChangeIgnore, nil
+	}
+
+	switch value.StringValue {
+	case onSchemaChangeIgnore, onSchemaChangeFail, onSchemaChangeAppendColumns, onSchemaChangeSyncAllColumns:
+		return value.StringValue, nil
+
+	default:
+		return "", fmt.Errorf(
+			"Unknown on_schema_change `%s` for model %s, expected one of: ignore, fail, append_new_columns or sync_all_columns",
+			value.StringValue,
+			f.Name,
+		)
+	}
+}
+
+// The unique_key config of the model, which can be a single column or a list of columns
+func uniqueKeys(f *fs.File) []string {
+	value := f.GetConfig("unique_key")
+
+	switch value.Type() {
+	case compilerInterface.StringVal:
+		if value.StringValue == "" {
+			return nil
+		}
+		return []string{value.StringValue}
+
+	case compilerInterface.ListVal:
+		keys := make([]string, 0, len(value.ListValue))
+		for _, key := range value.ListValue {
+			keys = append(keys, key.AsStringValue())
+		}
+		return keys
+
+	default:
+		return nil
+	}
+}
+
+// Loads the output of the query into the existing table of an incremental model, first applying the
+// model's on_schema_change config if the columns of the query no longer match the table
+func runIncremental(ctx context.Context, client *bigquery.Client, f *fs.File, target *config.Target) (string, error) {
+	query := BuildQuery(f)
+
+	mode, err := onSchemaChange(f)
+	if err != nil {
+		return query, err
+	}
+
+	table := client.DatasetInProject(target.ProjectID, target.DataSet).Table(f.Name)
+
+	metadata, err := table.Metadata(ctx)
+	if err != nil {
+		return query, fmt.Errorf("Cannot get table metadata %s: %s", f.Name, err)
+	}
+
+	querySchema, err := DryRunSchema(ctx, query, target)
+	if err != nil {
+		return query, fmt.Errorf("Unable to get the schema of model %s: %s", f.Name, err)
+	}
+
+	changes := diffSchemas(metadata.Schema, querySchema)
+	tableName := "`" + target.ProjectID + "`.`" + target.DataSet + "`.`" + f.Name + "`"
+
+	columns, err := columnsToLoad(mode, changes, metadata.Schema, querySchema)
+	if err != nil {
+		return query, fmt.Errorf("Schema of model %s has changed:\n\t- %s", f.Name, err)
+	}
+
+	if changes.hasChanges() && (mode == onSchemaChangeAppendColumns || mode == onSchemaChangeSyncAllColumns) {
+		if len(changes.Added) > 0 {
+			if _, err := table.Update(ctx, bigquery.TableMetadataToUpdate{
+				Schema: appendColumns(metadata.Schema, changes.Added),
+			}, metadata.ETag); err != nil {
+				return query, fmt.Errorf("Unable to add new columns to %s: %s", f.Name, err)
+			}
+		}
+
+		if mode == onSchemaChangeSyncAllColumns && len(changes.Removed) > 0 {
+			if err := runStatement(ctx, client, dropColumnsSQL(tableName, changes.Removed), target); err != nil {
+				return query, fmt.Errorf("Unable to remove old columns from %s: %s", f.Name, err)
+			}
+		}
+	}
+
+	var builder strings.Builder
+
+	if udf := f.GetConfig("udf"); udf.Type() == compilerInterface.StringVal {
+		builder.WriteString(udf.StringValue)
+	}
+
+	builder.WriteString(incrementalSQL(tableName, f.CompiledContents, columns, uniqueKeys(f)))
+	statement := builder.String()
+
+	if err := runStatement(ctx, client, statement, target); err != nil {
+		return statement, fmt.Errorf("Unable to run model %s: %s", f.Name, err)
+	}
+
+	return statement, nil
+}
+
+// Compares the top level columns of the table against those of the query
+func diffSchemas(tableSchema, querySchema Schema) schemaChanges {
+	var changes schemaChanges
+
+	tableFields := make(map[string]*bigquery.FieldSchema, len(tableSchema))
+	for _, field := range tableSchema {
+		tableFields[strings.ToLower(field.Name)] = field
+	}
+
+	queryFields := make(map[string]struct{}, len(querySchema))
+	for _, field := range querySchema {
+		queryFields[strings.ToLower(field.Name)] = struct{}{}
+
+		existing, found := tableFields[strings.ToLower(field.Name)]
+		if !found {
+			changes.Added = append(changes.Added, field)
+			continue
+		}
+
+		if existingType, newType := fieldDataType(existing), fieldDataType(field); existingType != newType {
+			changes.Changed = append(
+				changes.Changed,
+				fmt.Sprintf("column `%s` has changed type from %s to %s", field.Name, existingType, newType),
+			)
+		}
+	}
+
+	for _, field := range tableSchema {
+		if _, found := queryFields[strings.ToLower(field.Name)]; !found {
+			changes.Removed = append(changes.Removed, field)
+		}
+	}
+
+	return changes
+}
+
+// Decides which columns of the query get loaded into the table, based on the on_schema_change config
+func columnsToLoad(mode string, changes schemaChanges, tableSchema, querySchema Schema) ([]string, error) {
+	switch mode {
+	case onSchemaChangeFail:
+		if changes.hasChanges() {
+			return nil, fmt.Errorf("%s", strings.Join(changes.describe(), "\n\t- "))
+		}
+
+	case onSchemaChangeSyncAllColumns:
+		// BigQuery can't change the type of an existing column in place
+		if len(changes.Changed) > 0 {
+			return nil, fmt.Errorf("%s", strings.Join(changes.Changed, "\n\t- "))
+		}
+	}
+
+	columns := make([]string, 0, len(querySchema))
+
+	if mode == onSchemaChangeIgnore {
+		// Only load the columns which already exist in the table
+		added := make(map[string]struct{}, len(changes.Added))
+		for _, field := range changes.Added {
+			added[strings.ToLower(field.Name)] = struct{}{}
+		}
+
+		for _, field := range querySchema {
+			if _, found := added[strings.ToLower(field.Name)]; !found {
+				columns = append(columns, field.Name)
+			}
+		}
+
+		return columns, nil
+	}
+
+	for _, field := range querySchema {
+		columns = append(columns, field.Name)
+	}
+
+	return columns, nil
+}
+
+// Returns the table schema with the new columns appended; as BigQuery only allows new columns to be nullable
+// any required columns are relaxed
+func appendColumns(tableSchema Schema, added []*bigquery.FieldSchema) Schema {
+	schema := make(Schema, 0, len(tableSchema)+len(added))
+	schema = append(schema, tableSchema...)
+
+	for _, field := range added {
+		column := *field
+		column.Required = false
+
+		schema = append(schema, &column)
+	}
+
+	return schema
+}
+
+func dropColumnsSQL(tableName string, removed []*bigquery.FieldSchema) string {
+	drops := make([]string, len(removed))
+	for i, field := range removed {
+		drops[i] = "DROP COLUMN IF EXISTS `" + field.Name + "`"
+	}
+
+	return "ALTER TABLE " + tableName + "\n" + strings.Join(drops, ",\n")
+}
+
+// Builds the statement which loads the query into the table; a MERGE on the unique key if one is given
+// otherwise the rows are appended to the table
+func incrementalSQL(tableName string, query string, columns []string, uniqueKeys []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = "`" + column + "`"
+	}
+	columnList := strings.Join(quoted, ", ")
+
+	if len(uniqueKeys) == 0 {
+		return fmt.Sprintf(
+			"INSERT INTO %s (%s)\nSELECT %s FROM (\n%s\n)",
+			tableName,
+			columnList,
+			columnList,
+			strings.TrimSpace(query),
+		)
+	}
+
+	conditions := make([]string, len(uniqueKeys))
+	for i, key := range uniqueKeys {
+		conditions[i] = fmt.Sprintf("DBT_INTERNAL_SOURCE.`%s` = DBT_INTERNAL_DEST.`%s`", key, key)
+	}
+
+	updates := make([]string, len(quoted))
+	for i, column := range quoted {
+		updates[i] = fmt.Sprintf("%s = DBT_INTERNAL_SOURCE.%s", column, column)
+	}
+
+	return fmt.Sprintf(
+		"MERGE %s AS DBT_INTERNAL_DEST\nUSING (\n%s\n) AS DBT_INTERNAL_SOURCE\nON %s\nWHEN MATCHED THEN UPDATE SET %s\nWHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)",
+		tableName,
+		strings.TrimSpace(query),
+		strings.Join(conditions, " AND "),
+		strings.Join(updates, ", "),
+		columnList,
+		columnList,
+	)
+}
+
+// Runs a statement which doesn't return any rows (such as DDL or DML) and waits for it to complete
+func runStatement(ctx context.Context, client *bigquery.Client, statement string, target *config.Target) error {
+	q := client.Query(statement)
+	q.Location = target.Location
+
+	// Default read information
+	q.DefaultProjectID = target.ProjectID
+	q.DefaultDatasetID = target.DataSet
+	q.DisableQueryCache = true
+
+	job, err := q.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	if status.State != bigquery.Done {
+		return fmt.Errorf("execution job %s in state %d", job.ID(), status.State)
+	}
+
+	return status.Err()
+}
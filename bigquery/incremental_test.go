@@ -0,0 +1,121 @@
+package bigquery
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	tableSchema := Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType, Required: true},
+		{Name: "name", Type: bigquery.StringFieldType},
+		{Name: "old_column", Type: bigquery.StringFieldType},
+		{Name: "amount", Type: bigquery.IntegerFieldType},
+	}
+
+	querySchema := Schema{
+		{Name: "ID", Type: bigquery.IntegerFieldType},
+		{Name: "name", Type: bigquery.StringFieldType},
+		{Name: "amount", Type: bigquery.FloatFieldType},
+		{Name: "new_column", Type: bigquery.BooleanFieldType},
+	}
+
+	changes := diffSchemas(tableSchema, querySchema)
+	require.True(t, changes.hasChanges())
+
+	assert.Equal(
+		t,
+		[]string{
+			"new column `new_column` of type BOOLEAN",
+			"removed column `old_column` of type STRING",
+			"column `amount` has changed type from INTEGER to FLOAT",
+		},
+		changes.describe(),
+	)
+
+	assert.False(t, diffSchemas(tableSchema, tableSchema).hasChanges())
+}
+
+func TestColumnsToLoad(t *testing.T) {
+	tableSchema := Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "old_column", Type: bigquery.StringFieldType},
+	}
+
+	querySchema := Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "new_column", Type: bigquery.StringFieldType},
+	}
+
+	changes := diffSchemas(tableSchema, querySchema)
+
+	columns, err := columnsToLoad(onSchemaChangeIgnore, changes, tableSchema, querySchema)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id"}, columns)
+
+	columns, err = columnsToLoad(onSchemaChangeAppendColumns, changes, tableSchema, querySchema)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "new_column"}, columns)
+
+	columns, err = columnsToLoad(onSchemaChangeSyncAllColumns, changes, tableSchema, querySchema)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "new_column"}, columns)
+
+	_, err = columnsToLoad(onSchemaChangeFail, changes, tableSchema, querySchema)
+	assert.EqualError(t, err, "new column `new_column` of type STRING\n\t- removed column `old_column` of type STRING")
+
+	// Type changes can't be synced
+	changedSchema := Schema{
+		{Name: "id", Type: bigquery.StringFieldType},
+		{Name: "old_column", Type: bigquery.StringFieldType},
+	}
+	_, err = columnsToLoad(onSchemaChangeSyncAllColumns, diffSchemas(tableSchema, changedSchema), tableSchema, changedSchema)
+	assert.EqualError(t, err, "column `id` has changed type from INTEGER to STRING")
+}
+
+func TestAppendColumns(t *testing.T) {
+	tableSchema := Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType, Required: true},
+	}
+
+	added := []*bigquery.FieldSchema{
+		{Name: "new_column", Type: bigquery.StringFieldType, Required: true},
+	}
+
+	schema := appendColumns(tableSchema, added)
+	require.Len(t, schema, 2)
+
+	assert.True(t, schema[0].Required, "existing columns should be left unchanged")
+	assert.Equal(t, "new_column", schema[1].Name)
+	assert.False(t, schema[1].Required, "new columns should be nullable")
+	assert.True(t, added[0].Required, "the query schema should not be modified")
+}
+
+func TestIncrementalSQL(t *testing.T) {
+	table := "`project`.`dataset`.`model`"
+
+	assert.Equal(
+		t,
+		"INSERT INTO `project`.`dataset`.`model` (`id`, `value`)\nSELECT `id`, `value` FROM (\nSELECT 1 AS id, 2 AS value\n)",
+		incrementalSQL(table, "SELECT 1 AS id, 2 AS value\n", []string{"id", "value"}, nil),
+	)
+
+	assert.Equal(
+		t,
+		"MERGE `project`.`dataset`.`model` AS DBT_INTERNAL_DEST\n"+
+			"USING (\nSELECT 1 AS id, 2 AS value\n) AS DBT_INTERNAL_SOURCE\n"+
+			"ON DBT_INTERNAL_SOURCE.`id` = DBT_INTERNAL_DEST.`id`\n"+
+			"WHEN MATCHED THEN UPDATE SET `id` = DBT_INTERNAL_SOURCE.`id`, `value` = DBT_INTERNAL_SOURCE.`value`\n"+
+			"WHEN NOT MATCHED THEN INSERT (`id`, `value`) VALUES (`id`, `value`)",
+		incrementalSQL(table, "SELECT 1 AS id, 2 AS value", []string{"id", "value"}, []string{"id"}),
+	)
+
+	assert.Equal(
+		t,
+		"ALTER TABLE `project`.`dataset`.`model`\nDROP COLUMN IF EXISTS `a`,\nDROP COLUMN IF EXISTS `b`",
+		dropColumnsSQL(table, []*bigquery.FieldSchema{{Name: "a"}, {Name: "b"}}),
+	)
+}
@@ -0,0 +1,205 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/bigquery"
+
+	"ddbt/compilerInterface"
+	"ddbt/config"
+	"ddbt/fs"
+)
+
+// The ways a model can react to the existing object in BigQuery being of a different type (i.e. a table
+// where the model is now a view)
+const (
+	onMaterializationChangeFail    = "fail"
+	onMaterializationChangeReplace = "replace"
+)
+
+// The type of object in BigQuery a materialization is stored as
+func tableTypeFor(materialization string) bigquery.TableType {
+	switch materialization {
+	case "view":
+		return bigquery.ViewTable
+	case "materialized_view":
+		return bigquery.MaterializedView
+	default:
+		return bigquery.RegularTable
+	}
+}
+
+// A human readable name for a type of object in BigQuery
+func tableTypeName(tableType bigquery.TableType) string {
+	switch tableType {
+	case bigquery.RegularTable:
+		return "table"
+	case bigquery.ViewTable:
+		return "view"
+	case bigquery.MaterializedView:
+		return "materialized view"
+	case bigquery.ExternalTable:
+		return "external table"
+	default:
+		return string(tableType)
+	}
+}
+
+// The on_materialization_change config of the model, defaulting to fail
+func onMaterializationChange(f *fs.File) (string, error) {
+	value := f.GetConfig("on_materialization_change")
+	if value.Type() != compilerInterface.StringVal || value.StringValue == "" {
+		return onMaterializationChangeFail, nil
+	}
+
+	switch value.StringValue {
+	case onMaterializationChangeFail, onMaterializationChangeReplace:
+		return value.StringValue, nil
+
+	default:
+		return "", fmt.Errorf(
+			"Unknown on_materialization_change `%s` for model %s, expected either fail or replace",
+			value.StringValue,
+			f.Name,
+		)
+	}
+}
+
+// Looks up the existing object for the model, returning nil if there isn't one.
+//
+// If the existing object is a different type to the model's materialization, it is dropped if the model's
+// on_materialization_change allows it or this is a full refresh (otherwise an error is returned). The model's query
+// is dry run before the existing object is dropped, so a query which can't be built doesn't leave the model
+// missing. In that case the metadata of the dropped object is returned as replaced, so its labels and description
+// can be carried over to the new object
+func checkMaterialization(ctx context.Context, table *bigquery.Table, f *fs.File, query string, target *config.Target) (existing *bigquery.TableMetadata, replaced *bigquery.TableMetadata, err error) {
+	metadata, err := table.Metadata(ctx)
+	if err != nil {
+		if isErrTableNotFound(err) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, fmt.Errorf("Cannot get table metadata %s: %s", f.Name, err)
+	}
+
+	expected := tableTypeFor(f.GetMaterialization())
+	if metadata.Type == expected {
+		return metadata, nil, nil
+	}
+
+	policy, err := onMaterializationChange(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if policy != onMaterializationChangeReplace && !f.IsFullRefresh() {
+		return nil, nil, fmt.Errorf(
+			"Model %s is materialized as a %s, but a %s already exists; set on_materialization_change='replace' or run with --full-refresh to drop and recreate it",
+			f.Name,
+			tableTypeName(expected),
+			tableTypeName(metadata.Type),
+		)
+	}
+
+	if _, err := DryRunSchema(ctx, query, target); err != nil {
+		return nil, nil, fmt.Errorf(
+			"Not replacing the existing %s %s, as the model can not be built: %s",
+			tableTypeName(metadata.Type),
+			f.Name,
+			err,
+		)
+	}
+
+	if err := table.Delete(ctx); err != nil {
+		return nil, nil, fmt.Errorf("Unable to drop existing %s %s: %s", tableTypeName(metadata.Type), f.Name, err)
+	}
+
+	return nil, metadata, nil
+}
+
+// Creates or updates the view for a model
+func runView(ctx context.Context, table *bigquery.Table, f *fs.File, query string, existing, replaced *bigquery.TableMetadata) error {
+	if existing == nil {
+		metadata := carriedMetadata(replaced)
+		metadata.ViewQuery = query
+
+		if err := table.Create(ctx, metadata); err != nil {
+			return fmt.Errorf("Unable to create view: %s %s", f.Name, err)
+		}
+
+		return nil
+	}
+
+	// Only the query is updated, so the labels and description of the view are kept
+	if _, err := table.Update(ctx, bigquery.TableMetadataToUpdate{
+		ViewQuery: query,
+	}, existing.ETag); err != nil {
+		return fmt.Errorf("Cannot update view metadata %s: %s", f.Name, err)
+	}
+
+	return nil
+}
+
+// Creates the materialized view for a model; as BigQuery can not change the query of a materialized view, it is
+// recreated if the query has changed
+func runMaterializedView(ctx context.Context, table *bigquery.Table, f *fs.File, query string, existing, replaced *bigquery.TableMetadata) error {
+	if existing != nil {
+		if existing.MaterializedView != nil && existing.MaterializedView.Query == query {
+			return nil
+		}
+
+		if err := table.Delete(ctx); err != nil {
+			return fmt.Errorf("Unable to drop materialized view %s: %s", f.Name, err)
+		}
+
+		replaced = existing
+	}
+
+	metadata := carriedMetadata(replaced)
+	metadata.MaterializedView = &bigquery.MaterializedViewDefinition{
+		Query:         query,
+		EnableRefresh: true,
+	}
+
+	if err := table.Create(ctx, metadata); err != nil {
+		return fmt.Errorf("Unable to create materialized view: %s %s", f.Name, err)
+	}
+
+	return nil
+}
+
+// Sets the labels and description of the object that was replaced on the newly built table
+func restoreMetadata(ctx context.Context, table *bigquery.Table, f *fs.File, replaced *bigquery.TableMetadata) error {
+	if replaced == nil || (replaced.Description == "" && len(replaced.Labels) == 0) {
+		return nil
+	}
+
+	update := bigquery.TableMetadataToUpdate{}
+
+	if replaced.Description != "" {
+		update.Description = replaced.Description
+	}
+
+	for key, value := range replaced.Labels {
+		update.SetLabel(key, value)
+	}
+
+	if _, err := table.Update(ctx, update, ""); err != nil {
+		return fmt.Errorf("Unable to restore the labels and description of %s: %s", f.Name, err)
+	}
+
+	return nil
+}
+
+// The metadata which is carried over from a replaced object to the new one
+func carriedMetadata(replaced *bigquery.TableMetadata) *bigquery.TableMetadata {
+	metadata := &bigquery.TableMetadata{}
+
+	if replaced != nil {
+		metadata.Description = replaced.Description
+		metadata.Labels = replaced.Labels
+	}
+
+	return metadata
+}
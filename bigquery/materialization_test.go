@@ -0,0 +1,34 @@
+package bigquery
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableTypeFor(t *testing.T) {
+	assert.Equal(t, bigquery.RegularTable, tableTypeFor("table"))
+	assert.Equal(t, bigquery.RegularTable, tableTypeFor("incremental"))
+	assert.Equal(t, bigquery.RegularTable, tableTypeFor("project_sharded_table"))
+	assert.Equal(t, bigquery.ViewTable, tableTypeFor("view"))
+	assert.Equal(t, bigquery.MaterializedView, tableTypeFor("materialized_view"))
+
+	assert.Equal(t, "materialized view", tableTypeName(tableTypeFor("materialized_view")))
+}
+
+func TestCarriedMetadata(t *testing.T) {
+	assert.Equal(t, &bigquery.TableMetadata{}, carriedMetadata(nil))
+
+	metadata := carriedMetadata(&bigquery.TableMetadata{
+		Description: "my model",
+		Labels:      map[string]string{"team": "data"},
+		ViewQuery:   "SELECT 1",
+		Type:        bigquery.ViewTable,
+	})
+
+	assert.Equal(t, &bigquery.TableMetadata{
+		Description: "my model",
+		Labels:      map[string]string{"team": "data"},
+	}, metadata)
+}
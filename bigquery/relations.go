@@ -0,0 +1,166 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+
+	"ddbt/config"
+)
+
+// GetTableMetadata returns the metadata of a table or view in the target's dataset, or nil if it doesn't exist
+func GetTableMetadata(ctx context.Context, target *config.Target, name string) (*bigquery.TableMetadata, error) {
+	client, err := GetClientFor(target.RandExecutionProject())
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := client.DatasetInProject(target.ProjectID, target.DataSet).Table(name).Metadata(ctx)
+	if err != nil {
+		if isErrTableNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("Cannot get table metadata %s.%s.%s: %s", target.ProjectID, target.DataSet, name, err)
+	}
+
+	return metadata, nil
+}
+
+// RenameTable moves a table to a new name, which may be in another dataset. BigQuery can't rename views, so they
+// have to be recreated instead
+func RenameTable(ctx context.Context, from *config.Target, fromName string, to *config.Target, toName string) error {
+	metadata, err := GetTableMetadata(ctx, from, fromName)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case metadata == nil:
+		return fmt.Errorf("Unable to rename %s.%s.%s: it does not exist", from.ProjectID, from.DataSet, fromName)
+	case metadata.Type != bigquery.RegularTable:
+		return fmt.Errorf("Unable to rename %s.%s.%s: only tables can be renamed, not a %s", from.ProjectID, from.DataSet, fromName, tableTypeName(metadata.Type))
+	}
+
+	client, err := GetClientFor(from.RandExecutionProject())
+	if err != nil {
+		return err
+	}
+
+	source := client.DatasetInProject(from.ProjectID, from.DataSet).Table(fromName)
+
+	copier := client.DatasetInProject(to.ProjectID, to.DataSet).Table(toName).CopierFrom(source)
+	copier.Location = from.Location
+	copier.CreateDisposition = bigquery.CreateIfNeeded
+	copier.WriteDisposition = bigquery.WriteEmpty
+
+	if err := waitForJob(ctx, copier.Run); err != nil {
+		return fmt.Errorf("Unable to copy %s.%s.%s to %s.%s.%s: %s", from.ProjectID, from.DataSet, fromName, to.ProjectID, to.DataSet, toName, err)
+	}
+
+	if err := source.Delete(ctx); err != nil {
+		return fmt.Errorf("Copied %s.%s.%s to %s, but was unable to remove it: %s", from.ProjectID, from.DataSet, fromName, toName, err)
+	}
+
+	return nil
+}
+
+// ExpandColumnTypes widens the numeric columns of a table which are narrower than the same columns in another
+// table, so rows from the other table can be loaded into it
+func ExpandColumnTypes(ctx context.Context, from *config.Target, fromName string, to *config.Target, toName string) error {
+	fromMetadata, err := GetTableMetadata(ctx, from, fromName)
+	if err != nil {
+		return err
+	}
+	toMetadata, err := GetTableMetadata(ctx, to, toName)
+	if err != nil {
+		return err
+	}
+
+	if fromMetadata == nil || toMetadata == nil {
+		return nil
+	}
+
+	expansions := widerColumnTypes(fromMetadata.Schema, toMetadata.Schema)
+	if len(expansions) == 0 {
+		return nil
+	}
+
+	columns := make([]string, 0, len(expansions))
+	for column := range expansions {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	var builder strings.Builder
+	builder.WriteString("ALTER TABLE `" + to.ProjectID + "`.`" + to.DataSet + "`.`" + toName + "`")
+	for i, column := range columns {
+		if i > 0 {
+			builder.WriteString(",")
+		}
+		builder.WriteString("\n\tALTER COLUMN `" + column + "` SET DATA TYPE " + expansions[column])
+	}
+
+	client, err := GetClientFor(to.RandExecutionProject())
+	if err != nil {
+		return err
+	}
+
+	if err := runStatement(ctx, client, builder.String(), to); err != nil {
+		return fmt.Errorf("Unable to expand the column types of %s.%s.%s: %s", to.ProjectID, to.DataSet, toName, err)
+	}
+
+	return nil
+}
+
+// The numeric types each type can be widened to, in the names used by `ALTER COLUMN SET DATA TYPE`
+var columnWidenings = map[bigquery.FieldType]map[bigquery.FieldType]string{
+	bigquery.IntegerFieldType: {
+		bigquery.NumericFieldType: "NUMERIC",
+		"BIGNUMERIC":              "BIGNUMERIC",
+		bigquery.FloatFieldType:   "FLOAT64",
+	},
+	bigquery.NumericFieldType: {
+		"BIGNUMERIC":            "BIGNUMERIC",
+		bigquery.FloatFieldType: "FLOAT64",
+	},
+}
+
+// The columns in `to` which can be widened to the type of the same column in `from`, and the type to widen them to
+func widerColumnTypes(from Schema, to Schema) map[string]string {
+	fromTypes := make(map[string]bigquery.FieldType, len(from))
+	for _, field := range from {
+		fromTypes[strings.ToLower(field.Name)] = field.Type
+	}
+
+	expansions := make(map[string]string)
+	for _, field := range to {
+		fromType, found := fromTypes[strings.ToLower(field.Name)]
+		if !found || fromType == field.Type {
+			continue
+		}
+
+		if wider, found := columnWidenings[field.Type][fromType]; found {
+			expansions[field.Name] = wider
+		}
+	}
+
+	return expansions
+}
+
+// RelationType returns the dbt relation type of a table: table, view, materialized_view or external
+func RelationType(metadata *bigquery.TableMetadata) string {
+	switch metadata.Type {
+	case bigquery.ViewTable:
+		return "view"
+	case bigquery.MaterializedView:
+		return "materialized_view"
+	case bigquery.ExternalTable:
+		return "external"
+	default:
+		return "table"
+	}
+}
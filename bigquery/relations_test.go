@@ -0,0 +1,34 @@
+package bigquery
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWiderColumnTypes(t *testing.T) {
+	from := Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "amount", Type: bigquery.FloatFieldType},
+		{Name: "Rate", Type: bigquery.NumericFieldType},
+		{Name: "name", Type: bigquery.StringFieldType},
+		{Name: "new_column", Type: bigquery.FloatFieldType},
+	}
+
+	to := Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "amount", Type: bigquery.IntegerFieldType},
+		{Name: "rate", Type: bigquery.IntegerFieldType},
+		{Name: "name", Type: bigquery.IntegerFieldType},
+	}
+
+	assert.Equal(
+		t,
+		map[string]string{"amount": "FLOAT64", "rate": "NUMERIC"},
+		widerColumnTypes(from, to),
+		"only numeric columns should be widened, and never narrowed or changed to another type",
+	)
+
+	assert.Empty(t, widerColumnTypes(to, from), "columns should never be narrowed")
+}
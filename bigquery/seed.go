@@ -0,0 +1,57 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"ddbt/fs"
+)
+
+// The label on a seed's table holding the hash of the seed file it was loaded from
+const seedHashLabel = "ddbt_seed_hash"
+
+// LoadSeedFileIfChanged loads the seed only if its table doesn't exist, was loaded from a different version
+// of the seed file or this is a full refresh, returning true if the seed was loaded
+func LoadSeedFileIfChanged(ctx context.Context, seed *fs.SeedFile) (bool, error) {
+	target, err := seed.GetTarget()
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case target.ProjectID == "":
+		return false, errors.New("no project ID defined to run query against")
+	case target.DataSet == "":
+		return false, errors.New("no dataset defined to run query against")
+	}
+
+	client, err := GetClientFor(target.RandExecutionProject())
+	if err != nil {
+		return false, err
+	}
+
+	hash, err := seed.Hash()
+	if err != nil {
+		return false, fmt.Errorf("Unable to hash seed file %s: %w", seed.Path, err)
+	}
+
+	metadata, err := client.DatasetInProject(target.ProjectID, target.DataSet).Table(seed.Name).Metadata(ctx)
+	if err != nil && !isErrTableNotFound(err) {
+		return false, fmt.Errorf("Cannot get table metadata %s: %w", seed.Name, err)
+	}
+
+	if err == nil && metadata.Labels[seedHashLabel] == hash && !seed.File.IsFullRefresh() {
+		return false, nil
+	}
+
+	if err := seed.ReadColumns(); err != nil {
+		return false, err
+	}
+
+	if err := LoadSeedFile(ctx, seed); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ddbt/bigquery"
+	"ddbt/compiler"
+	"ddbt/compilerInterface"
+	"ddbt/fs"
+)
+
+// Returns a validator for atomic models which, if the model sets `atomic_tests=true`, runs the model's tests
+// against the temporary table it has been built into
+func atomicTestValidator(gc *compiler.GlobalContext) bigquery.Validator {
+	return func(ctx context.Context, model *fs.File, relation string) error {
+		if runTests := model.GetConfig("atomic_tests"); runTests.Type() != compilerInterface.BooleanValue || !runTests.BooleanValue {
+			return nil
+		}
+
+		failures := make([]string, 0)
+
+		for _, test := range testsOf(model, false) {
+			result, err := runTest(ctx, gc, test, map[string]string{model.Name: relation})
+			if err != nil {
+				return err
+			}
+
+			if failure := describeTestFailure(result); failure != "" {
+				failures = append(failures, failure)
+			}
+		}
+
+		if len(failures) > 0 {
+			return fmt.Errorf("\t- %s", strings.Join(failures, "\n\t- "))
+		}
+
+		return nil
+	}
+}
+
+// Describes why the test failed, or returns an empty string if the test passed (or only warned)
+func describeTestFailure(result *testResult) string {
+	if result == nil {
+		return ""
+	}
+
+	switch result.status {
+	case testFailed:
+		return fmt.Sprintf("%s: %d failures", result.name, result.rows)
+
+	case testErrored, testCancelled:
+		return fmt.Sprintf("%s: %s", result.name, result.err)
+
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"ddbt/bigquery"
+	"ddbt/compiler"
+	"ddbt/config"
+	"ddbt/fs"
+	"ddbt/utils"
+)
+
+func init() {
+	rootCmd.AddCommand(buildCmd)
+	addModelsFlag(buildCmd)
+	addFailOnNotFoundFlag(buildCmd)
+	addEnableSchemaBasedTestsFlag(buildCmd)
+	addFullRefreshFlag(buildCmd)
+	addNoGrantsFlag(buildCmd)
+	addStoreFailuresFlag(buildCmd)
+	addTestOutputFlags(buildCmd)
+	addTestSelectionFlags(buildCmd)
+}
+
+var buildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Runs the seeds, models and tests of the DAG in order",
+	Long: "Build runs each model and seed in the DAG followed by its tests. Models built as tables are " +
+		"staged and tested before being published, and a failing test stops the models downstream of it from running",
+	Example: "ddbt build -m +my_model",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := validateTestOutputFlags(); err != nil {
+			fmt.Printf("❌ %s\n", err)
+			os.Exit(1)
+		}
+
+		config.GlobalCfg.FullRefresh = FullRefresh
+		config.GlobalCfg.NoGrants = NoGrants
+
+		fileSystem, globalContext := compileAllModels()
+		graph := buildGraph(fileSystem, ModelFilters)
+
+		tests, err := filterTests(graph.AddReferencingTests(), TestSelectors, TestExcludes)
+		if err != nil {
+			fmt.Printf("❌ %s\n", err)
+			os.Exit(1)
+		}
+
+		graph.AddTestsAsGates()
+
+		if exitCode := executeBuild(graph, tests, globalContext); exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	},
+}
+
+// The results of the tests run during a build, keyed by the test file in the graph
+type buildTestResults struct {
+	mutex   sync.Mutex
+	results map[*fs.File]*testResult
+}
+
+func (b *buildTestResults) add(test *fs.File, result *testResult) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.results[test] = result
+}
+
+func (b *buildTestResults) has(test *fs.File) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	_, found := b.results[test]
+	return found
+}
+
+func (b *buildTestResults) all() []testResult {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	results := make([]testResult, 0, len(b.results))
+	for _, result := range b.results {
+		results = append(results, *result)
+	}
+
+	return results
+}
+
+// Executes the models and tests in the graph, returning the exit code for the build
+func executeBuild(graph *fs.Graph, tests []*fs.File, globalContext *compiler.GlobalContext) int {
+	selected := make(map[*fs.File]struct{}, len(tests))
+	for _, test := range tests {
+		selected[test] = struct{}{}
+	}
+
+	if err := createMissingDatasets(graphFiles(graph)); err != nil {
+		fmt.Printf("❌ %s\n", err)
+		return 1
+	}
+
+	testResults := &buildTestResults{results: make(map[*fs.File]*testResult)}
+	validate := stagedTestValidator(globalContext, selected, testResults)
+
+	pb := utils.NewProgressBar("🏗️ Building DAG", graph.Len())
+	ctx := context.Background()
+
+	failed, skipped := graph.ExecuteSkippingDownstreams(func(file *fs.File) error {
+		defer pb.Increment()
+
+		switch file.Type {
+		case fs.ModelFile:
+			if file.GetMaterialization() == "ephemeral" {
+				return nil
+			}
+
+			if file.IsDynamicSQL() || upstreamProfile != "" {
+				if err := compiler.CompileModel(file, globalContext, true); err != nil {
+					return err
+				}
+			}
+
+			_, err := bigquery.RunStaged(ctx, file, validate)
+			return err
+
+		case fs.SeedNode:
+			_, err := bigquery.LoadSeedFileIfChanged(ctx, file.Seed)
+			return err
+
+		case fs.TestFile:
+			// Tests which have already been run against a staged model don't need to be run again
+			if _, found := selected[file]; !found || testResults.has(file) {
+				return nil
+			}
+
+			result, err := runTest(ctx, globalContext, file, nil)
+			if err != nil || result == nil {
+				return err
+			}
+
+			testResults.add(file, result)
+
+			if failure := describeTestFailure(result); failure != "" {
+				return errors.New(failure)
+			}
+		}
+
+		return nil
+	}, config.NumberThreads(), pb)
+
+	pb.Stop()
+
+	exitCode := 0
+
+	if len(failed) > 0 || len(skipped) > 0 {
+		fmt.Printf("\nBuild Results:\n")
+		exitCode = 1
+	}
+
+	failedModels := make([]*fs.File, 0, len(failed))
+	for file := range failed {
+		if file.Type != fs.TestFile || !testResults.has(file) {
+			failedModels = append(failedModels, file)
+		}
+	}
+	sortFilesByName(failedModels)
+
+	for _, file := range failedModels {
+		fmt.Printf("   ❌  %s: %s\n", file.Name, failed[file])
+	}
+
+	sortFilesByName(skipped)
+	for _, file := range skipped {
+		fmt.Printf("   ⏭  %s: skipped due to an upstream failure\n", file.Name)
+	}
+
+	if printTestResults(testResults.all()) && exitCode == 0 {
+		exitCode = 2 // Exit with a test error
+	}
+
+	return exitCode
+}
+
+// Returns a validator which runs the tests of a model (which only reference that model) against the staged table
+// the model has been built into, recording the results
+func stagedTestValidator(gc *compiler.GlobalContext, selected map[*fs.File]struct{}, testResults *buildTestResults) bigquery.Validator {
+	return func(ctx context.Context, model *fs.File, relation string) error {
+		failures := make([]string, 0)
+
+		for _, test := range testsOf(model, true) {
+			if _, found := selected[test]; !found {
+				continue
+			}
+
+			result, err := runTest(ctx, gc, test, map[string]string{model.Name: relation})
+			if err != nil {
+				return err
+			}
+
+			if result == nil {
+				continue
+			}
+
+			testResults.add(test, result)
+
+			if failure := describeTestFailure(result); failure != "" {
+				failures = append(failures, failure)
+			}
+		}
+
+		if len(failures) > 0 {
+			return fmt.Errorf("\t- %s", strings.Join(failures, "\n\t- "))
+		}
+
+		return nil
+	}
+}
+
+// The tests of a model sorted by name; if exclusive is set, any test which also references other models is left out
+func testsOf(model *fs.File, exclusive bool) []*fs.File {
+	tests := make([]*fs.File, 0)
+
+	for _, downstream := range model.Downstreams() {
+		if downstream.Type != fs.TestFile {
+			continue
+		}
+
+		if exclusive && referencesOtherModels(downstream, model) {
+			continue
+		}
+
+		tests = append(tests, downstream)
+	}
+
+	sortFilesByName(tests)
+
+	return tests
+}
+
+func referencesOtherModels(test *fs.File, model *fs.File) bool {
+	for _, upstream := range test.Upstreams() {
+		if upstream.Type == fs.ModelFile && upstream != model {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortFilesByName(files []*fs.File) {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"ddbt/bigquery"
+	"ddbt/config"
+	"ddbt/fs"
+)
+
+var (
+	dropOrphanedTables bool
+	cleanDevDatasets   []string
+)
+
+func init() {
+	rootCmd.AddCommand(cleanDevCmd)
+	cleanDevCmd.Flags().BoolVarP(&dropOrphanedTables, "yes", "y", false, "Drop the tables, rather than only listing them")
+	cleanDevCmd.Flags().StringSliceVar(&cleanDevDatasets, "dataset", nil, "Also clean this `project.dataset`, which isn't a per-user dataset (can be given multiple times)")
+}
+
+var cleanDevCmd = &cobra.Command{
+	Use:   "clean-dev",
+	Short: "Drops tables in your datasets which no longer belong to a model",
+	Long: "Lists the tables and views in the datasets your models and seeds are written to for the target which " +
+		"don't belong to any model or seed in the project, such as those left behind by renamed or deleted models. " +
+		"Only per-user datasets (model groups using `from_env`) and those given with --dataset are cleaned, as " +
+		"shared datasets hold tables from other pipelines. Nothing is dropped unless --yes is given",
+	Example: "ddbt clean-dev -t dev --yes",
+	Run: func(cmd *cobra.Command, args []string) {
+		fileSystem, _ := compileAllModels()
+		ctx := context.Background()
+
+		orphans, err := findOrphanedTables(ctx, fileSystem, cleanDevDatasets)
+		if err != nil {
+			fmt.Printf("❌ %s\n", err)
+			os.Exit(1)
+		}
+
+		if len(orphans) == 0 {
+			fmt.Printf("✅ Every table in your datasets belongs to a model or seed\n")
+			return
+		}
+
+		for _, orphan := range orphans {
+			fmt.Printf("🗑️ %s\n", orphan)
+		}
+
+		if !dropOrphanedTables {
+			fmt.Printf("\nℹ️  %d tables don't belong to a model or seed, run again with --yes to drop them\n", len(orphans))
+			return
+		}
+
+		failed := false
+		for _, orphan := range orphans {
+			if err := bigquery.DropTable(ctx, orphan.target, orphan.name); err != nil {
+				fmt.Printf("❌ %s\n", err)
+				failed = true
+			}
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+
+		fmt.Printf("\n✅ Dropped %d tables\n", len(orphans))
+	},
+}
+
+// A table in one of the project's datasets which doesn't belong to a model or seed
+type orphanedTable struct {
+	target *config.Target
+	name   string
+}
+
+func (o orphanedTable) String() string {
+	return o.target.ProjectID + "." + o.target.DataSet + "." + o.name
+}
+
+// The datasets the project's models and seeds are written to, keyed by `project.dataset`, and the tables they
+// should contain, keyed by `project.dataset.table`
+func projectTables(fileSystem *fs.FileSystem) (map[string]*config.Target, map[string]struct{}, error) {
+	files := fileSystem.Models()
+	for _, seed := range fileSystem.Seeds() {
+		files = append(files, seed.File)
+	}
+
+	datasets := make(map[string]*config.Target)
+	tables := make(map[string]struct{})
+
+	for _, file := range files {
+		if file.GetMaterialization() == "ephemeral" {
+			continue
+		}
+
+		target, err := file.GetTarget()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		dataset := target.ProjectID + "." + target.DataSet
+		datasets[dataset] = target
+		tables[dataset+"."+file.Name] = struct{}{}
+	}
+
+	return datasets, tables, nil
+}
+
+// The names of the datasets which are safe to clean; those which belong to a single user or are in the allow list.
+// Any other dataset may be shared with other pipelines and users, so it is returned as skipped
+func cleanableDatasets(datasets map[string]*config.Target, allowList []string) (cleanable []string, skipped []string) {
+	allowed := make(map[string]struct{}, len(allowList))
+	for _, name := range allowList {
+		allowed[name] = struct{}{}
+	}
+
+	cleanable = make([]string, 0, len(datasets))
+	skipped = make([]string, 0)
+
+	for name, target := range datasets {
+		if _, found := allowed[name]; found || target.PerUserDataset {
+			cleanable = append(cleanable, name)
+		} else {
+			skipped = append(skipped, name)
+		}
+	}
+
+	sort.Strings(cleanable)
+	sort.Strings(skipped)
+
+	return cleanable, skipped
+}
+
+// Finds the tables in the project's per-user (or allowed) datasets which don't belong to a model or seed
+func findOrphanedTables(ctx context.Context, fileSystem *fs.FileSystem, allowList []string) ([]orphanedTable, error) {
+	datasets, expected, err := projectTables(fileSystem)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range allowList {
+		if _, found := datasets[name]; !found {
+			return nil, fmt.Errorf("no model or seed is written to the dataset `%s`", name)
+		}
+	}
+
+	names, skipped := cleanableDatasets(datasets, allowList)
+	for _, name := range skipped {
+		fmt.Printf("⏭️  Skipping %s as it isn't a per-user dataset, pass --dataset=%s to clean it\n", name, name)
+	}
+
+	if len(names) == 0 {
+		return nil, errors.New("none of the project's datasets are per-user datasets; pass the datasets to clean with --dataset")
+	}
+
+	orphans := make([]orphanedTable, 0)
+	for _, name := range names {
+		target := datasets[name]
+
+		tables, err := bigquery.ListTables(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(tables)
+
+		for _, table := range tables {
+			if _, found := expected[name+"."+table]; !found {
+				orphans = append(orphans, orphanedTable{target, table})
+			}
+		}
+	}
+
+	return orphans, nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/compilerInterface"
+	"ddbt/config"
+	"ddbt/fs"
+)
+
+func TestProjectTables(t *testing.T) {
+	config.GlobalCfg = &config.Config{
+		Target: &config.Target{ProjectID: "project", DataSet: "dbt_someone_dev", PerUserDataset: true},
+	}
+
+	fileSystem, err := fs.InMemoryFileSystem(map[string]string{
+		"models/model_a.sql":   "SELECT 1",
+		"models/model_b.sql":   "SELECT 2",
+		"models/ephemeral.sql": "SELECT 3",
+	})
+	require.NoError(t, err)
+
+	fileSystem.Model("ephemeral").FolderConfig.Materialized = "ephemeral"
+	fileSystem.Model("model_b").SetConfig("schema", compilerInterface.NewString("other_dataset"))
+
+	datasets, tables, err := projectTables(fileSystem)
+	require.NoError(t, err)
+
+	assert.Len(t, datasets, 2)
+	assert.Contains(t, datasets, "project.dbt_someone_dev")
+	assert.Contains(t, datasets, "project.other_dataset")
+
+	assert.Equal(
+		t,
+		map[string]struct{}{
+			"project.dbt_someone_dev.model_a": {},
+			"project.other_dataset.model_b":   {},
+		},
+		tables,
+		"ephemeral models don't have a table",
+	)
+}
+
+func TestCleanableDatasets(t *testing.T) {
+	config.GlobalCfg = &config.Config{
+		Target: &config.Target{ProjectID: "project", DataSet: "dbt_someone_dev", PerUserDataset: true},
+	}
+
+	fileSystem, err := fs.InMemoryFileSystem(map[string]string{
+		"models/model_a.sql": "SELECT 1",
+		"models/model_b.sql": "SELECT 2",
+		"models/model_c.sql": "SELECT 3",
+	})
+	require.NoError(t, err)
+
+	// Models which override their dataset write to a shared dataset
+	fileSystem.Model("model_b").SetConfig("schema", compilerInterface.NewString("shared"))
+	fileSystem.Model("model_c").SetConfig("schema", compilerInterface.NewString("allowed"))
+
+	datasets, _, err := projectTables(fileSystem)
+	require.NoError(t, err)
+
+	cleanable, skipped := cleanableDatasets(datasets, []string{"project.allowed"})
+	assert.Equal(t, []string{"project.allowed", "project.dbt_someone_dev"}, cleanable)
+	assert.Equal(t, []string{"project.shared"}, skipped)
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"ddbt/config"
+)
+
+var packagesMirror string
+
+// Package names become folder names, so they must be plain identifiers as dbt requires
+var validPackageName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func init() {
+	rootCmd.AddCommand(depsCmd)
+	depsCmd.Flags().StringVar(&packagesMirror, "mirror", "", "Install git packages from this folder of vendored copies, rather than fetching them with git")
+}
+
+var depsCmd = &cobra.Command{
+	Use:   "deps",
+	Short: "Installs the packages listed in packages.yml",
+	Long: "Installs the packages listed in packages.yml into " + config.PackagesFolder + ", from which their macros and models " +
+		"are read. Packages can be `local:` folders or `git:` repositories at a `revision:` tag or branch. With --mirror, git " +
+		"packages are copied from `<mirror>/<repository>@<revision>` (or `<mirror>/<repository>` if they have no revision) " +
+		"instead, so no network access is needed",
+	Example: "ddbt deps --mirror ./vendor/packages",
+	Run: func(cmd *cobra.Command, args []string) {
+		packages, err := config.ReadPackages("packages.yml")
+		if err != nil {
+			fmt.Printf("❌ %s\n", err)
+			os.Exit(1)
+		}
+
+		if len(packages) == 0 {
+			fmt.Println("ℹ️  No packages are listed in packages.yml")
+			return
+		}
+
+		if err := installPackages(os.Stdout, packages, config.PackagesFolder, packagesMirror); err != nil {
+			fmt.Printf("❌ %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✅ Installed %d packages\n", len(packages))
+	},
+}
+
+// Installs each package into its own folder of the packages folder, named after its project
+func installPackages(out io.Writer, packages []config.Package, packagesFolder string, mirror string) error {
+	if err := os.MkdirAll(packagesFolder, os.ModePerm); err != nil {
+		return err
+	}
+
+	for _, pkg := range packages {
+		name, err := installPackage(pkg, packagesFolder, mirror)
+		if err != nil {
+			return fmt.Errorf("Unable to install %s: %s", pkg, err)
+		}
+
+		fmt.Fprintf(out, "📦 Installed %s from %s\n", name, pkg)
+	}
+
+	return nil
+}
+
+func installPackage(pkg config.Package, packagesFolder string, mirror string) (string, error) {
+	// Packages are fetched into a hidden folder first, so a failed install doesn't leave half a package behind
+	tmpFolder, err := ioutil.TempDir(packagesFolder, ".installing-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpFolder)
+
+	switch {
+	case pkg.Local != "":
+		err = copyFolder(pkg.Local, tmpFolder)
+
+	case mirror != "":
+		mirrorName := pkg.DefaultName()
+		if pkg.Revision != "" {
+			mirrorName += "@" + pkg.Revision
+		}
+
+		err = copyFolder(filepath.Join(mirror, mirrorName), tmpFolder)
+
+	default:
+		err = gitClone(pkg.Git, pkg.Revision, tmpFolder)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	name, err := config.ProjectName(tmpFolder)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		name = pkg.DefaultName()
+	}
+
+	if !validPackageName.MatchString(name) {
+		return "", fmt.Errorf("invalid package name `%s`, package names must only contain letters, digits and underscores", name)
+	}
+
+	installFolder := filepath.Join(packagesFolder, name)
+
+	// Never remove anything outside of the packages folder
+	if relPath, err := filepath.Rel(packagesFolder, installFolder); err != nil || relPath == "." || strings.HasPrefix(relPath, "..") || filepath.IsAbs(relPath) {
+		return "", fmt.Errorf("package `%s` would be installed outside of %s", name, packagesFolder)
+	}
+
+	if err := os.RemoveAll(installFolder); err != nil {
+		return "", err
+	}
+
+	return name, os.Rename(tmpFolder, installFolder)
+}
+
+// Clones a tag or branch of a repository, without its history
+func gitClone(repository string, revision string, folder string) error {
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if revision != "" {
+		args = append(args, "--branch", revision)
+	}
+	args = append(args, repository, folder)
+
+	if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("git clone failed: %s %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return os.RemoveAll(filepath.Join(folder, ".git"))
+}
+
+// Copies the contents of a folder into another, which must already exist
+func copyFolder(from string, to string) error {
+	if stat, err := os.Stat(from); err != nil {
+		return err
+	} else if !stat.IsDir() {
+		return fmt.Errorf("%s is not a folder", from)
+	}
+
+	return filepath.Walk(from, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(to, relPath)
+
+		switch {
+		case info.IsDir() && info.Name() == ".git":
+			return filepath.SkipDir
+
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode())
+
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			return os.Symlink(link, target)
+
+		default:
+			contents, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			return ioutil.WriteFile(target, contents, info.Mode())
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/config"
+	"ddbt/fs"
+)
+
+func writeFiles(t *testing.T, folder string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(folder, name)
+
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	}
+}
+
+func TestInstallAndReadPackages(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() { require.NoError(t, os.Chdir(wd)) }()
+
+	require.NoError(t, os.Chdir(t.TempDir()))
+
+	writeFiles(t, ".", map[string]string{
+		"macros/helper.sql":        "{% macro helper() %}root{% endmacro %}",
+		"models/model.sql":         "SELECT 1",
+		"tests/.keep":              "",
+		"shared/dbt_project.yml":   "name: shared_macros",
+		"shared/macros/helper.sql": "{% macro helper() %}shared{% endmacro %}",
+		"shared/.git/HEAD":         "ref: refs/heads/main",
+
+		"vendor/dbt-utils@0.8.0/dbt_project.yml":        "name: dbt_utils",
+		"vendor/dbt-utils@0.8.0/macros/star.sql":        "{% macro star() %}*{% endmacro %}",
+		"vendor/dbt-utils@0.8.0/models/utils_model.sql": "SELECT 2",
+	})
+
+	packages := []config.Package{
+		{Local: "shared"},
+		{Git: "https://github.com/dbt-labs/dbt-utils.git", Revision: "0.8.0"},
+	}
+	require.NoError(t, installPackages(ioutil.Discard, packages, config.PackagesFolder, "vendor"))
+
+	// Installing again replaces the packages
+	require.NoError(t, installPackages(ioutil.Discard, packages, config.PackagesFolder, "vendor"))
+
+	installed, err := ioutil.ReadDir(config.PackagesFolder)
+	require.NoError(t, err)
+	require.Len(t, installed, 2)
+	assert.Equal(t, "dbt_utils", installed[0].Name())
+	assert.Equal(t, "shared_macros", installed[1].Name())
+	assert.NoDirExists(t, filepath.Join(config.PackagesFolder, "shared_macros", ".git"))
+
+	fileSystem, err := fs.ReadFileSystem(ioutil.Discard)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join("macros", "helper.sql"), fileSystem.Macro("helper").Path)
+	assert.Equal(t, filepath.Join(config.PackagesFolder, "shared_macros", "macros", "helper.sql"), fileSystem.Macro("shared_macros.helper").Path)
+	assert.Equal(t, "dbt_utils", fileSystem.Macro("dbt_utils.star").Package)
+	assert.Nil(t, fileSystem.Macro("star"))
+
+	require.NotNil(t, fileSystem.Model("utils_model"))
+	assert.Equal(t, "dbt_utils", fileSystem.Model("utils_model").Package)
+}
+
+func TestInstallMissingMirrorPackage(t *testing.T) {
+	folder := t.TempDir()
+
+	err := installPackages(
+		ioutil.Discard,
+		[]config.Package{{Git: "https://github.com/dbt-labs/dbt-utils.git", Revision: "0.8.0"}},
+		filepath.Join(folder, config.PackagesFolder),
+		filepath.Join(folder, "vendor"),
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Unable to install https://github.com/dbt-labs/dbt-utils.git@0.8.0")
+}
+
+func TestInstallPackageWithAnInvalidName(t *testing.T) {
+	folder := t.TempDir()
+	packagesFolder := filepath.Join(folder, "project", config.PackagesFolder)
+
+	writeFiles(t, folder, map[string]string{
+		"project/dbt_project.yml": "name: project",
+		"outside/keep.txt":        "keep",
+	})
+
+	for _, name := range []string{"..", "../..", "'../outside'", filepath.Join(folder, "outside"), "dbt-utils", "1_package"} {
+		pkgFolder := filepath.Join(folder, "hostile")
+		writeFiles(t, pkgFolder, map[string]string{"dbt_project.yml": "name: " + name})
+
+		err := installPackages(ioutil.Discard, []config.Package{{Local: pkgFolder}}, packagesFolder, "")
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), "invalid package name", name)
+	}
+
+	assert.FileExists(t, filepath.Join(folder, "project", "dbt_project.yml"))
+	assert.FileExists(t, filepath.Join(folder, "outside", "keep.txt"))
+}
+
+func TestInstallGitPackage(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	folder := t.TempDir()
+	repository := filepath.Join(folder, "repository")
+
+	writeFiles(t, repository, map[string]string{
+		"dbt_project.yml":  "name: git_package",
+		"macros/macro.sql": "{% macro macro() %}{% endmacro %}",
+	})
+
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "Initial commit"},
+		{"tag", "v1.0.0"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repository
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+
+	packagesFolder := filepath.Join(folder, config.PackagesFolder)
+	require.NoError(t, installPackages(ioutil.Discard, []config.Package{{Git: repository, Revision: "v1.0.0"}}, packagesFolder, ""))
+
+	assert.FileExists(t, filepath.Join(packagesFolder, "git_package", "macros", "macro.sql"))
+	assert.NoDirExists(t, filepath.Join(packagesFolder, "git_package", ".git"))
+}
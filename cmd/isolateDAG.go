@@ -146,6 +146,8 @@ func isolateGraph(graph *fs.Graph) {
 		"debug",
 		"docs",
 		"dbt_modules",
+		"dbt_packages",
+		"packages.yml",
 		"macros",
 		// Hack for migration where we're using two version of dbt
 		"dbt_modules_v0_21_x",
@@ -207,11 +209,19 @@ func isolateGraph(graph *fs.Graph) {
 					return err
 				}
 
+			case fs.SeedNode:
+				// Seeds outside of the DAG are read by the models in it, so their CSV files need to exist too
+				if err := symLink(upstream.Path); err != nil && !os.IsExist(err) {
+					pb.Stop()
+					fmt.Printf("❌ Unable to isolate %s `%s`: %s\n", upstream.Type, upstream.Name, err)
+					return err
+				}
+
 			default:
 				// Any other than a model which is being used _should_ already be in the graph
 				pb.Stop()
 				fmt.Printf("❌ Unexpected Upstream %s `%s`\n", upstream.Type, upstream.Name)
-				return err
+				return fmt.Errorf("unexpected upstream %s `%s`", upstream.Type, upstream.Name)
 			}
 		}
 
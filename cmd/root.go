@@ -21,17 +21,21 @@ var rootCmd = &cobra.Command{
 	Version: utils.DdbtVersion,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		// Do not run init if we're running info commands which aren't actually going to execute operate on a project
-		if cmd != versionCmd && cmd != completionCmd && cmd.Name() != "help" {
+		if cmd == depsCmd {
+			// Installing packages doesn't need a profile or BigQuery, only the project folder
+			cdIntoDBTFolder()
+		} else if cmd != versionCmd && cmd != completionCmd && cmd.Name() != "help" {
 			initDDBT()
 		}
 	},
 }
 
 var (
-	targetProfile   string
-	upstreamProfile string
-	threads         int
+	targetProfile    string
+	upstreamProfile  string
+	threads          int
 	customConfigPath string
+	cliVars          string
 )
 
 func init() {
@@ -39,6 +43,7 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&upstreamProfile, "upstream", "u", "", "Which target profile to use when reading data outside the current DAG")
 	rootCmd.PersistentFlags().IntVar(&threads, "threads", 0, "How many threads to execute with")
 	rootCmd.PersistentFlags().StringVarP(&customConfigPath, "custom-config-path", "c", "", "Pass in a custom config path")
+	rootCmd.PersistentFlags().StringVar(&cliVars, "vars", "", "Supply variables to the project as a YAML dictionary, such as '{key: value}'")
 }
 
 func Execute() {
@@ -52,8 +57,14 @@ func initDDBT() {
 	// If you happen to be one folder up from the DBT project, we'll cd in there for you to be nice :)
 	cdIntoDBTFolder()
 
+	vars, err := config.ParseVars(cliVars)
+	if err != nil {
+		fmt.Printf("❌ %s\n", err)
+		os.Exit(1)
+	}
+
 	// Read the project config
-	cfg, err := config.Read(targetProfile, upstreamProfile, threads, customConfigPath, compiler.CompileStringWithCache)
+	cfg, err := config.Read(targetProfile, upstreamProfile, threads, customConfigPath, vars, compiler.CompileStringWithCache)
 	if err != nil {
 		fmt.Printf("❌ Unable to load config: %s\n", err)
 		os.Exit(1)
@@ -19,12 +19,16 @@ import (
 var ModelFilters []string
 var FailOnNotFound bool
 var EnableSchemaBasedTests bool
+var FullRefresh bool
+var NoGrants bool
 
 func init() {
 	rootCmd.AddCommand(runCmd)
 	addModelsFlag(runCmd)
 	addFailOnNotFoundFlag(runCmd)
 	addEnableSchemaBasedTestsFlag(runCmd)
+	addFullRefreshFlag(runCmd)
+	addNoGrantsFlag(runCmd)
 }
 
 var runCmd = &cobra.Command{
@@ -33,6 +37,9 @@ var runCmd = &cobra.Command{
 	Long:    "Run will execute the request DAG",
 	Example: "ddbt run -m +my_model",
 	Run: func(cmd *cobra.Command, args []string) {
+		config.GlobalCfg.FullRefresh = FullRefresh
+		config.GlobalCfg.NoGrants = NoGrants
+
 		fileSystem, globalContext := compileAllModels()
 
 		// If we've been given a model to run, run it
@@ -60,6 +67,14 @@ func addEnableSchemaBasedTestsFlag(cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(&EnableSchemaBasedTests, "enable-schema-based-tests", "s", false, "Enable Schema-based tests")
 }
 
+func addFullRefreshFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&FullRefresh, "full-refresh", false, "Rebuild incremental models from scratch, unless they set full_refresh=false")
+}
+
+func addNoGrantsFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&NoGrants, "no-grants", false, "Skip applying the grants config of models, for example on dev targets")
+}
+
 func readFileSystem() *fs.FileSystem {
 	// Read the models on the file system
 	fileSystem, err := fs.ReadFileSystem(os.Stderr)
@@ -311,14 +326,70 @@ func buildGraph(fileSystem *fs.FileSystem, modelFilters []string) *fs.Graph {
 	return graph
 }
 
+// Creates any datasets the models or seeds will be written to which don't exist yet
+func createMissingDatasets(files []*fs.File) error {
+	ctx := context.Background()
+	created := make(map[string]struct{})
+
+	for _, file := range files {
+		if file.Type != fs.SeedNode && (file.Type != fs.ModelFile || file.GetMaterialization() == "ephemeral") {
+			continue
+		}
+
+		target, err := file.GetTarget()
+		if err != nil {
+			return err
+		}
+
+		key := target.ProjectID + "." + target.DataSet
+		if _, found := created[key]; found {
+			continue
+		}
+		created[key] = struct{}{}
+
+		wasCreated, err := bigquery.CreateDatasetIfMissing(ctx, target)
+		if err != nil {
+			return err
+		}
+
+		if wasCreated {
+			fmt.Printf("🗂️ Created dataset %s\n", key)
+		}
+	}
+
+	return nil
+}
+
+// Returns all the files in the graph
+func graphFiles(graph *fs.Graph) []*fs.File {
+	files := make([]*fs.File, 0, graph.Len())
+	for file := range graph.ListNodes() {
+		files = append(files, file)
+	}
+
+	return files
+}
+
 func executeGraph(graph *fs.Graph, globalContext *compiler.GlobalContext) error {
+	if err := createMissingDatasets(graphFiles(graph)); err != nil {
+		fmt.Printf("❌ %s\n", err)
+		return err
+	}
+
 	pb := utils.NewProgressBar("🚀 Executing DAG", graph.Len())
 	defer pb.Stop()
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return graph.Execute(func(file *fs.File) error {
-		if file.Type == fs.ModelFile && file.GetMaterialization() != "ephemeral" {
+		if file.Type == fs.SeedNode {
+			if _, err := bigquery.LoadSeedFileIfChanged(ctx, file.Seed); err != nil {
+				pb.Stop()
+				fmt.Printf("❌ Unable to load seed %s: %s\n", file.Name, err)
+				cancel()
+				return err
+			}
+		} else if file.Type == fs.ModelFile && file.GetMaterialization() != "ephemeral" {
 			if file.IsDynamicSQL() || upstreamProfile != "" {
 				if err := compiler.CompileModel(file, globalContext, true); err != nil {
 					pb.Stop()
@@ -328,7 +399,7 @@ func executeGraph(graph *fs.Graph, globalContext *compiler.GlobalContext) error
 				}
 			}
 
-			if queryStr, err := bigquery.Run(ctx, file); err != nil {
+			if queryStr, err := bigquery.Run(ctx, file, atomicTestValidator(globalContext)); err != nil {
 				pb.Stop()
 
 				if err != context.Canceled {
@@ -38,6 +38,14 @@ func loadSeeds(fileSystem *fs.FileSystem) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	files := make([]*fs.File, 0, len(seeds))
+	for _, seed := range seeds {
+		files = append(files, seed.File)
+	}
+	if err := createMissingDatasets(files); err != nil {
+		return err
+	}
+
 	if err := readSeedColumns(ctx, seeds); err != nil {
 		return err
 	}
@@ -4,22 +4,46 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/atotto/clipboard"
 	"github.com/spf13/cobra"
 
 	"ddbt/bigquery"
 	"ddbt/compiler"
+	"ddbt/compilerInterface"
+	"ddbt/config"
 	"ddbt/fs"
 	"ddbt/utils"
 )
 
+var (
+	StoreFailures bool
+	TestSelectors []string
+	TestExcludes  []string
+)
+
 func init() {
 	rootCmd.AddCommand(testCmd)
 	addModelsFlag(testCmd)
 	addFailOnNotFoundFlag(testCmd)
+	addStoreFailuresFlag(testCmd)
+	addTestOutputFlags(testCmd)
+	addTestSelectionFlags(testCmd)
+}
+
+func addTestSelectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&TestSelectors, "select", []string{}, "Select which test(s) to run, by name, tag:x or test_type:schema|data")
+	cmd.Flags().StringArrayVar(&TestExcludes, "exclude", []string{}, "Exclude test(s) from running, by name, tag:x or test_type:schema|data")
+}
+
+func addStoreFailuresFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&StoreFailures, "store-failures", false, "Store the rows which fail tests in the audit dataset")
 }
 
 var testCmd = &cobra.Command{
@@ -28,13 +52,22 @@ var testCmd = &cobra.Command{
 	Long:    "Will execute any tests which reference models in the target DAG",
 	Example: "ddbt test -m +my_model",
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := validateTestOutputFlags(); err != nil {
+			fmt.Printf("❌ %s\n", err)
+			os.Exit(1)
+		}
+
 		fileSystem, globalContext := compileAllModels()
 
 		// If we've been given a model to run, run it
 		graph := buildGraph(fileSystem, ModelFilters)
 
 		// Add all tests which reference the graph
-		tests := graph.AddReferencingTests()
+		tests, err := filterTests(graph.AddReferencingTests(), TestSelectors, TestExcludes)
+		if err != nil {
+			fmt.Printf("❌ %s\n", err)
+			os.Exit(1)
+		}
 
 		if executeTests(tests, globalContext, graph) {
 			os.Exit(2) // Exit with a test error
@@ -48,73 +81,22 @@ func executeTests(tests []*fs.File, globalContext *compiler.GlobalContext, graph
 	ctx, cancel := context.WithCancel(context.Background())
 
 	var m sync.Mutex
-	widestTestName := 0
-	type testResult struct {
-		file  *fs.File
-		name  string
-		rows  uint64
-		err   error
-		query string
-	}
-	testResults := make(map[*fs.File]testResult)
+	testResults := make([]testResult, 0, len(tests))
 
 	_ = fs.ProcessFiles(
 		tests,
 		func(file *fs.File) error {
-			if file.IsDynamicSQL() {
-				if err := compiler.CompileModel(file, globalContext, true); err != nil {
-					pb.Stop()
-					fmt.Printf("❌ %s\n", err)
-					cancel()
-					os.Exit(1)
-				}
+			result, err := runTest(ctx, globalContext, file, nil)
+			if err != nil {
+				pb.Stop()
+				fmt.Printf("❌ %s\n", err)
+				cancel()
+				os.Exit(1)
 			}
 
-			query := bigquery.BuildQuery(file)
-
-			if strings.TrimSpace(query) != "" {
-				target, err := file.GetTarget()
-				if err != nil {
-					pb.Stop()
-					fmt.Printf("❌ Unable to get target for %s: %s\n", file.Name, err)
-					cancel()
-					os.Exit(1)
-				}
-
-				var rows uint64
-
-				if file.GetConfig("isSchemaTest").BooleanValue {
-					// schema tests: applied in YAML, returns the number of records that do not pass an assertion —
-					// when this number is 0, all records pass, therefore, your test passes
-					var results [][]bigquery.Value
-					results, _, err = bigquery.GetRows(ctx, query, target)
-
-					if err == nil {
-						if len(results) != 1 {
-							err = fmt.Errorf("a schema test should only return 1 row, got %d", len(results))
-						} else if len(results[0]) != 1 {
-							err = fmt.Errorf("a schema test should only return 1 column, got %d", len(results[0]))
-						} else {
-							rows, err = bigquery.ValueAsUint64(results[0][0])
-						}
-					}
-				} else {
-					// data tests: specific queries that return 0 records
-					rows, err = bigquery.NumberRows(query, target)
-				}
-
+			if result != nil {
 				m.Lock()
-				testResults[file] = testResult{
-					file:  file,
-					name:  file.Name,
-					rows:  rows,
-					err:   err,
-					query: query,
-				}
-
-				if len(file.Name) > widestTestName {
-					widestTestName = len(file.Name)
-				}
+				testResults = append(testResults, *result)
 				m.Unlock()
 			}
 
@@ -127,47 +109,168 @@ func executeTests(tests []*fs.File, globalContext *compiler.GlobalContext, graph
 
 	pb.Stop()
 
+	// Force these tests to be-rerun in future watch loops
+	for _, result := range testResults {
+		graph.UnmarkFileAsRun(result.file)
+	}
+
+	return printTestResults(testResults)
+}
+
+// Runs a single test, returning nil if the test has no query to run. If ref replacements are given, a copy of the
+// test is compiled with refs to those models replaced, and the test itself is left unchanged.
+//
+// An error is only returned if the test could not be compiled; errors from running the test are part of the result
+func runTest(ctx context.Context, globalContext *compiler.GlobalContext, file *fs.File, refReplacements map[string]string) (*testResult, error) {
+	if refReplacements != nil {
+		test := file
+		file = test.VirtualCopy()
+		file.SetConfig("isSchemaTest", test.GetConfig("isSchemaTest"))
+
+		if err := compiler.CompileModelWithRefReplacements(file, globalContext, true, refReplacements); err != nil {
+			return nil, err
+		}
+	}
+
+	// If we're storing failures the test needs to be recompiled to select the failing rows rather than count them
+	storeFailures := file.GetConfig("store_failures").BooleanValue
+	if StoreFailures && !storeFailures {
+		file.SetConfig("store_failures", compilerInterface.NewBoolean(true))
+		storeFailures = true
+
+		if err := compiler.CompileModelWithRefReplacements(file, globalContext, true, refReplacements); err != nil {
+			return nil, err
+		}
+	} else if file.IsDynamicSQL() && refReplacements == nil {
+		if err := compiler.CompileModel(file, globalContext, true); err != nil {
+			return nil, err
+		}
+	}
+
+	query := bigquery.BuildQuery(file)
+
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+
+	target, err := file.GetTarget()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get target for %s: %s", file.Name, err)
+	}
+
+	var rows uint64
+	var link string
+	start := time.Now()
+
+	if storeFailures {
+		// Both schema and data tests now return the failing rows, which we write into the audit dataset
+		rows, err = bigquery.StoreTestFailures(ctx, file.Name, query, target)
+		link = bigquery.ConsoleURL(target.ProjectID, bigquery.AuditDataset(target), file.Name)
+	} else {
+		rows, err = countTestFailures(ctx, file, query, target)
+	}
+
+	result := &testResult{
+		file:     file,
+		name:     file.Name,
+		rows:     rows,
+		err:      err,
+		query:    query,
+		link:     link,
+		duration: time.Since(start),
+	}
+
+	switch {
+	case result.err == context.Canceled:
+		result.status = testCancelled
+
+	case result.err != nil:
+		result.status = testErrored
+
+	default:
+		result.status, err = testStatus(result.file, result.rows)
+		if err != nil {
+			result.status = testErrored
+			result.err = err
+		}
+	}
+
+	return result, nil
+}
+
+// Prints the results of the tests (and writes the test report if requested), returning true if any test failed
+func printTestResults(testResults []testResult) bool {
+	// Sort the results so the output is stable between runs
+	sort.Slice(testResults, func(i, j int) bool {
+		return testResults[i].name < testResults[j].name
+	})
+
+	widestTestName := 0
+	for _, result := range testResults {
+		if len(result.name) > widestTestName {
+			widestTestName = len(result.name)
+		}
+	}
+
 	var firstError *testResult
+	hasFailures := false
 
 	fmt.Printf("\nTest Results:\n")
-	for test, results := range testResults {
-		results := results
-
-		// Force this test to be-rerun in future watch loops
-		graph.UnmarkFileAsRun(results.file)
+	for i := range testResults {
+		results := &testResults[i]
 
 		var statusText string
 		var statusEmoji rune
 
-		switch {
-		case results.err == context.Canceled:
+		switch results.status {
+		case testCancelled:
 			statusText = "Cancelled"
 			statusEmoji = '🚧'
 
-		case results.err != nil:
+		case testErrored:
 			statusText = fmt.Sprintf("Error: %s", results.err)
 			statusEmoji = '🔴'
 
-		case results.rows > 0:
+		case testFailed:
 			statusText = fmt.Sprintf("%d Failures", results.rows)
 			statusEmoji = '❌'
 
+		case testWarned:
+			statusText = fmt.Sprintf("%d Failures (warning)", results.rows)
+			statusEmoji = '⚠'
+
 		default:
 			statusText = "Success"
 			statusEmoji = '✅'
 		}
 
-		if firstError == nil && statusEmoji != '✅' {
-			firstError = &results
+		if results.status != testPassed && results.status != testWarned {
+			hasFailures = true
+		}
+
+		// Warnings aren't errors, so a later failing test's query is the one copied
+		if firstError == nil && (results.status == testFailed || results.status == testErrored) {
+			firstError = results
 		}
 
 		fmt.Printf(
 			"   %c  %s %s %s\n",
 			statusEmoji,
-			test.Name,
-			strings.Repeat(".", widestTestName-len(test.Name)+3),
+			results.name,
+			strings.Repeat(".", widestTestName-len(results.name)+3),
 			statusText,
 		)
+
+		if results.link != "" && results.status != testPassed && results.status != testErrored {
+			fmt.Printf("         Failing rows stored in %s\n", results.link)
+		}
+	}
+
+	if TestOutputFormat != "" {
+		if err := writeTestReport(testResults); err != nil {
+			fmt.Printf("❌ Unable to write test report: %s\n", err)
+			hasFailures = true
+		}
 	}
 
 	if firstError != nil {
@@ -176,9 +279,210 @@ func executeTests(tests []*fs.File, globalContext *compiler.GlobalContext, graph
 		} else {
 			fmt.Printf("📎 Test Query for %s has been copied into your clipboard\n\n", firstError.name)
 		}
+	}
+
+	return hasFailures
+}
+
+// Filters the tests down to those matching any of the selectors (or all tests if there are no selectors),
+// removing any which match one of the excludes
+func filterTests(tests []*fs.File, selectors []string, excludes []string) ([]*fs.File, error) {
+	filtered := make([]*fs.File, 0, len(tests))
+
+	for _, test := range tests {
+		selected := len(selectors) == 0
+
+		for _, selector := range selectors {
+			matches, err := testMatchesSelector(test, selector)
+			if err != nil {
+				return nil, err
+			}
+
+			if matches {
+				selected = true
+				break
+			}
+		}
+
+		for _, exclude := range excludes {
+			if !selected {
+				break
+			}
+
+			matches, err := testMatchesSelector(test, exclude)
+			if err != nil {
+				return nil, err
+			}
+
+			if matches {
+				selected = false
+			}
+		}
+
+		if selected {
+			filtered = append(filtered, test)
+		}
+	}
+
+	return filtered, nil
+}
+
+// Checks if a test matches a selector, which can be `test_type:schema`, `test_type:data`, `tag:x`,
+// or a glob (optionally prefixed with `test_name:`) which matches against the name of the test
+func testMatchesSelector(test *fs.File, selector string) (bool, error) {
+	switch {
+	case strings.HasPrefix(selector, "test_type:"):
+		isSchemaTest := test.GetConfig("isSchemaTest").BooleanValue
+
+		switch selector[len("test_type:"):] {
+		case "schema":
+			return isSchemaTest, nil
+		case "data":
+			return !isSchemaTest, nil
+		default:
+			return false, fmt.Errorf("unknown test type in selector `%s`, expected `schema` or `data`", selector)
+		}
+
+	case strings.HasPrefix(selector, "tag:"):
+		return test.HasTag(selector[len("tag:"):]), nil
+
+	case strings.HasPrefix(selector, "test_name:"):
+		selector = selector[len("test_name:"):]
+	}
+
+	matches, err := path.Match(selector, test.Name)
+	if err != nil {
+		return false, fmt.Errorf("invalid test selector `%s`: %s", selector, err)
+	}
+
+	return matches, nil
+}
+
+type testResult struct {
+	file     *fs.File
+	name     string
+	rows     uint64
+	err      error
+	query    string
+	link     string
+	duration time.Duration
+	status   testOutcome
+}
+
+type testOutcome int
+
+const (
+	testPassed testOutcome = iota
+	testWarned
+	testFailed
+	testErrored
+	testCancelled
+)
+
+// Runs the test query and returns the number of failures it found; for data tests this is the number of rows
+// returned, whereas schema tests return a single row holding the count
+func countTestFailures(ctx context.Context, file *fs.File, query string, target *config.Target) (uint64, error) {
+	if !file.GetConfig("isSchemaTest").BooleanValue || file.GetConfig("store_failures").BooleanValue {
+		// data tests (and tests selecting their failing rows): specific queries that return 0 records
+		return bigquery.NumberRows(query, target)
+	}
+
+	// schema tests: applied in YAML, returns the number of records that do not pass an assertion —
+	// when this number is 0, all records pass, therefore, your test passes
+	results, _, err := bigquery.GetRows(ctx, query, target)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(results) != 1 {
+		return 0, fmt.Errorf("a schema test should only return 1 row, got %d", len(results))
+	}
+
+	if len(results[0]) != 1 {
+		return 0, fmt.Errorf("a schema test should only return 1 column, got %d", len(results[0]))
+	}
+
+	return bigquery.ValueAsUint64(results[0][0])
+}
+
+// Works out if a test has passed, warned or failed based on the number of failing rows
+// and the severity, warn_if and error_if config of the test
+func testStatus(file *fs.File, failures uint64) (testOutcome, error) {
+	severity := "error"
+	if value := file.GetConfig("severity"); value.Type() == compilerInterface.StringVal && value.StringValue != "" {
+		severity = strings.ToLower(value.StringValue)
+	}
+
+	warnIf := "!=0"
+	if value := file.GetConfig("warn_if"); value.Type() == compilerInterface.StringVal && value.StringValue != "" {
+		warnIf = value.StringValue
+	}
+
+	errorIf := "!=0"
+	if value := file.GetConfig("error_if"); value.Type() == compilerInterface.StringVal && value.StringValue != "" {
+		errorIf = value.StringValue
+	}
+
+	switch severity {
+	case "error":
+		isError, err := testConditionMatches(errorIf, failures)
+		if err != nil {
+			return testFailed, err
+		}
+
+		if isError {
+			return testFailed, nil
+		}
 
-		return true
+	case "warn":
+		// A warning severity test can never error
+
+	default:
+		return testFailed, fmt.Errorf("unknown test severity `%s`", severity)
+	}
+
+	isWarning, err := testConditionMatches(warnIf, failures)
+	if err != nil {
+		return testFailed, err
+	}
+
+	if isWarning {
+		return testWarned, nil
+	}
+
+	return testPassed, nil
+}
+
+// Checks if the number of failures matches a condition such as ">10" or "!=0"
+func testConditionMatches(condition string, failures uint64) (bool, error) {
+	condition = strings.TrimSpace(condition)
+
+	// Check the two character operators before the single character ones
+	for _, operator := range []string{"!=", "==", ">=", "<=", "=", ">", "<"} {
+		if !strings.HasPrefix(condition, operator) {
+			continue
+		}
+
+		threshold, err := strconv.ParseUint(strings.TrimSpace(condition[len(operator):]), 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("unable to parse test condition `%s`: %s", condition, err)
+		}
+
+		switch operator {
+		case "!=":
+			return failures != threshold, nil
+		case "==", "=":
+			return failures == threshold, nil
+		case ">=":
+			return failures >= threshold, nil
+		case "<=":
+			return failures <= threshold, nil
+		case ">":
+			return failures > threshold, nil
+		default:
+			return failures < threshold, nil
+		}
 	}
 
-	return false
+	return false, fmt.Errorf("unable to parse test condition `%s`", condition)
 }
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"ddbt/compilerInterface"
+	"ddbt/fs"
+)
+
+var (
+	TestOutputFormat string
+	TestOutputFile   string
+)
+
+func addTestOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&TestOutputFormat, "output", "", "Write a report of the test results in the given format (junit or json)")
+	cmd.Flags().StringVar(&TestOutputFile, "output-file", "", "The file to write the test report to, which is required with --output")
+}
+
+func validateTestOutputFlags() error {
+	switch TestOutputFormat {
+	case "", "junit", "json":
+	default:
+		return fmt.Errorf("unknown test output format `%s`, expected `junit` or `json`", TestOutputFormat)
+	}
+
+	if TestOutputFile != "" && TestOutputFormat == "" {
+		return fmt.Errorf("--output-file requires an --output format")
+	}
+
+	// The report is written to a file, as stdout is full of progress bars and test results
+	if TestOutputFormat != "" && TestOutputFile == "" {
+		return fmt.Errorf("--output requires an --output-file to write the report to")
+	}
+
+	return nil
+}
+
+// A single test case within a report
+type testReportCase struct {
+	Name            string  `json:"name"`
+	Model           string  `json:"model,omitempty"`
+	Column          string  `json:"column,omitempty"`
+	Severity        string  `json:"severity"`
+	Status          string  `json:"status"`
+	Failures        uint64  `json:"failures"`
+	Error           string  `json:"error,omitempty"`
+	SQL             string  `json:"sql"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+func newTestReportCase(result testResult) testReportCase {
+	reportCase := testReportCase{
+		Name:            result.name,
+		Model:           testModelName(result.file),
+		Column:          result.file.GetConfig("column_name").AsStringValue(),
+		Severity:        "error",
+		Status:          result.status.String(),
+		Failures:        result.rows,
+		SQL:             result.query,
+		DurationSeconds: result.duration.Seconds(),
+	}
+
+	if severity := result.file.GetConfig("severity"); severity.Type() == compilerInterface.StringVal && severity.StringValue != "" {
+		reportCase.Severity = strings.ToLower(severity.StringValue)
+	}
+
+	if result.err != nil {
+		reportCase.Error = result.err.Error()
+	}
+
+	return reportCase
+}
+
+// The model a test is testing; schema tests record this in their config, for data tests we use the models they reference
+func testModelName(file *fs.File) string {
+	if model := file.GetConfig("model"); model.Type() == compilerInterface.StringVal && model.StringValue != "" {
+		return model.StringValue
+	}
+
+	models := make([]string, 0)
+	for _, upstream := range file.Upstreams() {
+		if upstream.Type == fs.ModelFile {
+			models = append(models, upstream.Name)
+		}
+	}
+	sort.Strings(models)
+
+	return strings.Join(models, ",")
+}
+
+func (o testOutcome) String() string {
+	switch o {
+	case testPassed:
+		return "pass"
+	case testWarned:
+		return "warn"
+	case testFailed:
+		return "fail"
+	case testErrored:
+		return "error"
+	case testCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// Writes the test results out to the output file in the requested format
+func writeTestReport(results []testResult) error {
+	cases := make([]testReportCase, len(results))
+	for i, result := range results {
+		cases[i] = newTestReportCase(result)
+	}
+
+	w, err := os.Create(TestOutputFile)
+	if err != nil {
+		return err
+	}
+
+	switch TestOutputFormat {
+	case "junit":
+		err = writeJUnitReport(w, cases)
+	case "json":
+		err = writeJSONReport(w, cases)
+	default:
+		err = fmt.Errorf("unknown test output format `%s`", TestOutputFormat)
+	}
+
+	// Closing flushes the report to disk, so it can fail even if every write succeeded
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func writeJSONReport(w io.Writer, cases []testReportCase) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(struct {
+		Results []testReportCase `json:"results"`
+	}{
+		Results: cases,
+	})
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	ClassName  string          `xml:"classname,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Failure    *junitMessage   `xml:"failure,omitempty"`
+	Error      *junitMessage   `xml:"error,omitempty"`
+	Skipped    *junitMessage   `xml:"skipped,omitempty"`
+	SystemOut  string          `xml:"system-out"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+}
+
+func writeJUnitReport(w io.Writer, cases []testReportCase) error {
+	suite := junitTestSuite{
+		Name:  "ddbt",
+		Tests: len(cases),
+		Cases: make([]junitTestCase, 0, len(cases)),
+	}
+
+	var totalTime float64
+
+	for _, c := range cases {
+		className := c.Model
+		if c.Column != "" {
+			className += "." + c.Column
+		}
+
+		testCase := junitTestCase{
+			Name:      c.Name,
+			ClassName: className,
+			Time:      fmt.Sprintf("%.3f", c.DurationSeconds),
+			Properties: []junitProperty{
+				{Name: "model", Value: c.Model},
+				{Name: "column", Value: c.Column},
+				{Name: "severity", Value: c.Severity},
+				{Name: "status", Value: c.Status},
+				{Name: "failures", Value: fmt.Sprintf("%d", c.Failures)},
+			},
+			SystemOut: c.SQL,
+		}
+
+		switch c.Status {
+		case testFailed.String():
+			suite.Failures++
+			testCase.Failure = &junitMessage{Message: fmt.Sprintf("%d failures", c.Failures), Type: c.Severity}
+
+		case testErrored.String():
+			suite.Errors++
+			testCase.Error = &junitMessage{Message: c.Error}
+
+		case testCancelled.String():
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{Message: "cancelled"}
+		}
+
+		totalTime += c.DurationSeconds
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	suite.Time = fmt.Sprintf("%.3f", totalTime)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	if err := encoder.Encode(junitTestSuites{
+		Name:     suite.Name,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/compilerInterface"
+	"ddbt/fs"
+)
+
+func testReportCases(t *testing.T) []testReportCase {
+	fileSystem, err := fs.InMemoryFileSystem(map[string]string{})
+	require.NoError(t, err)
+
+	newResult := func(name string, rows uint64, status testOutcome, err error) testResult {
+		file, fileErr := fileSystem.AddTestWithContents(name, "SELECT 1", true)
+		require.NoError(t, fileErr)
+
+		file.SetConfig("model", compilerInterface.NewString("model_a"))
+		file.SetConfig("column_name", compilerInterface.NewString("id"))
+
+		return testResult{
+			file:     file,
+			name:     name,
+			rows:     rows,
+			err:      err,
+			query:    "SELECT 1",
+			duration: 1500 * time.Millisecond,
+			status:   status,
+		}
+	}
+
+	return []testReportCase{
+		newTestReportCase(newResult("not_null_model_a__id_0", 0, testPassed, nil)),
+		newTestReportCase(newResult("unique_model_a__id_1", 2, testFailed, nil)),
+		newTestReportCase(newResult("unique_model_a__id_2", 0, testErrored, errors.New("bad query"))),
+	}
+}
+
+func TestJSONTestReport(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeJSONReport(&buf, testReportCases(t)))
+
+	assert.JSONEq(t, `{"results": [
+		{"name": "not_null_model_a__id_0", "model": "model_a", "column": "id", "severity": "error", "status": "pass", "failures": 0, "sql": "SELECT 1", "duration_seconds": 1.5},
+		{"name": "unique_model_a__id_1", "model": "model_a", "column": "id", "severity": "error", "status": "fail", "failures": 2, "sql": "SELECT 1", "duration_seconds": 1.5},
+		{"name": "unique_model_a__id_2", "model": "model_a", "column": "id", "severity": "error", "status": "error", "failures": 0, "error": "bad query", "sql": "SELECT 1", "duration_seconds": 1.5}
+	]}`, buf.String())
+}
+
+func TestJUnitTestReport(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeJUnitReport(&buf, testReportCases(t)))
+
+	output := buf.String()
+	assert.Contains(t, output, `<testsuites name="ddbt" tests="3" failures="1" errors="1" skipped="0" time="4.500">`)
+	assert.Contains(t, output, `<testcase name="not_null_model_a__id_0" classname="model_a.id" time="1.500">`)
+	assert.Contains(t, output, `<failure message="2 failures" type="error"></failure>`)
+	assert.Contains(t, output, `<error message="bad query"></error>`)
+	assert.Contains(t, output, `<property name="severity" value="error"></property>`)
+	assert.Contains(t, output, `<system-out>SELECT 1</system-out>`)
+}
+
+func TestTestReportNeedsAnOutputFile(t *testing.T) {
+	defer func() { TestOutputFormat, TestOutputFile = "", "" }()
+
+	TestOutputFormat, TestOutputFile = "junit", ""
+	err := validateTestOutputFlags()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--output requires an --output-file")
+
+	TestOutputFormat, TestOutputFile = "json", "report.json"
+	assert.NoError(t, validateTestOutputFlags())
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/compilerInterface"
+	"ddbt/fs"
+)
+
+func TestTestConditionMatches(t *testing.T) {
+	cases := []struct {
+		condition string
+		failures  uint64
+		expected  bool
+	}{
+		{"!=0", 0, false},
+		{"!=0", 1, true},
+		{">10", 10, false},
+		{">10", 11, true},
+		{">= 10", 10, true},
+		{"<5", 4, true},
+		{"<=5", 6, false},
+		{"=3", 3, true},
+		{"==3", 4, false},
+	}
+
+	for _, c := range cases {
+		matches, err := testConditionMatches(c.condition, c.failures)
+		require.NoError(t, err, c.condition)
+		assert.Equal(t, c.expected, matches, "%s with %d failures", c.condition, c.failures)
+	}
+
+	_, err := testConditionMatches("lots", 1)
+	assert.Error(t, err)
+
+	_, err = testConditionMatches(">ten", 1)
+	assert.Error(t, err)
+}
+
+func TestTestStatus(t *testing.T) {
+	fileSystem, err := fs.InMemoryFileSystem(map[string]string{})
+	require.NoError(t, err)
+
+	newTest := func(name string, config map[string]string) *fs.File {
+		file, err := fileSystem.AddTestWithContents(name, "SELECT 1", true)
+		require.NoError(t, err)
+
+		for key, value := range config {
+			file.SetConfig(key, compilerInterface.NewString(value))
+		}
+
+		return file
+	}
+
+	defaultTest := newTest("default", nil)
+	warnTest := newTest("warn", map[string]string{"severity": "warn"})
+	thresholdTest := newTest("threshold", map[string]string{"warn_if": ">10", "error_if": ">100"})
+
+	cases := []struct {
+		file     *fs.File
+		failures uint64
+		expected testOutcome
+	}{
+		{defaultTest, 0, testPassed},
+		{defaultTest, 1, testFailed},
+		{warnTest, 0, testPassed},
+		{warnTest, 1000, testWarned},
+		{thresholdTest, 10, testPassed},
+		{thresholdTest, 11, testWarned},
+		{thresholdTest, 101, testFailed},
+	}
+
+	for _, c := range cases {
+		status, err := testStatus(c.file, c.failures)
+		require.NoError(t, err)
+		assert.Equal(t, c.expected, status, "%s with %d failures", c.file.Name, c.failures)
+	}
+}
+
+func TestFilterTests(t *testing.T) {
+	fileSystem, err := fs.InMemoryFileSystem(map[string]string{})
+	require.NoError(t, err)
+
+	newTest := func(name string, isSchemaTest bool, tags ...string) *fs.File {
+		file, err := fileSystem.AddTestWithContents(name, "SELECT 1", isSchemaTest)
+		require.NoError(t, err)
+
+		file.FolderConfig.Tags = tags
+
+		return file
+	}
+
+	uniqueTest := newTest("unique_model_a__id_0", true, "fast")
+	relationshipTest := newTest("relationships_model_a__parent_id_0", true, "slow")
+	dataTest := newTest("model_a_is_not_empty", false)
+	tests := []*fs.File{uniqueTest, relationshipTest, dataTest}
+
+	cases := []struct {
+		selectors []string
+		excludes  []string
+		expected  []*fs.File
+	}{
+		{nil, nil, tests},
+		{[]string{"test_type:schema"}, nil, []*fs.File{uniqueTest, relationshipTest}},
+		{[]string{"test_type:data"}, nil, []*fs.File{dataTest}},
+		{[]string{"tag:fast"}, nil, []*fs.File{uniqueTest}},
+		{[]string{"unique_*"}, nil, []*fs.File{uniqueTest}},
+		{[]string{"test_name:*_model_a__*"}, nil, []*fs.File{uniqueTest, relationshipTest}},
+		{[]string{"tag:fast", "test_type:data"}, nil, []*fs.File{uniqueTest, dataTest}},
+		{nil, []string{"tag:slow"}, []*fs.File{uniqueTest, dataTest}},
+		{[]string{"test_type:schema"}, []string{"relationships_*"}, []*fs.File{uniqueTest}},
+	}
+
+	for _, c := range cases {
+		filtered, err := filterTests(tests, c.selectors, c.excludes)
+		require.NoError(t, err)
+		assert.Equal(t, c.expected, filtered, "select %v exclude %v", c.selectors, c.excludes)
+	}
+
+	_, err = filterTests(tests, []string{"test_type:unknown"}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,383 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"ddbt/bigquery"
+	"ddbt/compiler"
+	"ddbt/compilerInterface"
+	"ddbt/config"
+	"ddbt/fs"
+	"ddbt/properties"
+	"ddbt/utils"
+)
+
+func init() {
+	rootCmd.AddCommand(unitTestCmd)
+	addModelsFlag(unitTestCmd)
+	addFailOnNotFoundFlag(unitTestCmd)
+}
+
+var unitTestCmd = &cobra.Command{
+	Use:     "unit-test",
+	Short:   "Runs the unit tests defined for models",
+	Long:    "Compiles each model with unit tests with every ref replaced by the given fixture rows, then compares the output to the expected rows",
+	Example: "ddbt unit-test -m my_model",
+	Run: func(cmd *cobra.Command, args []string) {
+		fileSystem, globalContext := compileAllModels()
+
+		unitTests := allUnitTests(fileSystem)
+
+		// If we've been given models, only run the unit tests for them
+		if len(ModelFilters) > 0 {
+			_ = buildGraph(fileSystem, ModelFilters)
+
+			filtered := make([]*properties.UnitTest, 0, len(unitTests))
+			for _, unitTest := range unitTests {
+				if model := fileSystem.Model(unitTest.Model); model != nil && model.IsInDAG() {
+					filtered = append(filtered, unitTest)
+				}
+			}
+			unitTests = filtered
+		}
+
+		if executeUnitTests(unitTests, fileSystem, globalContext) {
+			os.Exit(2) // Exit with a test error
+		}
+	},
+}
+
+// All the unit tests defined in the schema files, sorted by name
+func allUnitTests(fileSystem *fs.FileSystem) []*properties.UnitTest {
+	unitTests := make([]*properties.UnitTest, 0)
+
+	for _, schema := range fileSystem.AllSchemas() {
+		unitTests = append(unitTests, schema.Properties.UnitTests...)
+	}
+
+	sort.Slice(unitTests, func(i, j int) bool {
+		return unitTests[i].Name < unitTests[j].Name
+	})
+
+	return unitTests
+}
+
+func executeUnitTests(unitTests []*properties.UnitTest, fileSystem *fs.FileSystem, gc *compiler.GlobalContext) bool {
+	pb := utils.NewProgressBar("🧪 Running Unit Tests", len(unitTests))
+
+	type unitTestResult struct {
+		name       string
+		err        error
+		missing    []string
+		unexpected []string
+	}
+	results := make([]unitTestResult, 0, len(unitTests))
+	widestTestName := 0
+
+	for _, unitTest := range unitTests {
+		missing, unexpected, err := runUnitTest(context.Background(), unitTest, fileSystem, gc)
+
+		results = append(results, unitTestResult{
+			name:       unitTest.Name,
+			err:        err,
+			missing:    missing,
+			unexpected: unexpected,
+		})
+
+		if len(unitTest.Name) > widestTestName {
+			widestTestName = len(unitTest.Name)
+		}
+
+		pb.Increment()
+	}
+
+	pb.Stop()
+
+	hasFailures := false
+
+	fmt.Printf("\nUnit Test Results:\n")
+	for _, result := range results {
+		var statusText string
+		var statusEmoji rune
+
+		switch {
+		case result.err != nil:
+			statusText = fmt.Sprintf("Error: %s", result.err)
+			statusEmoji = '🔴'
+
+		case len(result.missing) > 0 || len(result.unexpected) > 0:
+			statusText = fmt.Sprintf("%d missing rows, %d unexpected rows", len(result.missing), len(result.unexpected))
+			statusEmoji = '❌'
+
+		default:
+			statusText = "Success"
+			statusEmoji = '✅'
+		}
+
+		if statusEmoji != '✅' {
+			hasFailures = true
+		}
+
+		fmt.Printf(
+			"   %c  %s %s %s\n",
+			statusEmoji,
+			result.name,
+			strings.Repeat(".", widestTestName-len(result.name)+3),
+			statusText,
+		)
+
+		for _, row := range result.missing {
+			fmt.Printf("         - %s\n", row)
+		}
+
+		for _, row := range result.unexpected {
+			fmt.Printf("         + %s\n", row)
+		}
+	}
+
+	return hasFailures
+}
+
+// Runs a unit test, returning the expected rows which were missing from the output of the model
+// and the rows in the output which were not expected
+func runUnitTest(ctx context.Context, unitTest *properties.UnitTest, fileSystem *fs.FileSystem, gc *compiler.GlobalContext) ([]string, []string, error) {
+	model := fileSystem.Model(unitTest.Model)
+	if model == nil {
+		return nil, nil, fmt.Errorf("Unable to find model `%s`", unitTest.Model)
+	}
+
+	target, err := model.GetTarget()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fixtureTypes, err := unitTestFixtureTypes(ctx, unitTest, fileSystem, target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query, err := buildUnitTestQuery(model, unitTest, gc, fixtureTypes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	actualRows, actualSchema, err := bigquery.GetRows(ctx, query, target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The expected rows are cast to the types the model outputs, so they can match
+	expectedQuery, err := unitTest.Expect.ToTypedSQL(bigquery.ColumnTypes(actualSchema))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expectedRows, expectedSchema, err := bigquery.GetRows(ctx, expectedQuery, target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := make([]string, len(expectedSchema))
+	for i, field := range expectedSchema {
+		columns[i] = field.Name
+	}
+
+	actualColumns := make([]string, len(actualSchema))
+	for i, field := range actualSchema {
+		actualColumns[i] = field.Name
+	}
+
+	return diffUnitTestRows(columns, expectedRows, actualColumns, actualRows)
+}
+
+// The column types of each model the unit test gives rows for, keyed by the ref name, so the fixtures have the
+// same types as the models they replace. The types come from a dry run of the model, or the table of a seed
+func unitTestFixtureTypes(ctx context.Context, unitTest *properties.UnitTest, fileSystem *fs.FileSystem, target *config.Target) (map[string]map[string]string, error) {
+	fixtureTypes := make(map[string]map[string]string)
+
+	for _, input := range unitTest.Given {
+		if input.SQL != "" {
+			continue
+		}
+
+		refName, err := input.RefName()
+		if err != nil {
+			return nil, err
+		}
+
+		var schema bigquery.Schema
+		if upstream := fileSystem.Model(refName); upstream != nil {
+			schema, err = bigquery.DryRunSchema(ctx, bigquery.BuildQuery(upstream), target)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to get the column types of model %s: %s", refName, err)
+			}
+		} else if seed := fileSystem.Seed(refName); seed != nil {
+			seedTarget, err := seed.GetTarget()
+			if err != nil {
+				return nil, err
+			}
+
+			schema, err = bigquery.GetColumnsFromTableWithContext(
+				ctx,
+				fmt.Sprintf("`%s`.`%s`.`%s`", seedTarget.ProjectID, seedTarget.DataSet, refName),
+				seedTarget,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to get the column types of seed %s: %s", refName, err)
+			}
+		} else {
+			return nil, fmt.Errorf("Unable to find model `%s` given in unit test %s", refName, unitTest.Name)
+		}
+
+		fixtureTypes[refName] = bigquery.ColumnTypes(schema)
+	}
+
+	return fixtureTypes, nil
+}
+
+// Compiles the model with each ref replaced by a CTE holding the fixture rows for it, cast to the fixture's types
+func buildUnitTestQuery(model *fs.File, unitTest *properties.UnitTest, gc *compiler.GlobalContext, fixtureTypes map[string]map[string]string) (string, error) {
+	refOverrides := make(map[string]string)
+
+	var fixtures strings.Builder
+
+	for _, input := range unitTest.Given {
+		refName, err := input.RefName()
+		if err != nil {
+			return "", err
+		}
+
+		fixture, err := input.ToTypedSQL(fixtureTypes[refName])
+		if err != nil {
+			return "", fmt.Errorf("Unable to build fixture for %s: %s", input.Input, err)
+		}
+
+		cteName := fmt.Sprintf("__dbt__fixture__%s", refName)
+		refOverrides[refName] = cteName
+
+		fixtures.WriteString(cteName)
+		fixtures.WriteString(" AS (\n\t")
+		fixtures.WriteString(strings.Replace(strings.TrimSpace(fixture), "\n", "\n\t", -1))
+		fixtures.WriteString("\n),\n\n")
+	}
+
+	// Compile a copy of the model, so we don't change what the model itself has compiled to
+	file := model.VirtualCopy()
+	if err := compiler.CompileModelWithRefOverrides(file, gc, true, refOverrides); err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+
+	// UDFs are statements of their own, so they go before the query rather than inside its CTEs
+	if udf := file.GetConfig("udf"); udf.Type() == compilerInterface.StringVal {
+		builder.WriteString(udf.StringValue)
+	}
+
+	builder.WriteString("WITH ")
+	builder.WriteString(fixtures.String())
+	builder.WriteString("__dbt__main_query AS (\n\t")
+	builder.WriteString(strings.Replace(strings.TrimSpace(file.CompiledContents), "\n", "\n\t", -1))
+	builder.WriteString("\n)\n\nSELECT * FROM __dbt__main_query")
+
+	return builder.String(), nil
+}
+
+// Compares the rows (ignoring their order) on the expected columns, returning the expected rows which are missing
+// and the actual rows which where not expected
+func diffUnitTestRows(columns []string, expected [][]bigquery.Value, actualColumns []string, actual [][]bigquery.Value) ([]string, []string, error) {
+	actualIndex := make(map[string]int, len(actualColumns))
+	for i, column := range actualColumns {
+		actualIndex[strings.ToLower(column)] = i
+	}
+
+	projection := make([]int, len(columns))
+	for i, column := range columns {
+		index, found := actualIndex[strings.ToLower(column)]
+		if !found {
+			return nil, nil, fmt.Errorf("expected column `%s` is not in the output of the model", column)
+		}
+
+		projection[i] = index
+	}
+
+	// Rows are compared by the type of each value as well as how it's formatted, so `1` doesn't match `'1'` or `1.0`
+	formatRow := func(row []bigquery.Value, indexes []int) (key string, formatted string) {
+		keys := make([]string, len(columns))
+		parts := make([]string, len(columns))
+		for i, column := range columns {
+			var value bigquery.Value
+			if indexes == nil {
+				value = row[i]
+			} else {
+				value = row[indexes[i]]
+			}
+
+			parts[i] = column + ": " + formatUnitTestValue(value)
+			keys[i] = fmt.Sprintf("%T|%s", value, formatUnitTestValue(value))
+		}
+
+		return strings.Join(keys, "\x00"), "{" + strings.Join(parts, ", ") + "}"
+	}
+
+	// Count the expected rows, then remove each actual row we see
+	remaining := make(map[string]int)
+	expectedRows := make(map[string]string)
+	for _, row := range expected {
+		key, formatted := formatRow(row, nil)
+		remaining[key]++
+		expectedRows[key] = formatted
+	}
+
+	unexpected := make([]string, 0)
+	for _, row := range actual {
+		key, formatted := formatRow(row, projection)
+
+		if remaining[key] > 0 {
+			remaining[key]--
+		} else {
+			unexpected = append(unexpected, formatted)
+		}
+	}
+
+	missing := make([]string, 0)
+	for key, count := range remaining {
+		for i := 0; i < count; i++ {
+			missing = append(missing, expectedRows[key])
+		}
+	}
+
+	sort.Strings(missing)
+	sort.Strings(unexpected)
+
+	return missing, unexpected, nil
+}
+
+// Formats a value from BigQuery so values of different types can be told apart; strings are quoted and
+// floats always have a decimal point
+func formatUnitTestValue(value bigquery.Value) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+
+	case string:
+		return strconv.Quote(v)
+
+	case float64:
+		formatted := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(formatted, ".eIN") {
+			formatted += ".0"
+		}
+		return formatted
+
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
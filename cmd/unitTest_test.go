@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/bigquery"
+	"ddbt/compiler"
+	"ddbt/config"
+	"ddbt/fs"
+	"ddbt/properties"
+)
+
+func TestDiffUnitTestRows(t *testing.T) {
+	expected := [][]bigquery.Value{
+		{int64(1), "a"},
+		{int64(2), "b"},
+		{int64(2), "b"},
+	}
+
+	// Same rows in a different order, with an extra column which isn't being checked
+	actual := [][]bigquery.Value{
+		{"x", "b", int64(2)},
+		{"y", "a", int64(1)},
+		{"z", "b", int64(2)},
+	}
+
+	missing, unexpected, err := diffUnitTestRows([]string{"id", "value"}, expected, []string{"other", "VALUE", "id"}, actual)
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+	assert.Empty(t, unexpected)
+
+	// Now with differences
+	actual = [][]bigquery.Value{
+		{"x", "b", int64(2)},
+		{"y", nil, int64(1)},
+	}
+
+	missing, unexpected, err = diffUnitTestRows([]string{"id", "value"}, expected, []string{"other", "value", "id"}, actual)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{id: 1, value: "a"}`, `{id: 2, value: "b"}`}, missing)
+	assert.Equal(t, []string{"{id: 1, value: NULL}"}, unexpected)
+
+	// Values only match if they're the same type, and NULL is only equal to NULL
+	expected = [][]bigquery.Value{
+		{int64(1), nil},
+		{float64(1), "<nil>"},
+		{"1", float64(2.5)},
+	}
+	actual = [][]bigquery.Value{
+		{"1", "<nil>"},
+		{int64(1), nil},
+		{float64(1), float64(2.5)},
+	}
+
+	missing, unexpected, err = diffUnitTestRows([]string{"id", "value"}, expected, []string{"id", "value"}, actual)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{id: "1", value: 2.5}`, `{id: 1.0, value: "<nil>"}`}, missing)
+	assert.Equal(t, []string{`{id: "1", value: "<nil>"}`, `{id: 1.0, value: 2.5}`}, unexpected)
+
+	// Missing columns are an error
+	_, _, err = diffUnitTestRows([]string{"id", "value"}, expected, []string{"id"}, [][]bigquery.Value{{int64(1)}})
+	assert.Error(t, err)
+}
+
+func TestBuildUnitTestQueryWithAUDF(t *testing.T) {
+	config.GlobalCfg = &config.Config{
+		Name:   "Unit Test",
+		Target: &config.Target{ProjectID: "project", DataSet: "dataset"},
+	}
+
+	fileSystem, err := fs.InMemoryFileSystem(map[string]string{
+		"models/model.sql":    "{{ config(udf='CREATE TEMP FUNCTION one() AS (1);\n') }}SELECT one() AS id FROM {{ ref('upstream') }}",
+		"models/upstream.sql": "SELECT 1 AS id",
+	})
+	require.NoError(t, err)
+
+	for _, file := range fileSystem.AllFiles() {
+		require.NoError(t, compiler.ParseFile(file))
+	}
+
+	gc, err := compiler.NewGlobalContext(config.GlobalCfg, fileSystem)
+	require.NoError(t, err)
+
+	unitTest := &properties.UnitTest{
+		Name:  "test_udf",
+		Model: "model",
+		Given: []properties.UnitTestInput{{Input: "ref('upstream')", UnitTestRows: properties.UnitTestRows{SQL: "SELECT 2 AS id"}}},
+	}
+
+	query, err := buildUnitTestQuery(fileSystem.Model("model"), unitTest, gc, nil)
+	require.NoError(t, err)
+
+	// The UDF must be before the WITH, rather than inside a CTE
+	assert.Equal(
+		t,
+		"CREATE TEMP FUNCTION one() AS (1);\n"+
+			"WITH __dbt__fixture__upstream AS (\n\tSELECT 2 AS id\n),\n\n"+
+			"__dbt__main_query AS (\n\tSELECT one() AS id FROM __dbt__fixture__upstream\n)\n\n"+
+			"SELECT * FROM __dbt__main_query",
+		query,
+	)
+}
@@ -23,6 +23,9 @@ func init() {
 	rootCmd.AddCommand(watchCmd)
 	addModelsFlag(watchCmd)
 	addFailOnNotFoundFlag(watchCmd)
+	addStoreFailuresFlag(watchCmd)
+	addFullRefreshFlag(watchCmd)
+	addNoGrantsFlag(watchCmd)
 	watchCmd.Flags().BoolVarP(&skipInitialBuild, "skip-run", "s", false, "Skip the initial execution of the DAG and go straight into watch mode")
 }
 
@@ -32,6 +35,9 @@ var watchCmd = &cobra.Command{
 	Long:    "This mode will run all the models in your DAG and any related tests. Whenever you change a file used in the DAG that part of the DAG will be automatically re-run and re-tested.",
 	Example: "ddbt watch -m +my_model",
 	Run: func(cmd *cobra.Command, args []string) {
+		config.GlobalCfg.FullRefresh = FullRefresh
+		config.GlobalCfg.NoGrants = NoGrants
+
 		// Do the initial build of the models and then add the tests
 		fileSystem, gc := compileAllModels()
 		graph := buildGraph(fileSystem, ModelFilters)
@@ -242,6 +248,13 @@ func handleRerun(graph *fs.Graph, gc *compiler.GlobalContext) ([]*fs.File, error
 			testMutex.Lock()
 			foundTests = append(foundTests, file)
 			testMutex.Unlock()
+		} else if file.Type == fs.SeedNode {
+			if _, err := bigquery.LoadSeedFileIfChanged(ctx, file.Seed); err != nil {
+				pb.Stop()
+				fmt.Printf("⚠️ Unable to load seed %s: %s\n", file.Name, err)
+				cancel()
+				return err
+			}
 		} else if file.Type == fs.ModelFile && file.GetMaterialization() != "ephemeral" {
 			if file.IsDynamicSQL() || upstreamProfile != "" {
 				if err := compiler.CompileModel(file, gc, true); err != nil {
@@ -252,7 +265,7 @@ func handleRerun(graph *fs.Graph, gc *compiler.GlobalContext) ([]*fs.File, error
 				}
 			}
 
-			if queryStr, err := bigquery.Run(ctx, file); err != nil {
+			if queryStr, err := bigquery.Run(ctx, file, atomicTestValidator(gc)); err != nil {
 				pb.Stop()
 
 				if err != context.Canceled {
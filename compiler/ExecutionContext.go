@@ -3,6 +3,7 @@ package compiler
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 
 	"ddbt/compilerInterface"
@@ -20,6 +21,12 @@ type ExecutionContext struct {
 
 	globalContext *GlobalContext
 	parentContext compilerInterface.ExecutionContext
+	packageName   string // Set when running a macro from a package, so it can call the package's other macros
+
+	refOverrides        map[string]string // If set, refs to models are resolved to these values instead (used by unit tests and atomic builds)
+	partialRefOverrides bool              // If set, refs to models without an override are resolved as normal
+
+	statements *statementResults // The results of statement blocks, shared by every state of the file being compiled
 }
 
 // Ensure our execution context matches the interface in the AST package
@@ -33,6 +40,7 @@ func NewExecutionContext(file *fs.File, fileSystem *fs.FileSystem, isExecuting b
 		isExecuting:   isExecuting,
 		globalContext: globalContext,
 		parentContext: parent,
+		statements:    newStatementResults(),
 	}
 }
 
@@ -49,14 +57,28 @@ func (e *ExecutionContext) GetVariable(name string) *compilerInterface.Value {
 	e.varaiblesMutex.RUnlock()
 
 	if !found {
+		if e.packageName != "" {
+			if macro, err := e.globalContext.getPackageMacro(e.packageName, name); err == nil && macro != nil {
+				return compilerInterface.NewFunction(macro)
+			}
+		}
+
 		return e.parentContext.GetVariable(name)
 	} else {
 		return variable
 	}
 }
 
-func (e *ExecutionContext) RegisterMacro(name string, ec compilerInterface.ExecutionContext, function compilerInterface.FunctionDef) {
-	e.parentContext.RegisterMacro(name, ec, function)
+func (e *ExecutionContext) GetFilter(name string) *compilerInterface.Value {
+	return e.parentContext.GetFilter(name)
+}
+
+func (e *ExecutionContext) RegisterMacro(name string, ec compilerInterface.ExecutionContext, function compilerInterface.FunctionDef) error {
+	return e.globalContext.registerMacroInFile(e.file, name, ec, function)
+}
+
+func (e *ExecutionContext) ImportMacros(path string) (*compilerInterface.Value, error) {
+	return e.globalContext.ImportMacros(path)
 }
 
 func (e *ExecutionContext) ErrorAt(part compilerInterface.AST, error string) error {
@@ -73,7 +95,12 @@ func (e *ExecutionContext) NilResultFor(part compilerInterface.AST) error {
 }
 
 func (e *ExecutionContext) PushState() compilerInterface.ExecutionContext {
-	return NewExecutionContext(e.file, e.fileSystem, e.isExecuting, e.globalContext, e)
+	ec := NewExecutionContext(e.file, e.fileSystem, e.isExecuting, e.globalContext, e)
+	ec.refOverrides = e.refOverrides
+	ec.partialRefOverrides = e.partialRefOverrides
+	ec.statements = e.statements
+
+	return ec
 }
 
 func (e *ExecutionContext) CopyVariablesInto(ec compilerInterface.ExecutionContext) {
@@ -92,14 +119,33 @@ func (e *ExecutionContext) RegisterUpstreamAndGetRef(modelName string, fileType
 
 	switch fs.FileType(fileType) {
 	case fs.ModelFile:
+		if e.refOverrides != nil {
+			override, found := e.refOverrides[modelName]
+			if found {
+				return compilerInterface.NewString(override), nil
+			}
+
+			if !e.partialRefOverrides {
+				return nil, fmt.Errorf("No fixture given for ref('%s')", modelName)
+			}
+		}
+
 		upstream = e.fileSystem.Model(modelName)
 
+		// Seeds can be referenced just like models
+		if upstream == nil {
+			if seed := e.fileSystem.Seed(modelName); seed != nil {
+				upstream = seed.File
+			}
+		}
+
 	case fs.MacroFile:
-		upstream = e.fileSystem.Macro(modelName)
+		// Macros are registered by the path of their file
+		upstream = e.fileSystem.MacroAt(modelName)
 
 		if upstream == nil {
 			// For tests
-			upstream = e.fileSystem.Model(modelName)
+			upstream = e.fileSystem.Model(strings.TrimSuffix(modelName, ".sql"))
 		}
 
 	default:
@@ -110,27 +156,33 @@ func (e *ExecutionContext) RegisterUpstreamAndGetRef(modelName string, fileType
 		return nil, fmt.Errorf("Unable to find model `%s`", modelName)
 	}
 
-	e.file.RecordDependencyOn(upstream)
+	// Files compiled with overrides are copies, which shouldn't become part of the graph
+	if e.refOverrides == nil {
+		e.file.RecordDependencyOn(upstream)
+	}
 
 	target, err := upstream.GetTarget()
 	if err != nil {
 		return nil, err
 	}
 
+	// Models referenced with their package are still built under their own name
+	if upstream.Package != "" && modelName == upstream.Package+"."+upstream.Name {
+		modelName = upstream.Name
+	}
+
 	switch upstream.GetMaterialization() {
-	case "table", "incremental", "project_sharded_table", "view":
+	case "table", "incremental", "project_sharded_table", "view", "materialized_view", "seed":
 		//ToDo: views are being treated as tables until they are properly implemented
 
 		// If "--upstream=target" has been provided and this model is not in the DAG, then we read from the upstream
 		// target, rather than the target defined in "--target=target"
+		relationType := relationTypeFor(upstream.GetMaterialization())
+
 		if target.ReadUpstream != nil && !upstream.IsInDAG() {
-			return compilerInterface.NewString(
-				"`" + target.ReadUpstream.ProjectID + "`.`" + target.ReadUpstream.DataSet + "`.`" + modelName + "`",
-			), nil
+			return newRelation(target.ReadUpstream.ProjectID, target.ReadUpstream.DataSet, modelName, relationType).value(), nil
 		} else {
-			return compilerInterface.NewString(
-				"`" + target.ProjectID + "`.`" + target.DataSet + "`.`" + modelName + "`",
-			), nil
+			return newRelation(target.ProjectID, target.DataSet, modelName, relationType).value(), nil
 		}
 
 	case "ephemeral":
@@ -2,6 +2,8 @@ package compiler
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"sync"
 
 	"ddbt/compiler/dbtUtils"
@@ -13,16 +15,21 @@ import (
 type GlobalContext struct {
 	fileSystem *fs.FileSystem
 
-	macroMutex sync.RWMutex
-	macros     map[string]*macroDef
+	macroMutex    sync.RWMutex
+	macros        map[string]*macroDef              // The project's macros by name
+	packageMacros map[string]map[string]*macroDef   // Each package's macros by name
+	fileMacros    map[*fs.File]map[string]*macroDef // The macros defined by each compiled file, for imports
 
 	constants map[string]*compilerInterface.Value
+
+	output io.Writer // Where messages from the project, such as `print_table()`, are written
 }
 
 type macroDef struct {
-	ec       compilerInterface.ExecutionContext
-	function compilerInterface.FunctionDef
-	fileName string
+	ec          compilerInterface.ExecutionContext
+	function    compilerInterface.FunctionDef
+	file        *fs.File
+	packageName string
 }
 
 var _ compilerInterface.ExecutionContext = &GlobalContext{}
@@ -33,8 +40,11 @@ func NewGlobalContext(cfg *config.Config, fileSystem *fs.FileSystem) (*GlobalCon
 	}
 
 	return &GlobalContext{
-		fileSystem: fileSystem,
-		macros:     make(map[string]*macroDef),
+		fileSystem:    fileSystem,
+		macros:        make(map[string]*macroDef),
+		packageMacros: make(map[string]map[string]*macroDef),
+		fileMacros:    make(map[*fs.File]map[string]*macroDef),
+		output:        os.Stderr,
 		constants: map[string]*compilerInterface.Value{
 			"adapter": funcMapAsValue(adapterFunctions),
 
@@ -75,9 +85,32 @@ func NewGlobalContext(cfg *config.Config, fileSystem *fs.FileSystem) (*GlobalCon
 
 			// https://docs.getdbt.com/reference/dbt-jinja-functions/modules
 			"modules": compilerInterface.NewMap(map[string]*compilerInterface.Value{
-				"datetime": funcMapAsValue(datetimeFunctions),
+				"datetime": newDatetimeModule(),
+				"pytz":     newPytzModule(),
+				"re":       newReModule(),
+			}),
+
+			// https://docs.getdbt.com/reference/dbt-jinja-functions/flags
+			"flags": compilerInterface.NewMap(map[string]*compilerInterface.Value{
+				"FULL_REFRESH": compilerInterface.NewBoolean(cfg.FullRefresh),
+			}),
+
+			// https://docs.getdbt.com/reference/dbt-jinja-functions/invocation_id
+			"invocation_id": compilerInterface.NewString(invocationID),
+
+			// https://docs.getdbt.com/reference/dbt-jinja-functions/run_started_at
+			"run_started_at": newDatetimeValue(runStartedAt, true),
+
+			// https://docs.getdbt.com/reference/dbt-classes#relation
+			"api": compilerInterface.NewMap(map[string]*compilerInterface.Value{
+				"Relation": funcMapAsValue(funcMap{
+					"create": apiRelationCreate,
+				}),
 			}),
 
+			// https://docs.getdbt.com/reference/dbt-jinja-functions/var
+			"var": newVarValue(),
+
 			// https://docs.getdbt.com/reference/dbt-jinja-functions/project_name
 			"project_name": compilerInterface.NewString(cfg.Name),
 
@@ -87,6 +120,12 @@ func NewGlobalContext(cfg *config.Config, fileSystem *fs.FileSystem) (*GlobalCon
 	}, nil
 }
 
+// SetOutput changes where messages from the project are written, which is stderr by default so they go to the
+// same place as the progress of the command
+func (g *GlobalContext) SetOutput(output io.Writer) {
+	g.output = output
+}
+
 func (g *GlobalContext) SetVariable(name string, value *compilerInterface.Value) {
 	panic("Cannot set variable on parentContext context - read only during execution")
 }
@@ -108,14 +147,55 @@ func (g *GlobalContext) GetVariable(name string) *compilerInterface.Value {
 	if !found {
 		if builtInFunction != nil {
 			return compilerInterface.NewFunction(builtInFunction)
+		} else if packageMacros := g.fileSystem.PackageMacros(name); packageMacros != nil {
+			return g.packageNamespace(packageMacros, nil)
 		} else {
 			return &compilerInterface.Value{IsUndefined: true}
 		}
+	} else if packageMacros := g.fileSystem.PackageMacros(name); packageMacros != nil {
+		// An installed package takes the place of a built in namespace of the same name, such as `dbt_utils`
+		return g.packageNamespace(packageMacros, variable)
 	} else {
 		return variable
 	}
 }
 
+// The macros of a package, so they can be called as `my_package.my_macro()`. Any functions of the built in namespace
+// the package replaces which it doesn't define itself are kept
+func (g *GlobalContext) packageNamespace(packageMacros []*fs.File, builtIn *compilerInterface.Value) *compilerInterface.Value {
+	namespace := make(map[string]*compilerInterface.Value)
+
+	if builtIn != nil && builtIn.Type() == compilerInterface.MapVal {
+		for name, function := range builtIn.MapValue {
+			namespace[name] = function
+		}
+	}
+
+	for _, file := range packageMacros {
+		// As with the project's macros, any error here is reported when the file itself is compiled
+		macros, err := g.macrosInFile(file)
+		if err != nil {
+			continue
+		}
+
+		for name, macro := range macros {
+			namespace[name] = compilerInterface.NewFunction(g.macroFunction(macro))
+		}
+	}
+
+	return compilerInterface.NewMap(namespace)
+}
+
+// Filters are in their own namespace in Jinja, so a variable named `list` doesn't hide the `list` filter. Any other
+// function can also be used as a filter
+func (g *GlobalContext) GetFilter(name string) *compilerInterface.Value {
+	if filter, found := builtInFilters[name]; found {
+		return compilerInterface.NewFunction(filter)
+	}
+
+	return g.GetVariable(name)
+}
+
 func (g *GlobalContext) ErrorAt(part compilerInterface.AST, error string) error {
 	panic("ErrorAt not implemented for global context")
 }
@@ -133,40 +213,89 @@ func (g *GlobalContext) CopyVariablesInto(_ compilerInterface.ExecutionContext)
 }
 
 func (g *GlobalContext) GetMacro(name string) (compilerInterface.FunctionDef, error) {
-	g.macroMutex.RLock()
-	macro, found := g.macros[name]
-	g.macroMutex.RUnlock()
+	return g.getPackageMacro("", name)
+}
+
+// Returns a macro from the given package, or from the project if the package name is empty
+func (g *GlobalContext) getPackageMacro(packageName string, name string) (compilerInterface.FunctionDef, error) {
+	macro := g.lookupMacro(packageName, name)
 
 	// Check if it's compiled and registered
-	if !found {
-		// Do we have a macro file which isn't compiled yet?
-		if file := g.fileSystem.Macro(name); file != nil {
-			// Compile it
-			if err := ParseFile(file); err != nil {
-				return nil, err
-			}
+	if macro == nil {
+		fileName := name
+		if packageName != "" {
+			fileName = packageName + "." + name
+		}
+
+		// Do we have a macro file which isn't compiled yet? (There may be more than one in different folders)
+		files := g.fileSystem.MacrosNamed(fileName)
+		if len(files) == 0 {
+			// No macro exists for this
+			return nil, nil
+		}
 
-			if err := CompileModel(file, g, true); err != nil {
+		// Compile them
+		for _, file := range files {
+			if _, err := g.macrosInFile(file); err != nil {
 				return nil, err
 			}
+		}
 
-			// Attempt to re-read the compiled macro
-			g.macroMutex.RLock()
-			macro, found = g.macros[name]
-			g.macroMutex.RUnlock()
+		// Attempt to re-read the compiled macro
+		macro = g.lookupMacro(packageName, name)
 
-			// If it's still not found, then the macro is not registering it self with it's filename
-			if !found {
-				return nil, fmt.Errorf("The macro file %s is not registering a macro with the same name!", name)
-			}
-		} else {
-			// No macro exists for this
-			return nil, nil
+		// If it's still not found, then the macro is not registering it self with it's filename
+		if macro == nil {
+			return nil, fmt.Errorf("The macro file %s is not registering a macro with the same name!", fileName)
 		}
 	}
 
+	return g.macroFunction(macro), nil
+}
+
+func (g *GlobalContext) lookupMacro(packageName string, name string) *macroDef {
+	g.macroMutex.RLock()
+	defer g.macroMutex.RUnlock()
+
+	if packageName != "" {
+		return g.packageMacros[packageName][name]
+	}
+
+	return g.macros[name]
+}
+
+// Compiles the macro file if it hasn't been already, and returns the macros it defines
+func (g *GlobalContext) macrosInFile(file *fs.File) (map[string]*macroDef, error) {
+	g.macroMutex.RLock()
+	macros, found := g.fileMacros[file]
+	g.macroMutex.RUnlock()
+
+	if found {
+		return macros, nil
+	}
+
+	if err := ParseFile(file); err != nil {
+		return nil, err
+	}
+
+	if err := CompileModel(file, g, true); err != nil {
+		return nil, err
+	}
+
+	g.macroMutex.Lock()
+	defer g.macroMutex.Unlock()
+
+	// The file might not define any macros at all
+	if _, found := g.fileMacros[file]; !found {
+		g.fileMacros[file] = make(map[string]*macroDef)
+	}
+
+	return g.fileMacros[file], nil
+}
+
+func (g *GlobalContext) macroFunction(macro *macroDef) compilerInterface.FunctionDef {
 	return func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
-		if _, err := ec.RegisterUpstreamAndGetRef(macro.fileName, string(fs.MacroFile)); err != nil {
+		if _, err := ec.RegisterUpstreamAndGetRef(macro.file.Path, string(fs.MacroFile)); err != nil {
 			return nil, ec.ErrorAt(caller, err.Error())
 		}
 
@@ -174,24 +303,93 @@ func (g *GlobalContext) GetMacro(name string) (compilerInterface.FunctionDef, er
 		// Note we copy any varaibles defined within the macro's own file in to the context being executed here too
 		macro.ec.CopyVariablesInto(newEC)
 
-		// We keep the caller and execute context however as these will change from when the macro was registered to when
+		// Macros in a package can call the other macros of the package without naming it
+		if e, ok := newEC.(*ExecutionContext); ok {
+			e.packageName = macro.packageName
+		}
+
+		// We keep the caller, config and execute context however as these will change from when the macro was registered to when
 		// it is called
 		newEC.SetVariable("caller", ec.GetVariable("caller"))
+		newEC.SetVariable("config", ec.GetVariable("config"))
 		newEC.SetVariable("execute", ec.GetVariable("execute"))
 
 		return macro.function(newEC, caller, args)
-	}, nil
+	}
+}
+
+func (g *GlobalContext) RegisterMacro(name string, ec compilerInterface.ExecutionContext, function compilerInterface.FunctionDef) error {
+	e, ok := ec.(*ExecutionContext)
+	if !ok {
+		return fmt.Errorf("the macro %s can only be registered while compiling a file", name)
+	}
+
+	return g.registerMacroInFile(e.file, name, ec, function)
 }
 
-func (g *GlobalContext) RegisterMacro(name string, ec compilerInterface.ExecutionContext, function compilerInterface.FunctionDef) {
+// Registers a macro defined in the given file, within the file's package if it has one. Two macro files can't define
+// macros with the same name, although the project's macros replace the built in macros
+func (g *GlobalContext) registerMacroInFile(file *fs.File, name string, ec compilerInterface.ExecutionContext, function compilerInterface.FunctionDef) error {
 	g.macroMutex.Lock()
 	defer g.macroMutex.Unlock()
 
-	g.macros[name] = &macroDef{
-		ec:       ec,
-		function: function,
-		fileName: ec.FileName(),
+	macro := &macroDef{
+		ec:          ec,
+		function:    function,
+		file:        file,
+		packageName: file.Package,
 	}
+
+	macros := g.macros
+	if file.Package != "" {
+		if _, found := g.packageMacros[file.Package]; !found {
+			g.packageMacros[file.Package] = make(map[string]*macroDef)
+		}
+
+		macros = g.packageMacros[file.Package]
+	}
+
+	existing, found := macros[name]
+	switch {
+	case !found || existing.file.Path == file.Path || file.Type != fs.MacroFile || existing.file.Type != fs.MacroFile:
+		macros[name] = macro
+
+	case existing.file.IsVirtual() && !file.IsVirtual():
+		macros[name] = macro
+
+	case file.IsVirtual() && !existing.file.IsVirtual():
+		// The project's macro has already replaced the built in one
+
+	default:
+		return fmt.Errorf("the macro %s is defined in both %s and %s", name, existing.file.Path, file.Path)
+	}
+
+	if _, found := g.fileMacros[file]; !found {
+		g.fileMacros[file] = make(map[string]*macroDef)
+	}
+	g.fileMacros[file][name] = macro
+
+	return nil
+}
+
+// Returns the macros defined in a macro file as a map, for `{% import 'file.sql' as x %}`
+func (g *GlobalContext) ImportMacros(path string) (*compilerInterface.Value, error) {
+	file := g.fileSystem.MacroAt(path)
+	if file == nil {
+		return nil, fmt.Errorf("unable to find macro file `%s`", path)
+	}
+
+	macros, err := g.macrosInFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := make(map[string]*compilerInterface.Value, len(macros))
+	for name, macro := range macros {
+		imported[name] = compilerInterface.NewFunction(g.macroFunction(macro))
+	}
+
+	return compilerInterface.NewMap(imported), nil
 }
 
 func (g *GlobalContext) RegisterUpstreamAndGetRef(name string, fileType string) (*compilerInterface.Value, error) {
@@ -214,17 +412,24 @@ func (g *GlobalContext) UnregisterMacrosInFile(file *fs.File) {
 	g.macroMutex.Lock()
 	defer g.macroMutex.Unlock()
 
+	macros := g.macros
+	if file.Package != "" {
+		macros = g.packageMacros[file.Package]
+	}
+
 	toDelete := make([]string, 0)
 
 	// In case the macro name is different to the file name
 	// as one file might contain multiple macro's
-	for key, macroDef := range g.macros {
-		if macroDef.fileName == file.Name {
+	for key, macroDef := range macros {
+		if macroDef.file.Path == file.Path {
 			toDelete = append(toDelete, key)
 		}
 	}
 
 	for _, key := range toDelete {
-		delete(g.macros, key)
+		delete(macros, key)
 	}
+
+	delete(g.fileMacros, file)
 }
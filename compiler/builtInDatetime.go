@@ -0,0 +1,1017 @@
+package compiler
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // So pytz.timezone works the same on every machine
+
+	"ddbt/compiler/dbtUtils"
+	"ddbt/compilerInterface"
+)
+
+// The Go values behind the objects of Python's datetime module, stored in compilerInterface.Value.Object
+type pyDatetime struct {
+	time  time.Time
+	aware bool // Naive datetimes (without a tzinfo) are held in UTC
+}
+
+type pyDate struct {
+	time time.Time
+}
+
+type pyTimedelta struct {
+	duration time.Duration
+}
+
+type pyTimezone struct {
+	location *time.Location
+}
+
+// The time dbt was started, which is the same for every model in the run
+var runStartedAt = time.Now().UTC()
+
+// https://docs.python.org/3/library/datetime.html
+func newDatetimeModule() *compilerInterface.Value {
+	datetime := compilerInterface.NewFunction(datetimeConstructor)
+	datetime.MapValue = map[string]*compilerInterface.Value{
+		"now": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("tz"))
+			if err != nil {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+			}
+
+			if arguments[0].Type() == compilerInterface.Undefined || arguments[0].Type() == compilerInterface.NullVal {
+				return newDatetimeValue(time.Now().UTC(), false), nil
+			}
+
+			location, err := locationFromValue(arguments[0])
+			if err != nil {
+				return nil, ec.ErrorAt(caller, err.Error())
+			}
+
+			return newDatetimeValue(time.Now().In(location), true), nil
+		}),
+
+		"utcnow": compilerInterface.NewFunction(func(_ compilerInterface.ExecutionContext, _ compilerInterface.AST, _ compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			return newDatetimeValue(time.Now().UTC(), false), nil
+		}),
+
+		"today": compilerInterface.NewFunction(func(_ compilerInterface.ExecutionContext, _ compilerInterface.AST, _ compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			return newDatetimeValue(time.Now().UTC(), false), nil
+		}),
+
+		"strptime": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			values, err := requiredArgs(ec, caller, args, "datetime.strptime", compilerInterface.StringVal, compilerInterface.StringVal)
+			if err != nil {
+				return nil, err
+			}
+
+			t, aware, err := strptime(values[0].StringValue, values[1].StringValue)
+			if err != nil {
+				return nil, ec.ErrorAt(caller, err.Error())
+			}
+
+			return newDatetimeValue(t, aware), nil
+		}),
+
+		"fromisoformat": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			values, err := requiredArgs(ec, caller, args, "datetime.fromisoformat", compilerInterface.StringVal)
+			if err != nil {
+				return nil, err
+			}
+
+			t, aware, err := fromISOFormat(values[0].StringValue)
+			if err != nil {
+				return nil, ec.ErrorAt(caller, err.Error())
+			}
+
+			return newDatetimeValue(t, aware), nil
+		}),
+
+		"fromtimestamp": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			arguments, err := dbtUtils.GetArgs(args, dbtUtils.ParamWithDefault("timestamp", compilerInterface.NewNumber(0)), dbtUtils.Param("tz"))
+			if err != nil {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+			}
+
+			t := timeFromTimestamp(arguments[0].NumberValue)
+			if arguments[1].Type() == compilerInterface.Undefined || arguments[1].Type() == compilerInterface.NullVal {
+				return newDatetimeValue(t, false), nil
+			}
+
+			location, err := locationFromValue(arguments[1])
+			if err != nil {
+				return nil, ec.ErrorAt(caller, err.Error())
+			}
+
+			return newDatetimeValue(t.In(location), true), nil
+		}),
+
+		"utcfromtimestamp": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			values, err := requiredArgs(ec, caller, args, "datetime.utcfromtimestamp", compilerInterface.NumberVal)
+			if err != nil {
+				return nil, err
+			}
+
+			return newDatetimeValue(timeFromTimestamp(values[0].NumberValue), false), nil
+		}),
+	}
+
+	date := compilerInterface.NewFunction(dateConstructor)
+	date.MapValue = map[string]*compilerInterface.Value{
+		"today": compilerInterface.NewFunction(func(_ compilerInterface.ExecutionContext, _ compilerInterface.AST, _ compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			return newDateValue(time.Now().UTC()), nil
+		}),
+
+		"fromisoformat": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			values, err := requiredArgs(ec, caller, args, "date.fromisoformat", compilerInterface.StringVal)
+			if err != nil {
+				return nil, err
+			}
+
+			t, err := time.Parse("2006-01-02", values[0].StringValue)
+			if err != nil {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("Invalid isoformat string: '%s'", values[0].StringValue))
+			}
+
+			return newDateValue(t), nil
+		}),
+	}
+
+	return compilerInterface.NewMap(map[string]*compilerInterface.Value{
+		"datetime":  datetime,
+		"date":      date,
+		"time":      compilerInterface.NewFunction(timeConstructor),
+		"timedelta": compilerInterface.NewFunction(timedeltaConstructor),
+		"timezone": compilerInterface.NewMap(map[string]*compilerInterface.Value{
+			"utc": newTimezoneValue(time.UTC),
+		}),
+	})
+}
+
+// https://pythonhosted.org/pytz/
+func newPytzModule() *compilerInterface.Value {
+	return compilerInterface.NewMap(map[string]*compilerInterface.Value{
+		"utc": newTimezoneValue(time.UTC),
+		"UTC": newTimezoneValue(time.UTC),
+
+		"timezone": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			values, err := requiredArgs(ec, caller, args, "pytz.timezone", compilerInterface.StringVal)
+			if err != nil {
+				return nil, err
+			}
+
+			location, err := locationFromValue(values[0])
+			if err != nil {
+				return nil, ec.ErrorAt(caller, err.Error())
+			}
+
+			return newTimezoneValue(location), nil
+		}),
+
+		"FixedOffset": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			values, err := requiredArgs(ec, caller, args, "pytz.FixedOffset", compilerInterface.NumberVal)
+			if err != nil {
+				return nil, err
+			}
+
+			return newTimezoneValue(fixedOffset(int(values[0].NumberValue) * 60)), nil
+		}),
+	})
+}
+
+func datetimeConstructor(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	arguments, err := dbtUtils.GetArgs(
+		args,
+		dbtUtils.Param("year"),
+		dbtUtils.Param("month"),
+		dbtUtils.Param("day"),
+		dbtUtils.ParamWithDefault("hour", compilerInterface.NewNumber(0)),
+		dbtUtils.ParamWithDefault("minute", compilerInterface.NewNumber(0)),
+		dbtUtils.ParamWithDefault("second", compilerInterface.NewNumber(0)),
+		dbtUtils.ParamWithDefault("microsecond", compilerInterface.NewNumber(0)),
+		dbtUtils.Param("tzinfo"),
+	)
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	parts, err := intArgs("datetime", arguments[:7], []string{"year", "month", "day", "hour", "minute", "second", "microsecond"})
+	if err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	if err := checkTimeParts(parts); err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	location, aware := time.UTC, false
+	if tz := arguments[7]; tz.Type() != compilerInterface.Undefined && tz.Type() != compilerInterface.NullVal {
+		if location, err = locationFromValue(tz); err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+		aware = true
+	}
+
+	t := time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], parts[6]*1000, location)
+	return newDatetimeValue(t, aware), nil
+}
+
+func dateConstructor(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("year"), dbtUtils.Param("month"), dbtUtils.Param("day"))
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	parts, err := intArgs("date", arguments, []string{"year", "month", "day"})
+	if err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	if err := checkTimeParts(parts); err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	return newDateValue(time.Date(parts[0], time.Month(parts[1]), parts[2], 0, 0, 0, 0, time.UTC)), nil
+}
+
+func timeConstructor(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	arguments, err := dbtUtils.GetArgs(
+		args,
+		dbtUtils.ParamWithDefault("hour", compilerInterface.NewNumber(0)),
+		dbtUtils.ParamWithDefault("minute", compilerInterface.NewNumber(0)),
+		dbtUtils.ParamWithDefault("second", compilerInterface.NewNumber(0)),
+		dbtUtils.ParamWithDefault("microsecond", compilerInterface.NewNumber(0)),
+	)
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	parts, err := intArgs("time", arguments, []string{"hour", "minute", "second", "microsecond"})
+	if err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	if err := checkTimeParts(append([]int{1900, 1, 1}, parts...)); err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	t := time.Date(1900, 1, 1, parts[0], parts[1], parts[2], parts[3]*1000, time.UTC)
+
+	value := compilerInterface.NewStringWithProperties(formatClock(t), map[string]*compilerInterface.Value{
+		"hour":        compilerInterface.NewNumber(float64(t.Hour())),
+		"minute":      compilerInterface.NewNumber(float64(t.Minute())),
+		"second":      compilerInterface.NewNumber(float64(t.Second())),
+		"microsecond": compilerInterface.NewNumber(float64(t.Nanosecond() / 1000)),
+		"isoformat":   stringMethod(formatClock(t)),
+		"strftime":    strftimeMethod(t, false),
+	})
+
+	return value, nil
+}
+
+func timedeltaConstructor(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	names := []string{"days", "seconds", "microseconds", "milliseconds", "minutes", "hours", "weeks"}
+	units := []time.Duration{24 * time.Hour, time.Second, time.Microsecond, time.Millisecond, time.Minute, time.Hour, 7 * 24 * time.Hour}
+
+	params := make([]compilerInterface.Argument, len(names))
+	for i, name := range names {
+		params[i] = dbtUtils.ParamWithDefault(name, compilerInterface.NewNumber(0))
+	}
+
+	arguments, err := dbtUtils.GetArgs(args, params...)
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	var duration time.Duration
+	for i, argument := range arguments {
+		duration += time.Duration(argument.NumberValue * float64(units[i]))
+	}
+
+	// Python's timedeltas are only precise to the microsecond
+	return newTimedeltaValue(duration.Round(time.Microsecond)), nil
+}
+
+func newDatetimeValue(t time.Time, aware bool) *compilerInterface.Value {
+	if !aware {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	}
+	t = t.Round(time.Microsecond)
+
+	tzinfo := compilerInterface.NewNull()
+	if aware {
+		tzinfo = newTimezoneValue(t.Location())
+	}
+
+	properties := map[string]*compilerInterface.Value{
+		"year":        compilerInterface.NewNumber(float64(t.Year())),
+		"month":       compilerInterface.NewNumber(float64(t.Month())),
+		"day":         compilerInterface.NewNumber(float64(t.Day())),
+		"hour":        compilerInterface.NewNumber(float64(t.Hour())),
+		"minute":      compilerInterface.NewNumber(float64(t.Minute())),
+		"second":      compilerInterface.NewNumber(float64(t.Second())),
+		"microsecond": compilerInterface.NewNumber(float64(t.Nanosecond() / 1000)),
+		"tzinfo":      tzinfo,
+
+		"strftime":   strftimeMethod(t, aware),
+		"weekday":    numberMethod(float64((int(t.Weekday()) + 6) % 7)),
+		"isoweekday": numberMethod(float64((int(t.Weekday())+6)%7 + 1)),
+		"timestamp":  numberMethod(float64(t.UnixNano()/1000) / 1e6),
+
+		"isoformat": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			arguments, err := dbtUtils.GetArgs(args, dbtUtils.ParamWithDefault("sep", compilerInterface.NewString("T")))
+			if err != nil {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+			}
+
+			return compilerInterface.NewString(formatDatetime(t, aware, arguments[0].StringValue)), nil
+		}),
+
+		"date": compilerInterface.NewFunction(func(_ compilerInterface.ExecutionContext, _ compilerInterface.AST, _ compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			return newDateValue(t), nil
+		}),
+
+		"replace": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			replaced, err := replaceTime(ec, caller, args, t, true)
+			if err != nil {
+				return nil, err
+			}
+
+			// Replacing the tzinfo changes whether the datetime is aware
+			for _, arg := range args {
+				if arg.Name == "tzinfo" {
+					if arg.Value.Type() == compilerInterface.NullVal || arg.Value.Type() == compilerInterface.Undefined {
+						return newDatetimeValue(replaced, false), nil
+					}
+
+					location, err := locationFromValue(arg.Value)
+					if err != nil {
+						return nil, ec.ErrorAt(caller, err.Error())
+					}
+
+					return newDatetimeValue(time.Date(replaced.Year(), replaced.Month(), replaced.Day(), replaced.Hour(), replaced.Minute(), replaced.Second(), replaced.Nanosecond(), location), true), nil
+				}
+			}
+
+			return newDatetimeValue(replaced, aware), nil
+		}),
+
+		"astimezone": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("tz"))
+			if err != nil {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+			}
+
+			// Naive datetimes are treated as UTC, rather than the local time of the machine
+			location := time.UTC
+			if arguments[0].Type() != compilerInterface.Undefined && arguments[0].Type() != compilerInterface.NullVal {
+				if location, err = locationFromValue(arguments[0]); err != nil {
+					return nil, ec.ErrorAt(caller, err.Error())
+				}
+			}
+
+			return newDatetimeValue(t.In(location), true), nil
+		}),
+
+		"utcoffset": compilerInterface.NewFunction(func(_ compilerInterface.ExecutionContext, _ compilerInterface.AST, _ compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			if !aware {
+				return compilerInterface.NewNull(), nil
+			}
+
+			_, offset := t.Zone()
+			return newTimedeltaValue(time.Duration(offset) * time.Second), nil
+		}),
+	}
+
+	value := compilerInterface.NewStringWithProperties(formatDatetime(t, aware, " "), properties)
+	value.Object = pyDatetime{time: t, aware: aware}
+
+	properties["__add__"] = compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		if delta, ok := args[0].Value.Unwrap().Object.(pyTimedelta); ok {
+			return newDatetimeValue(t.Add(delta.duration), aware), nil
+		}
+
+		return nil, unsupportedOperands(ec, caller, "+", value, args[0].Value)
+	})
+	properties["__radd__"] = properties["__add__"]
+
+	properties["__cmp__"] = compareMethod(func(other interface{}) (int, bool, error) {
+		dt, ok := other.(pyDatetime)
+		if !ok {
+			return 0, false, nil
+		}
+
+		if dt.aware != aware {
+			return 0, false, fmt.Errorf("can't compare offset-naive and offset-aware datetimes")
+		}
+
+		// Naive datetimes are held in UTC, so comparing the instants works for both
+		return compareTimes(t, dt.time), true, nil
+	})
+
+	properties["__sub__"] = compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		switch other := args[0].Value.Unwrap().Object.(type) {
+		case pyTimedelta:
+			return newDatetimeValue(t.Add(-other.duration), aware), nil
+
+		case pyDatetime:
+			if other.aware != aware {
+				return nil, ec.ErrorAt(caller, "can't subtract offset-naive and offset-aware datetimes")
+			}
+
+			return newTimedeltaValue(t.Sub(other.time)), nil
+		}
+
+		return nil, unsupportedOperands(ec, caller, "-", value, args[0].Value)
+	})
+
+	return value
+}
+
+func newDateValue(t time.Time) *compilerInterface.Value {
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	properties := map[string]*compilerInterface.Value{
+		"year":  compilerInterface.NewNumber(float64(t.Year())),
+		"month": compilerInterface.NewNumber(float64(t.Month())),
+		"day":   compilerInterface.NewNumber(float64(t.Day())),
+
+		"strftime":   strftimeMethod(t, false),
+		"isoformat":  stringMethod(t.Format("2006-01-02")),
+		"weekday":    numberMethod(float64((int(t.Weekday()) + 6) % 7)),
+		"isoweekday": numberMethod(float64((int(t.Weekday())+6)%7 + 1)),
+
+		"replace": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			replaced, err := replaceTime(ec, caller, args, t, false)
+			if err != nil {
+				return nil, err
+			}
+
+			return newDateValue(replaced), nil
+		}),
+	}
+
+	value := compilerInterface.NewStringWithProperties(t.Format("2006-01-02"), properties)
+	value.Object = pyDate{time: t}
+
+	properties["__add__"] = compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		if delta, ok := args[0].Value.Unwrap().Object.(pyTimedelta); ok {
+			return newDateValue(t.AddDate(0, 0, delta.days())), nil
+		}
+
+		return nil, unsupportedOperands(ec, caller, "+", value, args[0].Value)
+	})
+	properties["__radd__"] = properties["__add__"]
+
+	properties["__cmp__"] = compareMethod(func(other interface{}) (int, bool, error) {
+		if date, ok := other.(pyDate); ok {
+			return compareTimes(t, date.time), true, nil
+		}
+
+		return 0, false, nil
+	})
+
+	properties["__sub__"] = compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		switch other := args[0].Value.Unwrap().Object.(type) {
+		case pyTimedelta:
+			return newDateValue(t.AddDate(0, 0, -other.days())), nil
+
+		case pyDate:
+			return newTimedeltaValue(t.Sub(other.time)), nil
+		}
+
+		return nil, unsupportedOperands(ec, caller, "-", value, args[0].Value)
+	})
+
+	return value
+}
+
+func newTimedeltaValue(duration time.Duration) *compilerInterface.Value {
+	delta := pyTimedelta{duration: duration}
+	days, seconds, microseconds := delta.parts()
+
+	properties := map[string]*compilerInterface.Value{
+		"days":          compilerInterface.NewNumber(float64(days)),
+		"seconds":       compilerInterface.NewNumber(float64(seconds)),
+		"microseconds":  compilerInterface.NewNumber(float64(microseconds)),
+		"total_seconds": numberMethod(duration.Seconds()),
+	}
+
+	value := compilerInterface.NewStringWithProperties(delta.String(), properties)
+	value.Object = delta
+
+	properties["__add__"] = compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		other := args[0].Value.Unwrap()
+
+		switch o := other.Object.(type) {
+		case pyTimedelta:
+			return newTimedeltaValue(duration + o.duration), nil
+
+		case pyDatetime, pyDate:
+			// timedelta + datetime is the same as datetime + timedelta
+			return other.MapValue["__add__"].Function(ec, caller, compilerInterface.Arguments{{Value: value}})
+		}
+
+		return nil, unsupportedOperands(ec, caller, "+", value, other)
+	})
+
+	properties["__cmp__"] = compareMethod(func(other interface{}) (int, bool, error) {
+		o, ok := other.(pyTimedelta)
+		if !ok {
+			return 0, false, nil
+		}
+
+		switch {
+		case duration < o.duration:
+			return -1, true, nil
+		case duration > o.duration:
+			return 1, true, nil
+		default:
+			return 0, true, nil
+		}
+	})
+
+	properties["__sub__"] = compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		if o, ok := args[0].Value.Unwrap().Object.(pyTimedelta); ok {
+			return newTimedeltaValue(duration - o.duration), nil
+		}
+
+		return nil, unsupportedOperands(ec, caller, "-", value, args[0].Value)
+	})
+
+	properties["__mul__"] = compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		if other := args[0].Value.Unwrap(); other.Type() == compilerInterface.NumberVal {
+			return newTimedeltaValue(time.Duration(float64(duration) * other.NumberValue).Round(time.Microsecond)), nil
+		}
+
+		return nil, unsupportedOperands(ec, caller, "*", value, args[0].Value)
+	})
+	properties["__rmul__"] = properties["__mul__"]
+
+	return value
+}
+
+func newTimezoneValue(location *time.Location) *compilerInterface.Value {
+	value := compilerInterface.NewStringWithProperties(location.String(), map[string]*compilerInterface.Value{
+		"zone": compilerInterface.NewString(location.String()),
+
+		// pytz's way of attaching a timezone to a naive datetime
+		"localize": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			if len(args) < 1 {
+				return nil, ec.ErrorAt(caller, "localize expected 1 argument, got 0")
+			}
+
+			dt, ok := args[0].Value.Unwrap().Object.(pyDatetime)
+			if !ok || dt.aware {
+				return nil, ec.ErrorAt(caller, "localize can only be given a naive datetime")
+			}
+
+			t := dt.time
+			return newDatetimeValue(time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), location), true), nil
+		}),
+
+		"normalize": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			if len(args) < 1 {
+				return nil, ec.ErrorAt(caller, "normalize expected 1 argument, got 0")
+			}
+
+			dt, ok := args[0].Value.Unwrap().Object.(pyDatetime)
+			if !ok || !dt.aware {
+				return nil, ec.ErrorAt(caller, "normalize can only be given an aware datetime")
+			}
+
+			return newDatetimeValue(dt.time.In(location), true), nil
+		}),
+	})
+	value.Object = pyTimezone{location: location}
+
+	return value
+}
+
+// Reads a timezone, either from a pytz timezone or its name
+func locationFromValue(value *compilerInterface.Value) (*time.Location, error) {
+	value = value.Unwrap()
+
+	if tz, ok := value.Object.(pyTimezone); ok {
+		return tz.location, nil
+	}
+
+	if value.Type() != compilerInterface.StringVal {
+		return nil, fmt.Errorf("expected a timezone, got %s", value.Type())
+	}
+
+	name := value.StringValue
+	if strings.EqualFold(name, "utc") {
+		return time.UTC, nil
+	}
+
+	if match := utcOffsetPattern.FindStringSubmatch(name); match != nil {
+		hours, _ := strconv.Atoi(match[2])
+		minutes, _ := strconv.Atoi(match[3])
+
+		offset := hours*3600 + minutes*60
+		if match[1] == "-" {
+			offset = -offset
+		}
+
+		return fixedOffset(offset), nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone `%s`", name)
+	}
+
+	return location, nil
+}
+
+var utcOffsetPattern = regexp.MustCompile(`^([+-])(\d{2}):?(\d{2})$`)
+
+// A timezone at a fixed offset from UTC, named after the offset such as `+01:00`
+func fixedOffset(seconds int) *time.Location {
+	if seconds == 0 {
+		return time.UTC
+	}
+
+	sign := '+'
+	if seconds < 0 {
+		sign = '-'
+	}
+
+	abs := int(math.Abs(float64(seconds)))
+	return time.FixedZone(fmt.Sprintf("%c%02d:%02d", sign, abs/3600, (abs%3600)/60), seconds)
+}
+
+func (d pyTimedelta) days() int {
+	days, _, _ := d.parts()
+	return days
+}
+
+// Splits the timedelta in the same way as Python, where only the days can be negative
+func (d pyTimedelta) parts() (days, seconds, microseconds int) {
+	total := d.duration.Microseconds()
+	day := int64(24 * time.Hour / time.Microsecond)
+
+	days = int(total / day)
+	remainder := total % day
+	if remainder < 0 {
+		days--
+		remainder += day
+	}
+
+	return days, int(remainder / 1e6), int(remainder % 1e6)
+}
+
+// Formats the timedelta in the same way as Python, such as `1 day, 2:03:04`
+func (d pyTimedelta) String() string {
+	days, seconds, microseconds := d.parts()
+
+	var builder strings.Builder
+	if days != 0 {
+		plural := "s"
+		if days == 1 || days == -1 {
+			plural = ""
+		}
+
+		builder.WriteString(fmt.Sprintf("%d day%s, ", days, plural))
+	}
+
+	builder.WriteString(fmt.Sprintf("%d:%02d:%02d", seconds/3600, (seconds%3600)/60, seconds%60))
+	if microseconds != 0 {
+		builder.WriteString(fmt.Sprintf(".%06d", microseconds))
+	}
+
+	return builder.String()
+}
+
+// Formats a datetime in the same way as Python's `isoformat`
+func formatDatetime(t time.Time, aware bool, sep string) string {
+	formatted := t.Format("2006-01-02") + sep + formatClock(t)
+
+	if aware {
+		formatted += t.Format("-07:00")
+	}
+
+	return formatted
+}
+
+func formatClock(t time.Time) string {
+	formatted := t.Format("15:04:05")
+
+	if micro := t.Nanosecond() / 1000; micro != 0 {
+		formatted += fmt.Sprintf(".%06d", micro)
+	}
+
+	return formatted
+}
+
+func fromISOFormat(value string) (time.Time, bool, error) {
+	value = strings.Replace(value, "T", " ", 1)
+
+	for _, layout := range []string{"2006-01-02 15:04:05-07:00", "2006-01-02 15:04-07:00"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			_, offset := t.Zone()
+			return t.In(fixedOffset(offset)), true, nil
+		}
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02 15", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, false, nil
+		}
+	}
+
+	return time.Time{}, false, fmt.Errorf("Invalid isoformat string: '%s'", value)
+}
+
+func timeFromTimestamp(timestamp float64) time.Time {
+	seconds, fraction := math.Modf(timestamp)
+	return time.Unix(int64(seconds), int64(math.Round(fraction*1e6))*1000).UTC()
+}
+
+// Reads the arguments of a constructor as integers
+func intArgs(funcName string, values []*compilerInterface.Value, names []string) ([]int, error) {
+	ints := make([]int, len(values))
+
+	for i, value := range values {
+		if value.Type() != compilerInterface.NumberVal {
+			return nil, fmt.Errorf("%s's %s needs to be a number, got %s", funcName, names[i], value.Type())
+		}
+
+		ints[i] = int(value.NumberValue)
+	}
+
+	return ints, nil
+}
+
+// Checks the year, month, day, hour, minute, second and microsecond given to a constructor are in range, as
+// time.Date would otherwise normalise them; such as the 13th month becoming January of the next year
+func checkTimeParts(parts []int) error {
+	names := []string{"year", "month", "day", "hour", "minute", "second", "microsecond"}
+	ranges := [][2]int{{1, 9999}, {1, 12}, {1, 31}, {0, 23}, {0, 59}, {0, 59}, {0, 999999}}
+
+	for i, part := range parts {
+		if part < ranges[i][0] || part > ranges[i][1] {
+			return fmt.Errorf("ValueError: %s must be in %d..%d", names[i], ranges[i][0], ranges[i][1])
+		}
+	}
+
+	// The day of the month which follows the last is the first of the next month
+	if len(parts) >= 3 && time.Date(parts[0], time.Month(parts[1]), parts[2], 0, 0, 0, 0, time.UTC).Day() != parts[2] {
+		return fmt.Errorf("ValueError: day is out of range for month")
+	}
+
+	return nil
+}
+
+// Replaces the given parts of a date or datetime, as in `dt.replace(day=1)`
+func replaceTime(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments, t time.Time, hasTime bool) (time.Time, error) {
+	names := []string{"year", "month", "day"}
+	parts := []int{t.Year(), int(t.Month()), t.Day()}
+	if hasTime {
+		names = append(names, "hour", "minute", "second", "microsecond")
+		parts = append(parts, t.Hour(), t.Minute(), t.Second(), t.Nanosecond()/1000)
+	}
+
+	params := make([]compilerInterface.Argument, len(names))
+	for i, name := range names {
+		params[i] = dbtUtils.ParamWithDefault(name, compilerInterface.NewNumber(float64(parts[i])))
+	}
+
+	// The tzinfo is handled by the caller
+	withoutTZInfo := make(compilerInterface.Arguments, 0, len(args))
+	for _, arg := range args {
+		if arg.Name != "tzinfo" {
+			withoutTZInfo = append(withoutTZInfo, arg)
+		}
+	}
+
+	arguments, err := dbtUtils.GetArgs(withoutTZInfo, params...)
+	if err != nil {
+		return t, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	if parts, err = intArgs("replace", arguments, names); err != nil {
+		return t, ec.ErrorAt(caller, err.Error())
+	}
+
+	if err := checkTimeParts(parts); err != nil {
+		return t, ec.ErrorAt(caller, err.Error())
+	}
+
+	if !hasTime {
+		return time.Date(parts[0], time.Month(parts[1]), parts[2], 0, 0, 0, 0, t.Location()), nil
+	}
+
+	return time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], parts[6]*1000, t.Location()), nil
+}
+
+// The comparison operators call `a.__cmp__(b)`, which returns a negative number, zero or a positive number as `a` is
+// less than, equal to or greater than `b`; or undefined if `b` isn't something `a` can be compared with
+func compareMethod(compare func(other interface{}) (int, bool, error)) *compilerInterface.Value {
+	return compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		if len(args) != 1 {
+			return nil, ec.ErrorAt(caller, fmt.Sprintf("__cmp__ expected 1 argument, got %d", len(args)))
+		}
+
+		result, comparable, err := compare(args[0].Value.Unwrap().Object)
+		if err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+
+		if !comparable {
+			return compilerInterface.NewUndefined(), nil
+		}
+
+		return compilerInterface.NewNumber(float64(result)), nil
+	})
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func unsupportedOperands(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, op string, lhs, rhs *compilerInterface.Value) error {
+	return ec.ErrorAt(caller, fmt.Sprintf("unsupported operand types for %s: '%s' and '%s'", op, pyTypeName(lhs), pyTypeName(rhs)))
+}
+
+func pyTypeName(value *compilerInterface.Value) string {
+	switch value.Unwrap().Object.(type) {
+	case pyDatetime:
+		return "datetime"
+	case pyDate:
+		return "date"
+	case pyTimedelta:
+		return "timedelta"
+	case pyTimezone:
+		return "tzinfo"
+	default:
+		return string(value.Type())
+	}
+}
+
+func stringMethod(value string) *compilerInterface.Value {
+	return compilerInterface.NewFunction(func(_ compilerInterface.ExecutionContext, _ compilerInterface.AST, _ compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		return compilerInterface.NewString(value), nil
+	})
+}
+
+func numberMethod(value float64) *compilerInterface.Value {
+	return compilerInterface.NewFunction(func(_ compilerInterface.ExecutionContext, _ compilerInterface.AST, _ compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		return compilerInterface.NewNumber(value), nil
+	})
+}
+
+func strftimeMethod(t time.Time, aware bool) *compilerInterface.Value {
+	return compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		values, err := requiredArgs(ec, caller, args, "strftime", compilerInterface.StringVal)
+		if err != nil {
+			return nil, err
+		}
+
+		return compilerInterface.NewString(strftime(t, aware, values[0].StringValue)), nil
+	})
+}
+
+// Formats a time with Python's strftime directives
+func strftime(t time.Time, aware bool, format string) string {
+	var builder strings.Builder
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			builder.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		switch format[i] {
+		case 'a':
+			builder.WriteString(t.Format("Mon"))
+		case 'A':
+			builder.WriteString(t.Format("Monday"))
+		case 'w':
+			builder.WriteString(strconv.Itoa(int(t.Weekday())))
+		case 'd':
+			builder.WriteString(t.Format("02"))
+		case 'b':
+			builder.WriteString(t.Format("Jan"))
+		case 'B':
+			builder.WriteString(t.Format("January"))
+		case 'm':
+			builder.WriteString(t.Format("01"))
+		case 'y':
+			builder.WriteString(t.Format("06"))
+		case 'Y':
+			builder.WriteString(fmt.Sprintf("%04d", t.Year()))
+		case 'H':
+			builder.WriteString(t.Format("15"))
+		case 'I':
+			builder.WriteString(t.Format("03"))
+		case 'p':
+			builder.WriteString(t.Format("PM"))
+		case 'M':
+			builder.WriteString(t.Format("04"))
+		case 'S':
+			builder.WriteString(t.Format("05"))
+		case 'f':
+			builder.WriteString(fmt.Sprintf("%06d", t.Nanosecond()/1000))
+		case 'z':
+			if aware {
+				builder.WriteString(t.Format("-0700"))
+			}
+		case 'Z':
+			if aware {
+				builder.WriteString(t.Location().String())
+			}
+		case 'j':
+			builder.WriteString(fmt.Sprintf("%03d", t.YearDay()))
+		case 'U':
+			builder.WriteString(fmt.Sprintf("%02d", (t.YearDay()-1+7-int(t.Weekday()))/7))
+		case 'W':
+			builder.WriteString(fmt.Sprintf("%02d", (t.YearDay()-1+7-(int(t.Weekday())+6)%7)/7))
+		case 'c':
+			builder.WriteString(t.Format("Mon Jan _2 15:04:05 2006"))
+		case 'x':
+			builder.WriteString(t.Format("01/02/06"))
+		case 'X':
+			builder.WriteString(t.Format("15:04:05"))
+		case '%':
+			builder.WriteByte('%')
+		default:
+			builder.WriteByte('%')
+			builder.WriteByte(format[i])
+		}
+	}
+
+	return builder.String()
+}
+
+// The Go layouts for each of Python's strptime directives
+var strptimeLayouts = map[byte]string{
+	'a': "Mon",
+	'A': "Monday",
+	'd': "02",
+	'b': "Jan",
+	'B': "January",
+	'm': "01",
+	'y': "06",
+	'Y': "2006",
+	'H': "15",
+	'I': "03",
+	'p': "PM",
+	'M': "04",
+	'S': "05",
+	'f': "999999",
+	'z': "-0700",
+	'Z': "MST",
+	'%': "%",
+}
+
+// Parses a time with Python's strptime directives, returning whether it had a timezone
+func strptime(value string, format string) (time.Time, bool, error) {
+	var layout strings.Builder
+	aware := false
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			layout.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		directive, found := strptimeLayouts[format[i]]
+		if !found {
+			return time.Time{}, false, fmt.Errorf("strptime does not support the directive %%%c", format[i])
+		}
+
+		if format[i] == 'z' {
+			aware = true
+		}
+
+		layout.WriteString(directive)
+	}
+
+	t, err := time.Parse(layout.String(), value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("time data '%s' does not match format '%s'", value, format)
+	}
+
+	if aware {
+		_, offset := t.Zone()
+		t = t.In(fixedOffset(offset))
+	}
+
+	return t, aware, nil
+}
@@ -0,0 +1,105 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"ddbt/compiler/dbtUtils"
+	"ddbt/compilerInterface"
+	"ddbt/config"
+)
+
+// The prefixes of the implementations `adapter.dispatch` looks for, in order
+var dispatchPrefixes = []string{"bigquery", "default"}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/dispatch
+//
+// Returns the macro which implements `macro_name` for BigQuery, which is the first of `bigquery__<macro_name>` or
+// `default__<macro_name>` found in each project of the search order. Without a `macro_namespace` the root project and
+// then every package is searched, otherwise only the namespace is, unless `dispatch:` in dbt_project.yml gives it a
+// search order
+func adapterDispatch(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	arguments, err := dbtUtils.GetArgs(args,
+		dbtUtils.ParamWithDefault("macro_name", compilerInterface.NewString("")),
+		dbtUtils.ParamWithDefault("macro_namespace", compilerInterface.NewString("")),
+		dbtUtils.ParamWithDefault("packages", compilerInterface.NewList(nil)),
+	)
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	macroName := arguments[0].AsStringValue()
+	if macroName == "" {
+		return nil, ec.ErrorAt(caller, "dispatch requires the name of the macro")
+	}
+
+	e, ok := ec.(*ExecutionContext)
+	if !ok {
+		return nil, ec.ErrorAt(caller, "dispatch can only be used while compiling a file")
+	}
+	gc := e.globalContext
+
+	namespace := arguments[1].AsStringValue()
+	searchOrder := dispatchSearchOrder(gc, namespace, arguments[2])
+
+	searchedFor := make([]string, 0, len(searchOrder)*len(dispatchPrefixes))
+
+	for _, project := range searchOrder {
+		for _, prefix := range dispatchPrefixes {
+			name := prefix + "__" + macroName
+			searchedFor = append(searchedFor, fmt.Sprintf("'%s.%s'", project, name))
+
+			// The root project's macros aren't in a package
+			packageName := project
+			if config.GlobalCfg != nil && project == config.GlobalCfg.Name {
+				packageName = ""
+			}
+
+			macro, err := gc.getPackageMacro(packageName, name)
+			if err != nil {
+				return nil, ec.ErrorAt(caller, err.Error())
+			}
+
+			if macro != nil {
+				return compilerInterface.NewFunction(macro), nil
+			}
+		}
+	}
+
+	if namespace == "" {
+		namespace = strings.Join(searchOrder, ", ")
+	}
+
+	return nil, ec.ErrorAt(
+		caller,
+		fmt.Sprintf("In dispatch: No macro named '%s' found within namespace: '%s'; searched for: %s", macroName, namespace, strings.Join(searchedFor, ", ")),
+	)
+}
+
+// The projects dispatch searches for macros, where the root project is named as in dbt_project.yml
+func dispatchSearchOrder(gc *GlobalContext, namespace string, packages *compilerInterface.Value) []string {
+	// Before dbt 0.20 the search order was given to dispatch as `packages`
+	if packages.Type() == compilerInterface.ListVal && len(packages.ListValue) > 0 {
+		searchOrder := make([]string, len(packages.ListValue))
+		for i, pkg := range packages.ListValue {
+			searchOrder[i] = pkg.AsStringValue()
+		}
+
+		return searchOrder
+	}
+
+	rootProject := ""
+	if config.GlobalCfg != nil {
+		rootProject = config.GlobalCfg.Name
+
+		if searchOrder, found := config.GlobalCfg.Dispatch[namespace]; found && namespace != "" {
+			return searchOrder
+		}
+	}
+
+	if namespace != "" {
+		return []string{namespace}
+	}
+
+	return append([]string{rootProject}, gc.fileSystem.Packages()...)
+}
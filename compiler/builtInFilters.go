@@ -0,0 +1,1179 @@
+package compiler
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ddbt/compiler/dbtUtils"
+	"ddbt/compilerInterface"
+	"ddbt/jinja/ast"
+)
+
+// The Jinja2 filters, as listed in https://jinja.palletsprojects.com/en/2.11.x/templates/#list-of-builtin-filters
+//
+// Filters are desugared by the parser into function calls with the filtered value as the first argument, so
+// `{{ a | join(', ') }}` is called as `join(a, ', ')`
+var builtInFilters = funcMap{
+	"abs": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		number, err := numberArg(ec, caller, "abs", args)
+		if err != nil {
+			return nil, err
+		}
+
+		return compilerInterface.NewNumber(math.Abs(number)), nil
+	},
+
+	"batch": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args, dbtUtils.Param("value"), dbtUtils.ParamWithDefault("linecount", compilerInterface.NewNumber(0)), dbtUtils.Param("fill_with"))
+		if err != nil {
+			return nil, err
+		}
+
+		items, err := iterableOf(arguments[0])
+		if err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+
+		lineCount := int(arguments[1].NumberValue)
+		if lineCount < 1 {
+			return nil, ec.ErrorAt(caller, "batch requires a linecount of at least 1")
+		}
+
+		batches := make([]*compilerInterface.Value, 0, (len(items)+lineCount-1)/lineCount)
+		for start := 0; start < len(items); start += lineCount {
+			end := start + lineCount
+			if end > len(items) {
+				end = len(items)
+			}
+
+			batch := append([]*compilerInterface.Value{}, items[start:end]...)
+			if !arguments[2].IsUndefined {
+				for len(batch) < lineCount {
+					batch = append(batch, arguments[2])
+				}
+			}
+
+			batches = append(batches, compilerInterface.NewList(batch))
+		}
+
+		return compilerInterface.NewList(batches), nil
+	},
+
+	"capitalize": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		s, err := stringArg(ec, caller, "capitalize", args)
+		if err != nil {
+			return nil, err
+		}
+
+		return compilerInterface.NewString(capitalize(s)), nil
+	},
+
+	"count": lengthFilter,
+
+	"default": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args, dbtUtils.Param("value"), dbtUtils.Param("default_value"), dbtUtils.ParamWithDefault("boolean", compilerInterface.NewBoolean(false)))
+		if err != nil {
+			return nil, err
+		}
+
+		// Like Jinja, the default value is an empty string unless given
+		defaultValue := arguments[1]
+		if defaultValue.IsUndefined && len(args) < 2 {
+			defaultValue = compilerInterface.NewString("")
+		}
+
+		value := arguments[0]
+		if value.IsUndefined || value.IsNull || (arguments[2].BooleanValue && !value.TruthyValue()) {
+			return defaultValue, nil
+		}
+
+		return value, nil
+	},
+
+	"dictsort": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args,
+			dbtUtils.Param("value"),
+			dbtUtils.ParamWithDefault("case_sensitive", compilerInterface.NewBoolean(false)),
+			dbtUtils.ParamWithDefault("by", compilerInterface.NewString("key")),
+			dbtUtils.ParamWithDefault("reverse", compilerInterface.NewBoolean(false)),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if arguments[0].Type() != compilerInterface.MapVal {
+			return nil, ec.ErrorAt(caller, fmt.Sprintf("dictsort expects a map, got %s", arguments[0].Type()))
+		}
+
+		position := 0
+		switch arguments[2].StringValue {
+		case "key":
+		case "value":
+			position = 1
+		default:
+			return nil, ec.ErrorAt(caller, "You can only sort by either \"key\" or \"value\"")
+		}
+
+		pairs := itemsOf(arguments[0])
+		err = sortValues(pairs, arguments[3].BooleanValue, func(pair *compilerInterface.Value) *compilerInterface.Value {
+			return sortKey(pair.ListValue[position], arguments[1].BooleanValue)
+		})
+		if err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+
+		return compilerInterface.NewList(pairs), nil
+	},
+
+	"first": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		items, err := iterableArg(ec, caller, "first", args)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(items) == 0 {
+			return compilerInterface.NewUndefined(), nil
+		}
+
+		return items[0], nil
+	},
+
+	"float": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args, dbtUtils.Param("value"), dbtUtils.Param("default"))
+		if err != nil {
+			return nil, err
+		}
+
+		value := arguments[0]
+		switch value.Type() {
+		case compilerInterface.NumberVal, compilerInterface.BooleanValue:
+			number, _ := value.AsNumberValue()
+			return compilerInterface.NewNumber(number), nil
+
+		case compilerInterface.StringVal:
+			if number, err := strconv.ParseFloat(strings.TrimSpace(value.StringValue), 64); err == nil {
+				return compilerInterface.NewNumber(number), nil
+			}
+		}
+
+		return numberOrDefault(arguments[1]), nil
+	},
+
+	"groupby": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args,
+			dbtUtils.Param("value"),
+			dbtUtils.Param("attribute"),
+			dbtUtils.Param("default"),
+			dbtUtils.ParamWithDefault("case_sensitive", compilerInterface.NewBoolean(false)),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		items, err := iterableOf(arguments[0])
+		if err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+		items = append([]*compilerInterface.Value{}, items...)
+
+		caseSensitive := arguments[3].BooleanValue
+		grouperOf := func(item *compilerInterface.Value) *compilerInterface.Value {
+			grouper := attributeOf(item, arguments[1])
+			if grouper.IsUndefined && !arguments[2].IsUndefined {
+				return arguments[2]
+			}
+			return grouper
+		}
+
+		err = sortValues(items, false, func(item *compilerInterface.Value) *compilerInterface.Value {
+			return sortKey(grouperOf(item), caseSensitive)
+		})
+		if err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+
+		groups := make([]*compilerInterface.Value, 0)
+		var group *compilerInterface.Value
+		for _, item := range items {
+			grouper := grouperOf(item)
+
+			if group == nil || !sortKey(group.ListValue[0], caseSensitive).Equals(sortKey(grouper, caseSensitive)) {
+				group = compilerInterface.NewTuple(grouper, compilerInterface.NewList(nil))
+				group.MapValue = map[string]*compilerInterface.Value{"grouper": grouper}
+				groups = append(groups, group)
+			}
+
+			list := group.ListValue[1]
+			list.ListValue = append(list.ListValue, item)
+			group.MapValue["list"] = list
+		}
+
+		return compilerInterface.NewList(groups), nil
+	},
+
+	"indent": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args,
+			dbtUtils.Param("value"),
+			dbtUtils.Param("width"),
+			dbtUtils.ParamWithDefault("first", compilerInterface.NewBoolean(false)),
+			dbtUtils.ParamWithDefault("blank", compilerInterface.NewBoolean(false)),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// The width can either be a number of spaces or the string to indent with
+		indentation := strings.Repeat(" ", 4)
+		switch width := arguments[1]; width.Type() {
+		case compilerInterface.NumberVal:
+			indentation = strings.Repeat(" ", int(width.NumberValue))
+		case compilerInterface.StringVal:
+			indentation = width.StringValue
+		}
+
+		lines := strings.Split(arguments[0].AsStringValue(), "\n")
+		for i, line := range lines {
+			if (i > 0 || arguments[2].BooleanValue) && (line != "" || arguments[3].BooleanValue) {
+				lines[i] = indentation + line
+			}
+		}
+
+		return compilerInterface.NewString(strings.Join(lines, "\n")), nil
+	},
+
+	"int": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args,
+			dbtUtils.Param("value"),
+			dbtUtils.Param("default"),
+			dbtUtils.ParamWithDefault("base", compilerInterface.NewNumber(10)),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		value := arguments[0]
+		switch value.Type() {
+		case compilerInterface.NumberVal, compilerInterface.BooleanValue:
+			number, _ := value.AsNumberValue()
+			return compilerInterface.NewNumber(math.Trunc(number)), nil
+
+		case compilerInterface.StringVal:
+			s := strings.ReplaceAll(strings.TrimSpace(value.StringValue), "_", "")
+			base := int(arguments[2].NumberValue)
+
+			if base != 10 {
+				// Like Python, the prefix of the base is allowed (`0x1F` in base 16)
+				s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "0x"), "0o"), "0b")
+			}
+
+			if number, err := strconv.ParseInt(s, base, 64); err == nil {
+				return compilerInterface.NewNumber(float64(number)), nil
+			}
+
+			if number, err := strconv.ParseFloat(s, 64); err == nil && base == 10 {
+				return compilerInterface.NewNumber(math.Trunc(number)), nil
+			}
+		}
+
+		return numberOrDefault(arguments[1]), nil
+	},
+
+	"items": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		if err := expectArgs(ec, caller, "items", 1, args); err != nil {
+			return nil, err
+		}
+
+		value := args[0].Value.Unwrap()
+		switch value.Type() {
+		case compilerInterface.MapVal:
+			return compilerInterface.NewList(itemsOf(value)), nil
+
+		case compilerInterface.Undefined, compilerInterface.NullVal:
+			return compilerInterface.NewList(nil), nil
+
+		default:
+			return nil, ec.ErrorAt(caller, fmt.Sprintf("Can only get item pairs from a map, got %s", value.Type()))
+		}
+	},
+
+	"join": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args, dbtUtils.Param("value"), dbtUtils.ParamWithDefault("d", compilerInterface.NewString("")), dbtUtils.Param("attribute"))
+		if err != nil {
+			return nil, err
+		}
+
+		items, err := iterableOf(arguments[0])
+		if err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+
+		parts := make([]string, len(items))
+		for i, item := range items {
+			if !arguments[2].IsUndefined {
+				item = attributeOf(item, arguments[2])
+			}
+
+			parts[i] = item.AsStringValue()
+		}
+
+		return compilerInterface.NewString(strings.Join(parts, arguments[1].StringValue)), nil
+	},
+
+	"last": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		items, err := iterableArg(ec, caller, "last", args)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(items) == 0 {
+			return compilerInterface.NewUndefined(), nil
+		}
+
+		return items[len(items)-1], nil
+	},
+
+	"length": lengthFilter,
+
+	"list": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		items, err := iterableArg(ec, caller, "list", args)
+		if err != nil {
+			return nil, err
+		}
+
+		return compilerInterface.NewList(append([]*compilerInterface.Value{}, items...)), nil
+	},
+
+	"lower": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		values, err := requiredArgs(ec, caller, args, "lower", compilerInterface.StringVal)
+		if err != nil {
+			return nil, err
+		}
+
+		return compilerInterface.NewString(strings.ToLower(values[0].AsStringValue())), nil
+	},
+
+	// map either looks up an attribute of each item (`map(attribute='name')`) or applies another filter to each
+	// item (`map('upper')`)
+	"map": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		if len(args) < 2 {
+			return nil, ec.ErrorAt(caller, "map requires either an attribute or the name of a filter")
+		}
+
+		items, err := iterableOf(args[0].Value.Unwrap())
+		if err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+
+		positional, named := splitArgs(args[1:])
+		mapped := make([]*compilerInterface.Value, len(items))
+
+		if attribute, found := named["attribute"]; found {
+			for i, item := range items {
+				mapped[i] = attributeOf(item, attribute)
+
+				if defaultValue, found := named["default"]; found && mapped[i].IsUndefined {
+					mapped[i] = defaultValue
+				}
+			}
+
+			return compilerInterface.NewList(mapped), nil
+		}
+
+		if len(positional) == 0 || positional[0].Type() != compilerInterface.StringVal {
+			return nil, ec.ErrorAt(caller, "map requires either an attribute or the name of a filter")
+		}
+
+		filterName := positional[0].StringValue
+		filter := ec.GetFilter(filterName)
+		if filter.Function == nil {
+			return nil, ec.ErrorAt(caller, fmt.Sprintf("No filter named `%s`", filterName))
+		}
+
+		for i, item := range items {
+			mapped[i], err = filter.Function(ec, caller, filterCallArgs(item, args[2:]))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return compilerInterface.NewList(mapped), nil
+	},
+
+	"max": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		return minOrMax(ec, caller, args, 1)
+	},
+
+	"min": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		return minOrMax(ec, caller, args, -1)
+	},
+
+	"reject": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		return selectOrReject(ec, caller, args, false, false)
+	},
+
+	"rejectattr": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		return selectOrReject(ec, caller, args, true, false)
+	},
+
+	"replace": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		if len(args) != 3 && len(args) != 4 {
+			return nil, ec.ErrorAt(caller, fmt.Sprintf("replace requires 3 parameters, got %d", len(args)))
+		}
+
+		arguments, err := filterArgs(ec, caller, args, dbtUtils.Param("s"), dbtUtils.Param("old"), dbtUtils.Param("new"), dbtUtils.ParamWithDefault("count", compilerInterface.NewNumber(-1)))
+		if err != nil {
+			return nil, err
+		}
+
+		value := strings.Replace(
+			arguments[0].AsStringValue(),
+			arguments[1].AsStringValue(),
+			arguments[2].AsStringValue(),
+			int(arguments[3].NumberValue),
+		)
+		return compilerInterface.NewString(value), nil
+	},
+
+	"round": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args,
+			dbtUtils.Param("value"),
+			dbtUtils.ParamWithDefault("precision", compilerInterface.NewNumber(0)),
+			dbtUtils.ParamWithDefault("method", compilerInterface.NewString("common")),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		number, err := arguments[0].AsNumberValue()
+		if err != nil {
+			return nil, ec.ErrorAt(caller, fmt.Sprintf("round expects a number: %s", err))
+		}
+
+		scale := math.Pow(10, arguments[1].NumberValue)
+
+		switch arguments[2].StringValue {
+		case "common":
+			// Python's round, which rounds halves to the nearest even number
+			number = math.RoundToEven(number*scale) / scale
+		case "ceil":
+			number = math.Ceil(number*scale) / scale
+		case "floor":
+			number = math.Floor(number*scale) / scale
+		default:
+			return nil, ec.ErrorAt(caller, "method must be common, ceil or floor")
+		}
+
+		return compilerInterface.NewNumber(number), nil
+	},
+
+	"select": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		return selectOrReject(ec, caller, args, false, true)
+	},
+
+	"selectattr": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		return selectOrReject(ec, caller, args, true, true)
+	},
+
+	"slice": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args, dbtUtils.Param("value"), dbtUtils.ParamWithDefault("slices", compilerInterface.NewNumber(0)), dbtUtils.Param("fill_with"))
+		if err != nil {
+			return nil, err
+		}
+
+		items, err := iterableOf(arguments[0])
+		if err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+
+		slices := int(arguments[1].NumberValue)
+		if slices < 1 {
+			return nil, ec.ErrorAt(caller, "slice requires at least 1 slice")
+		}
+
+		// The first `len % slices` slices take one extra item each, exactly as Jinja does
+		perSlice := len(items) / slices
+		withExtra := len(items) % slices
+		offset := 0
+
+		columns := make([]*compilerInterface.Value, slices)
+		for i := 0; i < slices; i++ {
+			start := offset + i*perSlice
+			if i < withExtra {
+				offset++
+			}
+			end := offset + (i+1)*perSlice
+
+			column := append([]*compilerInterface.Value{}, items[start:end]...)
+			if !arguments[2].IsUndefined && i >= withExtra {
+				column = append(column, arguments[2])
+			}
+
+			columns[i] = compilerInterface.NewList(column)
+		}
+
+		return compilerInterface.NewList(columns), nil
+	},
+
+	"sort": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args,
+			dbtUtils.Param("value"),
+			dbtUtils.ParamWithDefault("reverse", compilerInterface.NewBoolean(false)),
+			dbtUtils.ParamWithDefault("case_sensitive", compilerInterface.NewBoolean(false)),
+			dbtUtils.Param("attribute"),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		items, err := iterableOf(arguments[0])
+		if err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+		items = append([]*compilerInterface.Value{}, items...)
+
+		err = sortValues(items, arguments[1].BooleanValue, func(item *compilerInterface.Value) *compilerInterface.Value {
+			if !arguments[3].IsUndefined {
+				item = attributeOf(item, arguments[3])
+			}
+			return sortKey(item, arguments[2].BooleanValue)
+		})
+		if err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+
+		return compilerInterface.NewList(items), nil
+	},
+
+	"string": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		if err := expectArgs(ec, caller, "string", 1, args); err != nil {
+			return nil, err
+		}
+
+		return compilerInterface.NewString(pythonString(args[0].Value)), nil
+	},
+
+	"sum": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args, dbtUtils.Param("value"), dbtUtils.Param("attribute"), dbtUtils.ParamWithDefault("start", compilerInterface.NewNumber(0)))
+		if err != nil {
+			return nil, err
+		}
+
+		items, err := iterableOf(arguments[0])
+		if err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+
+		total := arguments[2].NumberValue
+		for _, item := range items {
+			if !arguments[1].IsUndefined {
+				item = attributeOf(item, arguments[1])
+			}
+
+			number, err := item.AsNumberValue()
+			if err != nil {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("Unable to sum: %s", err))
+			}
+
+			total += number
+		}
+
+		return compilerInterface.NewNumber(total), nil
+	},
+
+	"title": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		s, err := stringArg(ec, caller, "title", args)
+		if err != nil {
+			return nil, err
+		}
+
+		// Words start after whitespace, dashes and opening brackets; as Jinja's title does
+		var builder strings.Builder
+		startOfWord := true
+		for _, r := range s {
+			if unicode.IsSpace(r) || strings.ContainsRune("-({[<", r) {
+				startOfWord = true
+				builder.WriteRune(r)
+			} else if startOfWord {
+				startOfWord = false
+				builder.WriteRune(unicode.ToUpper(r))
+			} else {
+				builder.WriteRune(unicode.ToLower(r))
+			}
+		}
+
+		return compilerInterface.NewString(builder.String()), nil
+	},
+
+	"trim": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args, dbtUtils.Param("value"), dbtUtils.ParamWithDefault("chars", compilerInterface.NewString("")))
+		if err != nil {
+			return nil, err
+		}
+
+		if arguments[1].IsUndefined || arguments[1].StringValue == "" {
+			return compilerInterface.NewString(strings.TrimSpace(arguments[0].AsStringValue())), nil
+		}
+
+		return compilerInterface.NewString(strings.Trim(arguments[0].AsStringValue(), arguments[1].StringValue)), nil
+	},
+
+	"unique": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args,
+			dbtUtils.Param("value"),
+			dbtUtils.ParamWithDefault("case_sensitive", compilerInterface.NewBoolean(false)),
+			dbtUtils.Param("attribute"),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		items, err := iterableOf(arguments[0])
+		if err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+
+		unique := make([]*compilerInterface.Value, 0, len(items))
+		seen := make([]*compilerInterface.Value, 0, len(items))
+
+	items:
+		for _, item := range items {
+			key := item
+			if !arguments[2].IsUndefined {
+				key = attributeOf(item, arguments[2])
+			}
+			key = sortKey(key, arguments[1].BooleanValue)
+
+			for _, previous := range seen {
+				if previous.Equals(key) {
+					continue items
+				}
+			}
+
+			seen = append(seen, key)
+			unique = append(unique, item)
+		}
+
+		return compilerInterface.NewList(unique), nil
+	},
+
+	"upper": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		values, err := requiredArgs(ec, caller, args, "upper", compilerInterface.StringVal)
+		if err != nil {
+			return nil, err
+		}
+
+		return compilerInterface.NewString(strings.ToUpper(values[0].AsStringValue())), nil
+	},
+
+	"wordwrap": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		arguments, err := filterArgs(ec, caller, args,
+			dbtUtils.Param("value"),
+			dbtUtils.ParamWithDefault("width", compilerInterface.NewNumber(79)),
+			dbtUtils.ParamWithDefault("break_long_words", compilerInterface.NewBoolean(true)),
+			dbtUtils.ParamWithDefault("wrapstring", compilerInterface.NewString("\n")),
+			dbtUtils.ParamWithDefault("break_on_hyphens", compilerInterface.NewBoolean(true)),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		width := int(arguments[1].NumberValue)
+		if width < 1 {
+			return nil, ec.ErrorAt(caller, "wordwrap requires a width of at least 1")
+		}
+
+		wrapString := arguments[3].StringValue
+		if arguments[3].IsUndefined {
+			wrapString = "\n"
+		}
+
+		// Existing lines are kept, and each is wrapped on its own
+		lines := strings.Split(arguments[0].AsStringValue(), "\n")
+		for i, line := range lines {
+			lines[i] = strings.Join(wrapWords(line, width, arguments[2].BooleanValue), wrapString)
+		}
+
+		return compilerInterface.NewString(strings.Join(lines, wrapString)), nil
+	},
+}
+
+func lengthFilter(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	if err := expectArgs(ec, caller, "length", 1, args); err != nil {
+		return nil, err
+	}
+
+	value := args[0].Value.Unwrap()
+	switch value.Type() {
+	case compilerInterface.StringVal:
+		return compilerInterface.NewNumber(float64(utf8.RuneCountInString(value.StringValue))), nil
+
+	case compilerInterface.ListVal:
+		return compilerInterface.NewNumber(float64(len(value.ListValue))), nil
+
+	case compilerInterface.MapVal:
+		return compilerInterface.NewNumber(float64(len(value.MapValue))), nil
+
+	case compilerInterface.Undefined:
+		return compilerInterface.NewNumber(0), nil
+
+	default:
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("object of type %s has no length", value.Type()))
+	}
+}
+
+// The default of the int and float filters, which is 0 unless given
+func numberOrDefault(defaultValue *compilerInterface.Value) *compilerInterface.Value {
+	if defaultValue.IsUndefined {
+		return compilerInterface.NewNumber(0)
+	}
+
+	return defaultValue
+}
+
+// Builds the arguments to call a filter on a value, as `value | filter(args...)` does
+func filterCallArgs(value *compilerInterface.Value, args compilerInterface.Arguments) compilerInterface.Arguments {
+	arguments := make(compilerInterface.Arguments, 0, len(args)+1)
+	arguments = append(arguments, compilerInterface.Argument{Value: value})
+
+	return append(arguments, args...)
+}
+
+func filterArgs(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments, params ...compilerInterface.Argument) ([]*compilerInterface.Value, error) {
+	arguments, err := dbtUtils.GetArgs(args, params...)
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	return arguments, nil
+}
+
+// Splits the arguments given to a filter which passes them on, such as the test name and arguments of `select`
+func splitArgs(args compilerInterface.Arguments) ([]*compilerInterface.Value, map[string]*compilerInterface.Value) {
+	positional := make([]*compilerInterface.Value, 0, len(args))
+	named := make(map[string]*compilerInterface.Value)
+
+	for _, arg := range args {
+		if arg.Name != "" {
+			named[arg.Name] = arg.Value.Unwrap()
+		} else {
+			positional = append(positional, arg.Value.Unwrap())
+		}
+	}
+
+	return positional, named
+}
+
+func stringArg(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, filterName string, args compilerInterface.Arguments) (string, error) {
+	if err := expectArgs(ec, caller, filterName, 1, args); err != nil {
+		return "", err
+	}
+
+	return args[0].Value.AsStringValue(), nil
+}
+
+func numberArg(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, filterName string, args compilerInterface.Arguments) (float64, error) {
+	if err := expectArgs(ec, caller, filterName, 1, args); err != nil {
+		return 0, err
+	}
+
+	number, err := args[0].Value.AsNumberValue()
+	if err != nil {
+		return 0, ec.ErrorAt(caller, fmt.Sprintf("%s expects a number: %s", filterName, err))
+	}
+
+	return number, nil
+}
+
+func iterableArg(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, filterName string, args compilerInterface.Arguments) ([]*compilerInterface.Value, error) {
+	if err := expectArgs(ec, caller, filterName, 1, args); err != nil {
+		return nil, err
+	}
+
+	items, err := iterableOf(args[0].Value.Unwrap())
+	if err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	return items, nil
+}
+
+// Returns the items Python would iterate over; the characters of a string or the keys of a map
+func iterableOf(value *compilerInterface.Value) ([]*compilerInterface.Value, error) {
+	value = value.Unwrap()
+
+	switch value.Type() {
+	case compilerInterface.ListVal:
+		return value.ListValue, nil
+
+	case compilerInterface.MapVal:
+		keys := sortedKeys(value.MapValue)
+		items := make([]*compilerInterface.Value, len(keys))
+		for i, key := range keys {
+			items[i] = compilerInterface.NewString(key)
+		}
+		return items, nil
+
+	case compilerInterface.StringVal:
+		items := make([]*compilerInterface.Value, 0, len(value.StringValue))
+		for _, r := range value.StringValue {
+			items = append(items, compilerInterface.NewString(string(r)))
+		}
+		return items, nil
+
+	case compilerInterface.Undefined:
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("%s is not iterable", value.Type())
+	}
+}
+
+// Returns the (key, value) pairs of a map; as our maps are unordered they are sorted by key
+func itemsOf(value *compilerInterface.Value) []*compilerInterface.Value {
+	keys := sortedKeys(value.MapValue)
+
+	pairs := make([]*compilerInterface.Value, len(keys))
+	for i, key := range keys {
+		pairs[i] = compilerInterface.NewTuple(compilerInterface.NewString(key), value.MapValue[key])
+	}
+
+	return pairs
+}
+
+func sortedKeys(m map[string]*compilerInterface.Value) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Looks up an attribute of an item, such as `name`, `0` or a dotted path like `address.city`
+func attributeOf(item *compilerInterface.Value, attribute *compilerInterface.Value) *compilerInterface.Value {
+	path := []string{attribute.AsStringValue()}
+	if attribute.Type() == compilerInterface.StringVal {
+		path = strings.Split(attribute.StringValue, ".")
+	}
+
+	for _, part := range path {
+		item = item.Unwrap()
+
+		if index, err := strconv.Atoi(part); err == nil && item.Type() == compilerInterface.ListVal {
+			if index < 0 || index >= len(item.ListValue) {
+				return compilerInterface.NewUndefined()
+			}
+
+			item = item.ListValue[index]
+			continue
+		}
+
+		next, found := item.Properties(false)[part]
+		if !found || next == nil {
+			return compilerInterface.NewUndefined()
+		}
+		item = next
+	}
+
+	return item.Unwrap()
+}
+
+// The key values are sorted and compared by; strings are compared in lowercase unless case sensitive
+func sortKey(value *compilerInterface.Value, caseSensitive bool) *compilerInterface.Value {
+	value = value.Unwrap()
+
+	if !caseSensitive && value.Type() == compilerInterface.StringVal {
+		return compilerInterface.NewString(strings.ToLower(value.StringValue))
+	}
+
+	return value
+}
+
+// A stable sort of the values by their keys, which errors if two keys cannot be compared
+func sortValues(values []*compilerInterface.Value, reverse bool, key func(*compilerInterface.Value) *compilerInterface.Value) error {
+	keys := make(map[*compilerInterface.Value]*compilerInterface.Value, len(values))
+	for _, value := range values {
+		keys[value] = key(value)
+	}
+
+	var err error
+	sort.SliceStable(values, func(i, j int) bool {
+		order, compareErr := compareValues(keys[values[i]], keys[values[j]])
+		if compareErr != nil {
+			err = compareErr
+		}
+
+		if reverse {
+			return order > 0
+		}
+		return order < 0
+	})
+
+	return err
+}
+
+// Compares two values as Python would, returning -1, 0 or 1
+func compareValues(a, b *compilerInterface.Value) (int, error) {
+	a, b = a.Unwrap(), b.Unwrap()
+
+	isNumeric := func(v *compilerInterface.Value) bool {
+		return v.Type() == compilerInterface.NumberVal || v.Type() == compilerInterface.BooleanValue
+	}
+
+	switch {
+	case isNumeric(a) && isNumeric(b):
+		x, _ := a.AsNumberValue()
+		y, _ := b.AsNumberValue()
+
+		switch {
+		case x < y:
+			return -1, nil
+		case x > y:
+			return 1, nil
+		default:
+			return 0, nil
+		}
+
+	case a.Type() == compilerInterface.StringVal && b.Type() == compilerInterface.StringVal:
+		return strings.Compare(a.StringValue, b.StringValue), nil
+
+	case a.Type() == compilerInterface.ListVal && b.Type() == compilerInterface.ListVal:
+		for i := 0; i < len(a.ListValue) && i < len(b.ListValue); i++ {
+			if order, err := compareValues(a.ListValue[i], b.ListValue[i]); err != nil || order != 0 {
+				return order, err
+			}
+		}
+
+		return compareValues(compilerInterface.NewNumber(float64(len(a.ListValue))), compilerInterface.NewNumber(float64(len(b.ListValue))))
+
+	default:
+		return 0, fmt.Errorf("'<' not supported between instances of %s and %s", a.Type(), b.Type())
+	}
+}
+
+func minOrMax(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments, direction int) (*compilerInterface.Value, error) {
+	arguments, err := filterArgs(ec, caller, args,
+		dbtUtils.Param("value"),
+		dbtUtils.ParamWithDefault("case_sensitive", compilerInterface.NewBoolean(false)),
+		dbtUtils.Param("attribute"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := iterableOf(arguments[0])
+	if err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	var best, bestKey *compilerInterface.Value
+	for _, item := range items {
+		key := item
+		if !arguments[2].IsUndefined {
+			key = attributeOf(item, arguments[2])
+		}
+		key = sortKey(key, arguments[1].BooleanValue)
+
+		if best == nil {
+			best, bestKey = item, key
+			continue
+		}
+
+		order, err := compareValues(key, bestKey)
+		if err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+
+		if order == direction {
+			best, bestKey = item, key
+		}
+	}
+
+	if best == nil {
+		return compilerInterface.NewUndefined(), nil
+	}
+
+	return best, nil
+}
+
+// Implements select, reject, selectattr and rejectattr; which filter items using a Jinja test, such as
+// `select('odd')` or `selectattr('enabled', 'equalto', true)`. Without a test, items are checked for truthiness
+func selectOrReject(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments, byAttribute bool, keep bool) (*compilerInterface.Value, error) {
+	if len(args) < 1 {
+		return nil, ec.ErrorAt(caller, "select requires a value to filter")
+	}
+
+	items, err := iterableOf(args[0].Value.Unwrap())
+	if err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	positional, _ := splitArgs(args[1:])
+
+	var attribute *compilerInterface.Value
+	if byAttribute {
+		if len(positional) == 0 {
+			return nil, ec.ErrorAt(caller, "Missing parameter for attribute name")
+		}
+
+		attribute, positional = positional[0], positional[1:]
+	}
+
+	var test func(v, arg *compilerInterface.Value) (bool, error)
+	var testArg *compilerInterface.Value
+	if len(positional) > 0 {
+		test = lookupTest(positional[0].AsStringValue())
+		if test == nil {
+			return nil, ec.ErrorAt(caller, fmt.Sprintf("Unknown test type `%s`", positional[0].AsStringValue()))
+		}
+
+		if len(positional) > 1 {
+			testArg = positional[1]
+		}
+	}
+
+	selected := make([]*compilerInterface.Value, 0, len(items))
+	for _, item := range items {
+		value := item.Unwrap()
+		if attribute != nil {
+			value = attributeOf(item, attribute)
+		}
+
+		result := value.TruthyValue()
+		if test != nil {
+			if result, err = test(value, testArg); err != nil {
+				return nil, ec.ErrorAt(caller, err.Error())
+			}
+		}
+
+		if result == keep {
+			selected = append(selected, item)
+		}
+	}
+
+	return compilerInterface.NewList(selected), nil
+}
+
+// Finds a Jinja test by name; the tests named after keywords are registered in upper case (`TRUE`, `None`)
+func lookupTest(name string) func(v, arg *compilerInterface.Value) (bool, error) {
+	for _, candidate := range []string{name, strings.ToUpper(name), capitalize(name)} {
+		if test, found := ast.BuiltInTests[candidate]; found {
+			return test
+		}
+	}
+
+	return nil
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	first, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(first)) + strings.ToLower(s[size:])
+}
+
+// Breaks a line into lines no longer than the width, breaking words longer than the width if asked to
+func wrapWords(line string, width int, breakLongWords bool) []string {
+	lines := make([]string, 0)
+	var current []rune
+
+	for _, word := range strings.Fields(line) {
+		runes := []rune(word)
+
+		for breakLongWords && len(runes) > width {
+			if len(current) > 0 {
+				space := width - len(current) - 1
+				if space <= 0 {
+					lines = append(lines, string(current))
+					current = nil
+					continue
+				}
+
+				lines = append(lines, string(current)+" "+string(runes[:space]))
+				current, runes = nil, runes[space:]
+				continue
+			}
+
+			lines = append(lines, string(runes[:width]))
+			runes = runes[width:]
+		}
+
+		switch {
+		case len(runes) == 0:
+		case len(current) == 0:
+			current = runes
+		case len(current)+1+len(runes) <= width:
+			current = append(append(current, ' '), runes...)
+		default:
+			lines = append(lines, string(current))
+			current = runes
+		}
+	}
+
+	if len(current) > 0 || len(lines) == 0 {
+		lines = append(lines, string(current))
+	}
+
+	return lines
+}
+
+// Renders a value as Python's str() would, so lists are rendered as `['a', 1]`
+func pythonString(value *compilerInterface.Value) string {
+	value = value.Unwrap()
+
+	switch value.Type() {
+	case compilerInterface.ListVal, compilerInterface.MapVal:
+		return pythonRepr(value)
+
+	case compilerInterface.BooleanValue:
+		if value.BooleanValue {
+			return "True"
+		}
+		return "False"
+
+	case compilerInterface.NullVal, compilerInterface.Undefined:
+		return "None"
+
+	default:
+		return value.AsStringValue()
+	}
+}
+
+func pythonRepr(value *compilerInterface.Value) string {
+	value = value.Unwrap()
+
+	switch value.Type() {
+	case compilerInterface.StringVal:
+		return "'" + strings.ReplaceAll(strings.ReplaceAll(value.StringValue, `\`, `\\`), "'", `\'`) + "'"
+
+	case compilerInterface.ListVal:
+		parts := make([]string, len(value.ListValue))
+		for i, item := range value.ListValue {
+			parts[i] = pythonRepr(item)
+		}
+
+		if value.IsTuple {
+			return "(" + strings.Join(parts, ", ") + ")"
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+
+	case compilerInterface.MapVal:
+		keys := sortedKeys(value.MapValue)
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = pythonRepr(compilerInterface.NewString(key)) + ": " + pythonRepr(value.MapValue[key])
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+
+	default:
+		return pythonString(value)
+	}
+}
@@ -1,6 +1,7 @@
 package compiler
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -8,6 +9,7 @@ import (
 	"ddbt/bigquery"
 	"ddbt/compiler/dbtUtils"
 	"ddbt/compilerInterface"
+	"ddbt/config"
 	"ddbt/fs"
 	"ddbt/utils"
 )
@@ -24,7 +26,7 @@ var builtInFunctions = map[string]compilerInterface.FunctionDef{
 		return compilerInterface.NewBoolean(args[0].Value.TruthyValue()), nil
 	},
 
-	"as_native": notImplemented(),
+	"as_native": asNativeFunction,
 
 	"as_number": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
 		if err := expectArgs(ec, caller, "as_number", 1, args); err != nil {
@@ -76,15 +78,15 @@ var builtInFunctions = map[string]compilerInterface.FunctionDef{
 
 	"execute": nil, // Note this is defined in the global context
 
-	"flags": notImplemented(),
+	"flags": nil, // Note this is defined in the global context
 
-	"fromjson": notImplemented(),
+	"fromjson": fromJSONFunction,
 
-	"fromyaml": notImplemented(),
+	"fromyaml": fromYAMLFunction,
 
 	"graph": notImplemented(),
 
-	"invocation_id": notImplemented(),
+	"invocation_id": nil, // Note this is defined in the global context
 
 	"log": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
 		var builder strings.Builder
@@ -98,26 +100,16 @@ var builtInFunctions = map[string]compilerInterface.FunctionDef{
 		return compilerInterface.NewUndefined(), nil
 	},
 
+	"load_result": loadResultFunction,
+
 	"modules": nil, // Note this is defined in the global context
 
+	"namespace": namespaceFunction,
+
 	"project_name": nil, // Note this is defined in the global context
 
 	"ref": refFunction,
 
-	"replace": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
-		if len(args) != 3 {
-			return nil, ec.ErrorAt(caller, fmt.Sprintf("replace requires 3 parameters, got %d", len(args)))
-		}
-
-		value := strings.Replace(
-			args[0].Value.AsStringValue(),
-			args[1].Value.AsStringValue(),
-			args[2].Value.AsStringValue(),
-			1,
-		)
-		return compilerInterface.NewString(value), nil
-	},
-
 	"return": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
 		if len(args) != 1 {
 			return nil, ec.ErrorAt(caller, fmt.Sprintf("return requires 1 parameter, got %d", len(args)))
@@ -126,134 +118,77 @@ var builtInFunctions = map[string]compilerInterface.FunctionDef{
 		return compilerInterface.NewReturnValue(args[0].Value), nil
 	},
 
-	"run_query": notImplemented(),
+	"run_query": runQueryFunction,
 
-	"run_started_at": notImplemented(),
+	"run_started_at": nil, // Note this is defined in the global context
 
 	"schema": notImplemented(),
 
 	"source": notImplemented(),
 
-	"statement": notImplemented(),
+	"statement": statementFunction,
 
 	"target": nil, // Note this is defined in the global context
 
 	"this": nil, // Note this is defined by the compiler when creating the original execution context
 
-	"tojson": notImplemented(),
+	"tojson": toJSONFunction,
 
-	"toyaml": notImplemented(),
+	"toyaml": toYAMLFunction,
 
-	"var": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
-		if len(args) < 1 {
-			return nil, ec.ErrorAt(caller, fmt.Sprintf("var requires at least 1 parameter, got %d", len(args)))
-		}
-
-		// FIXME: implement lookup to project level information
-
-		if len(args) > 1 {
-			return args[1].Value, nil
-		} else {
-			return compilerInterface.NewUndefined(), nil
-		}
-	},
+	"var": nil, // Note this is defined as a constant of the global context, as it also has a has_var method
 
 	// Extra's not in their main list
 	// https://docs.getdbt.com/docs/building-a-dbt-project/building-models/configuring-incremental-models/#filtering-rows-on-an-incremental-run
 	"is_incremental": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
-		return compilerInterface.NewBoolean(false), nil
-	},
+		// Whether the model's table already exists can only be known when we're about to execute it
+		if isOnlyCompilingSQL(ec) {
+			if _, err := ec.MarkAsDynamicSQL(); err != nil {
+				return nil, err
+			}
 
-	// Jinja2 Filter functions
-	"upper": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
-		values, err := requiredArgs(ec, caller, args, "upper", compilerInterface.StringVal)
-		if err != nil {
-			return nil, err
+			return compilerInterface.NewBoolean(false), nil
 		}
 
-		return compilerInterface.NewString(strings.ToUpper(values[0].AsStringValue())), nil
-	},
+		e, ok := ec.(*ExecutionContext)
+		if !ok || e.refOverrides != nil {
+			// Copies compiled with ref overrides (unit tests and the validation of atomic builds) are never incremental
+			return compilerInterface.NewBoolean(false), nil
+		}
 
-	"lower": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
-		values, err := requiredArgs(ec, caller, args, "lower", compilerInterface.StringVal)
+		incremental, err := bigquery.IsIncremental(context.Background(), e.file)
 		if err != nil {
-			return nil, err
+			return nil, ec.ErrorAt(caller, err.Error())
 		}
 
-		return compilerInterface.NewString(strings.ToLower(values[0].AsStringValue())), nil
+		return compilerInterface.NewBoolean(incremental), nil
 	},
 
-	"default": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
-		if len(args) != 2 {
-			return nil, ec.ErrorAt(caller, fmt.Sprintf("default requires 2 argumnets, got %d", len(args)))
-		}
-
-		if args[0].Value.IsUndefined || args[0].Value.IsNull {
-			return args[1].Value, nil
-		} else {
-			return args[0].Value, nil
-		}
-	},
+	// Jinja2 Filter functions are defined in builtInFilters
 
 	// Our specific functions
 	"indirect_ref": refFunction,
 
-	// Our specific functions ( without implementations in ddbt )
-	"is_marked_for_full_refresh": noopMethod(),
-
-	// DDBT Debugging function - Allows removing of macro's from models without completely removing them
-	"noop": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
-		return compilerInterface.NewUndefined(), nil
-	},
-}
-
-// The date time module from https://docs.getdbt.com/reference/dbt-jinja-functions/modules
-var datetimeFunctions = map[string]compilerInterface.FunctionDef{
-	// FIXME: we may need to implement this better
-	"time": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
-		values, err := requiredArgs(
-			ec, caller, args, "modules.datetime.time",
-			compilerInterface.NumberVal, compilerInterface.NumberVal, compilerInterface.NumberVal,
-		)
-		if err != nil {
-			return nil, err
+	"is_marked_for_full_refresh": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		if e, ok := ec.(*ExecutionContext); ok {
+			return compilerInterface.NewBoolean(e.file.IsFullRefresh()), nil
 		}
 
-		return compilerInterface.NewString(fmt.Sprintf("%2.f:%2.f:%2.f", values[0].NumberValue, values[0].NumberValue, values[0].NumberValue)), nil
+		return compilerInterface.NewBoolean(config.GlobalCfg != nil && config.GlobalCfg.FullRefresh), nil
 	},
 
-	"timedelta": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
-		values, err := requiredArgs(
-			ec, caller, args, "modules.datetime.timedelta",
-			compilerInterface.NumberVal,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		return compilerInterface.NewString(args[0].Name + "=" + values[0].AsStringValue()), nil
+	// DDBT Debugging function - Allows removing of macro's from models without completely removing them
+	"noop": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		return compilerInterface.NewUndefined(), nil
 	},
 }
 
 //https://docs.getdbt.com/reference/dbt-jinja-functions/adapter
 var adapterFunctions = map[string]compilerInterface.FunctionDef{
-	"dispatch":                   noopMethod(),
-	"get_missing_columns":        noopMethod(),
-	"expand_target_column_types": noopMethod(),
-	"get_relation": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
-		arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("database"), dbtUtils.Param("schema"), dbtUtils.Param("identifier"))
-		if err != nil {
-			return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
-		}
-
-		projectID := arguments[0].AsStringValue()
-		dataSet := arguments[1].AsStringValue()
-		table := arguments[2].AsStringValue()
-
-		return compilerInterface.NewString(
-			"`" + projectID + "`.`" + dataSet + "`.`" + table + "`",
-		), nil
-	},
+	"dispatch":                   adapterDispatch,
+	"get_missing_columns":        adapterGetMissingColumns,
+	"expand_target_column_types": adapterExpandTargetColumnTypes,
+	"get_relation":               adapterGetRelation,
 	"get_columns_in_relation": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
 		values, err := requiredArgs(ec, caller, args, "adapter.get_columns_in_relation", compilerInterface.StringVal)
 		if err != nil {
@@ -287,16 +222,89 @@ var adapterFunctions = map[string]compilerInterface.FunctionDef{
 
 		return compilerInterface.NewList(returnColumns), nil
 	},
-	"create_schema":        noopMethod(),
-	"drop_schema":          noopMethod(),
-	"drop_relation":        noopMethod(),
-	"rename_relation":      noopMethod(),
-	"get_columns_in_table": noopMethod(),
-	"already_exists":       noopMethod(),
+	"create_schema": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		target, err := schemaTarget(ec, caller, args)
+		if err != nil {
+			return nil, err
+		}
+
+		if isOnlyCompilingSQL(ec) {
+			return ec.MarkAsDynamicSQL()
+		}
+
+		if _, err := bigquery.CreateDatasetIfMissing(context.Background(), target); err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+
+		return compilerInterface.NewUndefined(), nil
+	},
+	"drop_schema": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		target, err := schemaTarget(ec, caller, args)
+		if err != nil {
+			return nil, err
+		}
+
+		if isOnlyCompilingSQL(ec) {
+			return ec.MarkAsDynamicSQL()
+		}
+
+		if err := bigquery.DropDataset(context.Background(), target); err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+
+		return compilerInterface.NewUndefined(), nil
+	},
+	"drop_relation":        adapterDropRelation,
+	"rename_relation":      adapterRenameRelation,
+	"get_columns_in_table": adapterGetColumnsInTable,
+	"already_exists":       adapterAlreadyExists,
 	"adapter_macro":        noopMethod(),
 
 	// Note listed on their site
-	"check_schema_exists": noopMethod(),
+	"check_schema_exists": func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		target, err := schemaTarget(ec, caller, args)
+		if err != nil {
+			return nil, err
+		}
+
+		if isOnlyCompilingSQL(ec) {
+			return ec.MarkAsDynamicSQL()
+		}
+
+		exists, err := bigquery.DatasetExists(context.Background(), target)
+		if err != nil {
+			return nil, ec.ErrorAt(caller, err.Error())
+		}
+
+		return compilerInterface.NewBoolean(exists), nil
+	},
+}
+
+// The target for the dataset passed to the schema adapter methods, in the project of the current target unless
+// another database is given
+func schemaTarget(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*config.Target, error) {
+	arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("database"), dbtUtils.Param("schema"))
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	target, err := ec.GetTarget()
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("Unable to get the target: %s", err))
+	}
+	target = target.Copy()
+
+	if database := arguments[0]; database.Type() == compilerInterface.StringVal && database.StringValue != "" {
+		target.ProjectID = database.StringValue
+	}
+
+	if schema := arguments[1]; schema.Type() == compilerInterface.StringVal && schema.StringValue != "" {
+		target.DataSet = schema.StringValue
+	} else {
+		return nil, ec.ErrorAt(caller, "a schema is required")
+	}
+
+	return target, nil
 }
 
 func notImplemented() compilerInterface.FunctionDef {
@@ -327,11 +335,45 @@ func refFunction(ec compilerInterface.ExecutionContext, caller compilerInterface
 
 	modelName := values[0].AsStringValue()
 
+	// A model in a package can be referenced as `ref('my_package', 'my_model')`
+	if len(args) > 1 {
+		packageName := modelName
+		modelName = args[1].Value.AsStringValue()
+
+		if config.GlobalCfg == nil || packageName != config.GlobalCfg.Name {
+			modelName = packageName + "." + modelName
+		}
+	}
+
 	return ec.RegisterUpstreamAndGetRef(modelName, string(fs.ModelFile))
 }
 
 type funcMap = map[string]compilerInterface.FunctionDef
 
+// namespace creates an object whose attributes can be changed with `{% set ns.attr = value %}`, even from inside a loop
+func namespaceFunction(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	attributes := make(map[string]*compilerInterface.Value)
+
+	for _, arg := range args {
+		value := arg.Value.Unwrap()
+
+		if arg.Name != "" {
+			attributes[arg.Name] = value
+			continue
+		}
+
+		if value.Type() != compilerInterface.MapVal {
+			return nil, ec.ErrorAt(caller, fmt.Sprintf("namespace expects a map or keyword arguments, got %s", value.Type()))
+		}
+
+		for key, item := range value.MapValue {
+			attributes[key] = item
+		}
+	}
+
+	return compilerInterface.NewNamespace(attributes), nil
+}
+
 func funcMapAsValue(in funcMap) *compilerInterface.Value {
 	rtn := make(map[string]*compilerInterface.Value)
 
@@ -4,6 +4,9 @@ import "ddbt/fs"
 
 // All our built in Macros
 const builtInMacros = `
+{# Returns the number of failing rows, or the failing rows themselves if we're storing them #}
+{% macro test_result(cte) %}{% if config.get('store_failures', false) %}SELECT * FROM {{ cte }}{% else %}SELECT COUNT(*) as num_errors FROM {{ cte }}{% endif %}{% endmacro %}
+
 {# This test checks that the value in column_name is always unique #}
 {% macro test_unique(model, column_name) %}
 WITH test_data AS (
@@ -18,7 +21,7 @@ WITH test_data AS (
 	HAVING COUNT({{ column_name }}) > 1
 )
 
-SELECT COUNT(*) as num_errors FROM test_data
+{{ test_result('test_data') }}
 {% endmacro %}
 
 
@@ -33,7 +36,7 @@ WITH test_data AS (
 	WHERE {{ column_name }} IS NULL
 )
 
-SELECT COUNT(*) as num_errors FROM test_data
+{{ test_result('test_data') }}
 {% endmacro %}
 
 
@@ -53,7 +56,7 @@ WITH test_data AS (
 	)
 )
 
-SELECT COUNT(*) as num_errors FROM test_data
+{{ test_result('test_data') }}
 {% endmacro %}
 
 {% macro test_relationships(model, column_name, to, field) %}
@@ -69,7 +72,223 @@ WITH test_data AS (
 	WHERE dest.{{ field }} IS NULL AND src.{{ column_name }} IS NOT NULL
 )
 
-SELECT COUNT(*) as num_errors FROM test_data
+{{ test_result('test_data') }}
+{% endmacro %}
+
+{# This test checks that the value is never null in column_name, for the rows selected by the where config #}
+{% macro test_not_null_where(model, column_name) %}
+WITH test_data AS (
+	SELECT
+	{{ column_name }} AS value
+	
+	FROM {{ model }}
+	
+	WHERE {{ column_name }} IS NULL
+)
+
+{{ test_result('test_data') }}
+{% endmacro %}
+
+
+{# This test checks that the combination of the given columns is always unique #}
+{% macro test_unique_combination_of_columns(model, combination_of_columns) %}
+WITH test_data AS (
+	SELECT
+	{% for column in combination_of_columns -%}
+		{{ column }},
+	{% endfor -%}
+	COUNT(*) AS count
+
+	FROM {{ model }}
+
+	GROUP BY {% for column in combination_of_columns %}{{ column }}{% if not loop.last %}, {% endif %}{% endfor %}
+
+	HAVING COUNT(*) > 1
+)
+
+{{ test_result('test_data') }}
+{% endmacro %}
+
+
+{# This test checks that the expression is true for every row, if a column_name is given it's prefixed to the expression #}
+{% macro test_expression_is_true(model, expression, column_name=none) %}
+WITH test_data AS (
+	SELECT
+	*
+
+	FROM {{ model }}
+
+	WHERE NOT({% if column_name %}{{ column_name }} {% endif %}{{ expression }})
+)
+
+{{ test_result('test_data') }}
+{% endmacro %}
+
+
+{# This test checks that the most recent value in field is within the last interval datepart's #}
+{% macro test_recency(model, field, datepart, interval) %}
+WITH recency AS (
+	SELECT
+	MAX(CAST({{ field }} AS DATETIME)) AS most_recent
+
+	FROM {{ model }}
+),
+
+test_data AS (
+	SELECT
+	most_recent
+
+	FROM recency
+
+	WHERE most_recent IS NULL OR most_recent < DATETIME_SUB(CURRENT_DATETIME(), INTERVAL {{ interval }} {{ datepart }})
+)
+
+{{ test_result('test_data') }}
+{% endmacro %}
+
+
+{# This test checks that column_name has at least one value which is not null #}
+{% macro test_at_least_one(model, column_name) %}
+WITH test_data AS (
+	SELECT
+	COUNT({{ column_name }}) AS count
+
+	FROM {{ model }}
+
+	HAVING COUNT({{ column_name }}) = 0
+)
+
+{{ test_result('test_data') }}
+{% endmacro %}
+
+
+{# This test checks that column_name has more than one distinct value #}
+{% macro test_not_constant(model, column_name) %}
+WITH test_data AS (
+	SELECT
+	COUNT(DISTINCT {{ column_name }}) AS count
+
+	FROM {{ model }}
+
+	HAVING COUNT(DISTINCT {{ column_name }}) = 1
+)
+
+{{ test_result('test_data') }}
+{% endmacro %}
+
+
+{# This test checks that the model has the same number of rows as compare_model #}
+{% macro test_equal_rowcount(model, compare_model) %}
+WITH model_count AS (
+	SELECT COUNT(*) AS count FROM {{ model }}
+),
+
+compare_model_count AS (
+	SELECT COUNT(*) AS count FROM {{ compare_model }}
+),
+
+test_data AS (
+	SELECT
+	model_count.count AS model_count,
+	compare_model_count.count AS compare_model_count
+
+	FROM model_count
+	CROSS JOIN compare_model_count
+
+	WHERE model_count.count != compare_model_count.count
+)
+
+{{ test_result('test_data') }}
+{% endmacro %}
+
+
+{# This test checks that the value in column_name is always between the min_value and max_value #}
+{% macro test_accepted_range(model, column_name, min_value, max_value, inclusive=true) %}
+WITH test_data AS (
+	SELECT
+	{{ column_name }} AS value
+
+	FROM {{ model }}
+
+	WHERE 1 = 2
+	{%- if min_value is defined %}
+	OR NOT({{ column_name }} >{% if inclusive %}={% endif %} {{ min_value }})
+	{%- endif %}
+	{%- if max_value is defined %}
+	OR NOT({{ column_name }} <{% if inclusive %}={% endif %} {{ max_value }})
+	{%- endif %}
+)
+
+{{ test_result('test_data') }}
+{% endmacro %}
+
+
+{# This test checks that the ranges defined by the lower and upper bound columns never overlap #}
+{% macro test_mutually_exclusive_ranges(model, lower_bound_column, upper_bound_column, partition_by=none, gaps='allowed', zero_length_range_allowed=false) %}
+WITH window_functions AS (
+	SELECT
+	{% if partition_by %}{{ partition_by }} AS partition_by_col,
+	{% endif -%}
+	{{ lower_bound_column }} AS lower_bound,
+	{{ upper_bound_column }} AS upper_bound,
+
+	LEAD({{ lower_bound_column }}) OVER (
+		{% if partition_by %}PARTITION BY {{ partition_by }} {% endif %}ORDER BY {{ lower_bound_column }}, {{ upper_bound_column }}
+	) AS next_lower_bound,
+
+	ROW_NUMBER() OVER (
+		{% if partition_by %}PARTITION BY {{ partition_by }} {% endif %}ORDER BY {{ lower_bound_column }} DESC, {{ upper_bound_column }} DESC
+	) = 1 AS is_last_record
+
+	FROM {{ model }}
+),
+
+calc AS (
+	SELECT
+	*,
+	COALESCE(lower_bound <{% if zero_length_range_allowed %}={% endif %} upper_bound, FALSE) AS lower_bound_less_than_upper_bound,
+	COALESCE(upper_bound {% if gaps == 'not_allowed' %}={% elif gaps == 'required' %}<{% else %}<={% endif %} next_lower_bound, is_last_record, FALSE) AS upper_bound_before_next_lower_bound
+
+	FROM window_functions
+),
+
+test_data AS (
+	SELECT
+	*
+
+	FROM calc
+
+	WHERE NOT(lower_bound_less_than_upper_bound AND upper_bound_before_next_lower_bound)
+)
+
+{{ test_result('test_data') }}
+{% endmacro %}
+
+
+{# This test checks that the values in column_name increase by interval (or interval datepart's for dates) each row #}
+{% macro test_sequential_values(model, column_name, interval=1, datepart=none) %}
+WITH windowed AS (
+	SELECT
+	{{ column_name }} AS value,
+	LAG({{ column_name }}) OVER (ORDER BY {{ column_name }}) AS previous_value
+
+	FROM {{ model }}
+),
+
+test_data AS (
+	SELECT
+	*
+
+	FROM windowed
+
+	{% if datepart -%}
+	WHERE NOT(CAST(value AS DATETIME) = DATETIME_ADD(CAST(previous_value AS DATETIME), INTERVAL {{ interval }} {{ datepart }}))
+	{%- else -%}
+	WHERE NOT(value = previous_value + {{ interval }})
+	{%- endif %}
+)
+
+{{ test_result('test_data') }}
 {% endmacro %}
 `
 
@@ -0,0 +1,324 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"ddbt/bigquery"
+	"ddbt/compiler/dbtUtils"
+	"ddbt/compilerInterface"
+)
+
+// The results of the statement blocks executed while compiling a file, for load_result to return
+type statementResults struct {
+	mutex   sync.Mutex
+	results map[string]*compilerInterface.Value
+}
+
+func newStatementResults() *statementResults {
+	return &statementResults{results: make(map[string]*compilerInterface.Value)}
+}
+
+func (s *statementResults) store(name string, result *compilerInterface.Value) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.results[name] = result
+}
+
+func (s *statementResults) load(name string) (*compilerInterface.Value, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	result, found := s.results[name]
+	return result, found
+}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/run_query
+func runQueryFunction(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	values, err := requiredArgs(ec, caller, args, "run_query", compilerInterface.StringVal)
+	if err != nil {
+		return nil, err
+	}
+
+	// The query can only be run once we're executing the model
+	if isOnlyCompilingSQL(ec) {
+		return ec.MarkAsDynamicSQL()
+	}
+
+	rows, schema, err := runQuery(ec, values[0].StringValue)
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("run_query returned an error: %s", err))
+	}
+
+	return newAgateTable(rows, schema), nil
+}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/statement-blocks
+//
+// Used as `{% call statement('name', fetch_result=True) %}SQL{% endcall %}`
+func statementFunction(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	arguments, err := dbtUtils.GetArgs(
+		args,
+		dbtUtils.Param("name"),
+		dbtUtils.ParamWithDefault("fetch_result", compilerInterface.NewBoolean(false)),
+		dbtUtils.Param("auto_begin"),
+		dbtUtils.Param("language"),
+	)
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	body := ec.GetVariable("caller")
+	if body.Type() != compilerInterface.FunctionalVal {
+		return nil, ec.ErrorAt(caller, "statement must be used in a call block: {% call statement('name') %}...{% endcall %}")
+	}
+
+	// Statements are only run once we're executing the model
+	if isOnlyCompilingSQL(ec) {
+		if _, err := ec.MarkAsDynamicSQL(); err != nil {
+			return nil, err
+		}
+
+		return compilerInterface.NewString(""), nil
+	}
+
+	sql, err := body.Function(ec, caller, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, schema, err := runQuery(ec, sql.AsStringValue())
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("statement returned an error: %s", err))
+	}
+
+	name := arguments[0].AsStringValue()
+	if name == "" {
+		return compilerInterface.NewString(""), nil
+	}
+
+	e, ok := ec.(*ExecutionContext)
+	if !ok {
+		return nil, ec.ErrorAt(caller, "statement results can only be stored while compiling a file")
+	}
+
+	result := map[string]*compilerInterface.Value{
+		"data":  compilerInterface.NewList([]*compilerInterface.Value{}),
+		"table": compilerInterface.NewUndefined(),
+	}
+
+	if arguments[1].TruthyValue() {
+		data := make([]*compilerInterface.Value, len(rows))
+		for i, row := range rows {
+			data[i] = newQueryRow(row)
+		}
+
+		result["data"] = compilerInterface.NewList(data)
+		result["table"] = newAgateTable(rows, schema)
+	}
+
+	e.statements.store(name, compilerInterface.NewMap(result))
+
+	return compilerInterface.NewString(""), nil
+}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/load_result
+func loadResultFunction(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	values, err := requiredArgs(ec, caller, args, "load_result", compilerInterface.StringVal)
+	if err != nil {
+		return nil, err
+	}
+
+	e, ok := ec.(*ExecutionContext)
+	if !ok {
+		return compilerInterface.NewUndefined(), nil
+	}
+
+	if result, found := e.statements.load(values[0].StringValue); found {
+		return result, nil
+	}
+
+	return compilerInterface.NewUndefined(), nil
+}
+
+func runQuery(ec compilerInterface.ExecutionContext, query string) ([][]bigquery.Value, bigquery.Schema, error) {
+	target, err := ec.GetTarget()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return bigquery.GetRows(context.Background(), query, target)
+}
+
+// Builds a table of query results which behaves like the Agate tables dbt returns, with `columns`, `rows`,
+// `column_names`, `column_types` and `print_table()`
+func newAgateTable(rows [][]bigquery.Value, schema bigquery.Schema) *compilerInterface.Value {
+	names := make([]string, len(schema))
+	types := make([]string, len(schema))
+	for i, field := range schema {
+		names[i] = field.Name
+		types[i] = string(field.Type)
+	}
+
+	tableRows := make([]*compilerInterface.Value, len(rows))
+	for i, row := range rows {
+		tableRows[i] = compilerInterface.NewNamedList(names, newQueryRow(row).ListValue)
+	}
+
+	columns := make([]*compilerInterface.Value, len(schema))
+	for i := range schema {
+		values := make([]*compilerInterface.Value, len(tableRows))
+		for j, row := range tableRows {
+			if i < len(row.ListValue) {
+				values[j] = row.ListValue[i]
+			} else {
+				values[j] = compilerInterface.NewNull()
+			}
+		}
+
+		columnValues := compilerInterface.NewList(values)
+		columns[i] = compilerInterface.NewMap(map[string]*compilerInterface.Value{
+			"name":      compilerInterface.NewString(names[i]),
+			"data_type": compilerInterface.NewString(types[i]),
+			"values": compilerInterface.NewFunction(func(_ compilerInterface.ExecutionContext, _ compilerInterface.AST, _ compilerInterface.Arguments) (*compilerInterface.Value, error) {
+				return columnValues, nil
+			}),
+		})
+	}
+
+	return compilerInterface.NewMap(map[string]*compilerInterface.Value{
+		"columns":      compilerInterface.NewNamedList(names, columns),
+		"rows":         compilerInterface.NewList(tableRows),
+		"column_names": compilerInterface.NewStringList(names),
+		"column_types": compilerInterface.NewStringList(types),
+		"print_table": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			arguments, err := dbtUtils.GetArgs(args, dbtUtils.ParamWithDefault("max_rows", compilerInterface.NewNumber(20)))
+			if err != nil {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+			}
+
+			maxRows, err := arguments[0].AsNumberValue()
+			if err != nil {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("max_rows must be a number: %s", err))
+			}
+
+			e, ok := ec.(*ExecutionContext)
+			if !ok {
+				return nil, ec.ErrorAt(caller, "print_table can only be used while compiling a file")
+			}
+
+			_, _ = fmt.Fprint(e.globalContext.output, formatTable(names, tableRows, int(maxRows)))
+
+			return compilerInterface.NewString(""), nil
+		}),
+	})
+}
+
+// Converts a row of query results into a list of values
+func newQueryRow(row []bigquery.Value) *compilerInterface.Value {
+	values := make([]*compilerInterface.Value, len(row))
+	for i, value := range row {
+		values[i] = newQueryValue(value)
+	}
+
+	return compilerInterface.NewList(values)
+}
+
+func newQueryValue(value bigquery.Value) *compilerInterface.Value {
+	switch v := value.(type) {
+	case nil:
+		return compilerInterface.NewNull()
+
+	case []bigquery.Value:
+		return newQueryRow(v)
+
+	case *big.Rat:
+		f, _ := v.Float64()
+		return compilerInterface.NewNumber(f)
+
+	case time.Time:
+		// Match how Python prints a datetime
+		return compilerInterface.NewString(v.Format("2006-01-02 15:04:05.999999-07:00"))
+	}
+
+	if converted, err := compilerInterface.NewValueFromInterface(value); err == nil {
+		return converted
+	}
+
+	return compilerInterface.NewString(fmt.Sprint(value))
+}
+
+// Formats the rows as a text table in the same style as Agate's print_table
+func formatTable(names []string, rows []*compilerInterface.Value, maxRows int) string {
+	if maxRows < 0 || maxRows > len(rows) {
+		maxRows = len(rows)
+	}
+
+	cells := make([][]string, maxRows)
+	widths := make([]int, len(names))
+	for i, name := range names {
+		widths[i] = len(name)
+
+		// Leave room for the ellipses of truncated tables
+		if maxRows < len(rows) && widths[i] < 3 {
+			widths[i] = 3
+		}
+	}
+
+	for i, row := range rows[:maxRows] {
+		cells[i] = make([]string, len(names))
+
+		for j := range names {
+			if j < len(row.ListValue) && row.ListValue[j].Type() != compilerInterface.NullVal {
+				cells[i][j] = row.ListValue[j].AsStringValue()
+			}
+
+			if len(cells[i][j]) > widths[j] {
+				widths[j] = len(cells[i][j])
+			}
+		}
+	}
+
+	var builder strings.Builder
+	writeRow := func(values []string, rightAlign func(column int) bool) {
+		builder.WriteString("|")
+		for i, value := range values {
+			if rightAlign(i) {
+				builder.WriteString(fmt.Sprintf(" %*s |", widths[i], value))
+			} else {
+				builder.WriteString(fmt.Sprintf(" %-*s |", widths[i], value))
+			}
+		}
+		builder.WriteString("\n")
+	}
+	alignLeft := func(int) bool { return false }
+
+	writeRow(names, alignLeft)
+
+	separators := make([]string, len(names))
+	for i, width := range widths {
+		separators[i] = strings.Repeat("-", width)
+	}
+	writeRow(separators, alignLeft)
+
+	for i, row := range cells {
+		writeRow(row, func(column int) bool {
+			return column < len(rows[i].ListValue) && rows[i].ListValue[column].Type() == compilerInterface.NumberVal
+		})
+	}
+
+	if maxRows < len(rows) {
+		ellipses := make([]string, len(names))
+		for i := range ellipses {
+			ellipses[i] = "..."
+		}
+		writeRow(ellipses, alignLeft)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,74 @@
+package compiler
+
+import (
+	"bytes"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAgateTable(t *testing.T) {
+	schema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "name", Type: bigquery.StringFieldType},
+	}
+	rows := [][]bigquery.Value{
+		{int64(1), "first"},
+		{int64(22), nil},
+	}
+
+	table := newAgateTable(rows, schema)
+
+	assert.Equal(t, []string{"id", "name"}, []string{
+		table.MapValue["column_names"].ListValue[0].StringValue,
+		table.MapValue["column_names"].ListValue[1].StringValue,
+	})
+
+	tableRows := table.MapValue["rows"].ListValue
+	require.Len(t, tableRows, 2)
+	assert.Equal(t, "first", tableRows[0].MapValue["name"].StringValue)
+	assert.True(t, tableRows[1].ListValue[1].IsNull)
+
+	names := table.MapValue["columns"].MapValue["name"]
+	assert.Equal(t, "STRING", names.MapValue["data_type"].StringValue)
+
+	values, err := names.MapValue["values"].Function(nil, nil, nil)
+	require.NoError(t, err)
+	assert.Len(t, values.ListValue, 2)
+
+	assert.Equal(
+		t,
+		"| id | name  |\n"+
+			"| -- | ----- |\n"+
+			"|  1 | first |\n"+
+			"| 22 |       |\n",
+		formatTable([]string{"id", "name"}, tableRows, 20),
+	)
+
+	assert.Equal(
+		t,
+		"| id  | name  |\n"+
+			"| --- | ----- |\n"+
+			"|   1 | first |\n"+
+			"| ... | ...   |\n",
+		formatTable([]string{"id", "name"}, tableRows, 1),
+	)
+}
+
+func TestPrintTableWritesToTheOutput(t *testing.T) {
+	table := newAgateTable(
+		[][]bigquery.Value{{int64(1)}},
+		bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+	)
+
+	var output bytes.Buffer
+	gc := &GlobalContext{}
+	gc.SetOutput(&output)
+
+	_, err := table.MapValue["print_table"].Function(&ExecutionContext{globalContext: gc}, nil, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "| id |\n| -- |\n|  1 |\n", output.String())
+}
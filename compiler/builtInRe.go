@@ -0,0 +1,345 @@
+package compiler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ddbt/compiler/dbtUtils"
+	"ddbt/compilerInterface"
+)
+
+// The flags of Python's re module, which are turned into inline flags as Go's regexp doesn't take flags
+const (
+	reIgnoreCase = 2
+	reMultiline  = 8
+	reDotAll     = 16
+)
+
+// https://docs.python.org/3/library/re.html
+//
+// Patterns are run by Go's regexp package, which doesn't support backreferences or lookarounds
+func newReModule() *compilerInterface.Value {
+	module := map[string]*compilerInterface.Value{
+		"I":          compilerInterface.NewNumber(reIgnoreCase),
+		"IGNORECASE": compilerInterface.NewNumber(reIgnoreCase),
+		"M":          compilerInterface.NewNumber(reMultiline),
+		"MULTILINE":  compilerInterface.NewNumber(reMultiline),
+		"S":          compilerInterface.NewNumber(reDotAll),
+		"DOTALL":     compilerInterface.NewNumber(reDotAll),
+
+		"compile": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("pattern"), dbtUtils.ParamWithDefault("flags", compilerInterface.NewNumber(0)))
+			if err != nil {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+			}
+
+			re, err := compileRegex(arguments[0], arguments[1])
+			if err != nil {
+				return nil, ec.ErrorAt(caller, err.Error())
+			}
+
+			return newPatternValue(re, arguments[0].AsStringValue()), nil
+		}),
+
+		"escape": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			values, err := requiredArgs(ec, caller, args, "re.escape", compilerInterface.StringVal)
+			if err != nil {
+				return nil, err
+			}
+
+			return compilerInterface.NewString(regexp.QuoteMeta(values[0].StringValue)), nil
+		}),
+	}
+
+	// The module level functions take the pattern as their first argument, then the same arguments as the methods of
+	// a compiled pattern followed by the flags
+	for name, method := range regexMethods {
+		name, method := name, method
+		params := append([]string{"pattern"}, method.params...)
+
+		module[name] = compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			arguments, err := dbtUtils.GetArgs(args, append(regexParams(params, method.defaults), dbtUtils.ParamWithDefault("flags", compilerInterface.NewNumber(0)))...)
+			if err != nil {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+			}
+
+			re, err := compileRegex(arguments[0], arguments[len(arguments)-1])
+			if err != nil {
+				return nil, ec.ErrorAt(caller, err.Error())
+			}
+
+			return method.function(ec, caller, re, arguments[1:len(arguments)-1])
+		})
+	}
+
+	return compilerInterface.NewMap(module)
+}
+
+type regexMethod struct {
+	params   []string
+	defaults map[string]*compilerInterface.Value
+	function func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, re *regexp.Regexp, args []*compilerInterface.Value) (*compilerInterface.Value, error)
+}
+
+var regexMethods = map[string]regexMethod{
+	"match": {
+		params: []string{"string"},
+		function: func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, re *regexp.Regexp, args []*compilerInterface.Value) (*compilerInterface.Value, error) {
+			s := args[0].AsStringValue()
+			return newMatchValue(re, s, anchoredMatch(re, s, false)), nil
+		},
+	},
+
+	"fullmatch": {
+		params: []string{"string"},
+		function: func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, re *regexp.Regexp, args []*compilerInterface.Value) (*compilerInterface.Value, error) {
+			s := args[0].AsStringValue()
+			return newMatchValue(re, s, anchoredMatch(re, s, true)), nil
+		},
+	},
+
+	"search": {
+		params: []string{"string"},
+		function: func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, re *regexp.Regexp, args []*compilerInterface.Value) (*compilerInterface.Value, error) {
+			s := args[0].AsStringValue()
+			return newMatchValue(re, s, re.FindStringSubmatchIndex(s)), nil
+		},
+	},
+
+	"findall": {
+		params: []string{"string"},
+		function: func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, re *regexp.Regexp, args []*compilerInterface.Value) (*compilerInterface.Value, error) {
+			matches := re.FindAllStringSubmatch(args[0].AsStringValue(), -1)
+			results := make([]*compilerInterface.Value, len(matches))
+
+			for i, match := range matches {
+				switch len(match) {
+				case 1:
+					// No groups returns the whole match
+					results[i] = compilerInterface.NewString(match[0])
+				case 2:
+					// A single group returns just that group
+					results[i] = compilerInterface.NewString(match[1])
+				default:
+					// Otherwise a tuple of the groups
+					results[i] = compilerInterface.NewStringList(match[1:])
+				}
+			}
+
+			return compilerInterface.NewList(results), nil
+		},
+	},
+
+	"sub": {
+		params:   []string{"repl", "string", "count"},
+		defaults: map[string]*compilerInterface.Value{"count": compilerInterface.NewNumber(0)},
+		function: func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, re *regexp.Regexp, args []*compilerInterface.Value) (*compilerInterface.Value, error) {
+			repl := pythonReplacement(args[0].AsStringValue())
+			s := args[1].AsStringValue()
+			count := int(args[2].NumberValue)
+
+			if count <= 0 {
+				return compilerInterface.NewString(re.ReplaceAllString(s, repl)), nil
+			}
+
+			var builder strings.Builder
+			last := 0
+			for _, match := range re.FindAllStringSubmatchIndex(s, count) {
+				builder.WriteString(s[last:match[0]])
+				builder.Write(re.ExpandString(nil, repl, s, match))
+				last = match[1]
+			}
+			builder.WriteString(s[last:])
+
+			return compilerInterface.NewString(builder.String()), nil
+		},
+	},
+
+	"split": {
+		params:   []string{"string", "maxsplit"},
+		defaults: map[string]*compilerInterface.Value{"maxsplit": compilerInterface.NewNumber(0)},
+		function: func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, re *regexp.Regexp, args []*compilerInterface.Value) (*compilerInterface.Value, error) {
+			n := -1
+			if maxSplit := int(args[1].NumberValue); maxSplit > 0 {
+				n = maxSplit + 1
+			}
+
+			return compilerInterface.NewStringList(re.Split(args[0].AsStringValue(), n)), nil
+		},
+	},
+}
+
+func regexParams(names []string, defaults map[string]*compilerInterface.Value) []compilerInterface.Argument {
+	params := make([]compilerInterface.Argument, len(names))
+
+	for i, name := range names {
+		params[i] = dbtUtils.ParamWithDefault(name, defaults[name])
+	}
+
+	return params
+}
+
+func compileRegex(pattern *compilerInterface.Value, flags *compilerInterface.Value) (*regexp.Regexp, error) {
+	if re, ok := pattern.Unwrap().Object.(*regexp.Regexp); ok {
+		return re, nil
+	}
+
+	inline := ""
+	f := int(flags.NumberValue)
+	if f&reIgnoreCase != 0 {
+		inline += "i"
+	}
+	if f&reMultiline != 0 {
+		inline += "m"
+	}
+	if f&reDotAll != 0 {
+		inline += "s"
+	}
+
+	expr := pattern.AsStringValue()
+	if inline != "" {
+		expr = "(?" + inline + ")" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression `%s`: %s", pattern.AsStringValue(), err)
+	}
+
+	return re, nil
+}
+
+// A compiled pattern, which has the same methods as the module without the pattern argument
+func newPatternValue(re *regexp.Regexp, pattern string) *compilerInterface.Value {
+	properties := map[string]*compilerInterface.Value{
+		"pattern": compilerInterface.NewString(pattern),
+	}
+
+	for name, method := range regexMethods {
+		method := method
+
+		properties[name] = compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			arguments, err := dbtUtils.GetArgs(args, regexParams(method.params, method.defaults)...)
+			if err != nil {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+			}
+
+			return method.function(ec, caller, re, arguments)
+		})
+	}
+
+	value := compilerInterface.NewStringWithProperties(pattern, properties)
+	value.Object = re
+
+	return value
+}
+
+// Finds a match which starts at the beginning of the string, and if full is set, also ends at the end of it
+func anchoredMatch(re *regexp.Regexp, s string, full bool) []int {
+	expr := `^(?:` + re.String() + `)`
+	if full {
+		expr += `$`
+	}
+
+	anchored, err := regexp.Compile(expr)
+	if err != nil {
+		return nil
+	}
+
+	return anchored.FindStringSubmatchIndex(s)
+}
+
+// A match object, or none if there was no match
+func newMatchValue(re *regexp.Regexp, s string, match []int) *compilerInterface.Value {
+	if match == nil {
+		return compilerInterface.NewNull()
+	}
+
+	groups := make([]*compilerInterface.Value, len(match)/2)
+	for i := range groups {
+		if match[i*2] < 0 {
+			groups[i] = compilerInterface.NewNull()
+		} else {
+			groups[i] = compilerInterface.NewString(s[match[i*2]:match[i*2+1]])
+		}
+	}
+
+	named := make(map[string]*compilerInterface.Value)
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			named[name] = groups[i]
+		}
+	}
+
+	groupIndex := func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, value *compilerInterface.Value) (int, error) {
+		if value.Type() == compilerInterface.StringVal {
+			if index := re.SubexpIndex(value.StringValue); index >= 0 {
+				return index, nil
+			}
+		} else if index := int(value.NumberValue); index >= 0 && index < len(groups) {
+			return index, nil
+		}
+
+		return 0, ec.ErrorAt(caller, fmt.Sprintf("no such group: %s", value.AsStringValue()))
+	}
+
+	return compilerInterface.NewStringWithProperties(groups[0].StringValue, map[string]*compilerInterface.Value{
+		"string": compilerInterface.NewString(s),
+
+		"group": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			if len(args) == 0 {
+				return groups[0], nil
+			}
+
+			results := make([]*compilerInterface.Value, len(args))
+			for i, arg := range args {
+				index, err := groupIndex(ec, caller, arg.Value.Unwrap())
+				if err != nil {
+					return nil, err
+				}
+
+				results[i] = groups[index]
+			}
+
+			if len(results) == 1 {
+				return results[0], nil
+			}
+
+			return compilerInterface.NewList(results), nil
+		}),
+
+		"groups": compilerInterface.NewFunction(func(_ compilerInterface.ExecutionContext, _ compilerInterface.AST, _ compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			return compilerInterface.NewList(groups[1:]), nil
+		}),
+
+		"groupdict": compilerInterface.NewFunction(func(_ compilerInterface.ExecutionContext, _ compilerInterface.AST, _ compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			return compilerInterface.NewMap(named), nil
+		}),
+
+		"start": numberMethod(float64(match[0])),
+		"end":   numberMethod(float64(match[1])),
+		"span": compilerInterface.NewFunction(func(_ compilerInterface.ExecutionContext, _ compilerInterface.AST, _ compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			return compilerInterface.NewList([]*compilerInterface.Value{
+				compilerInterface.NewNumber(float64(match[0])),
+				compilerInterface.NewNumber(float64(match[1])),
+			}), nil
+		}),
+	})
+}
+
+var pythonGroupReference = regexp.MustCompile(`\\(?:g<(\w+)>|(\d+))`)
+
+// Converts a Python replacement string, which references groups as `\1` or `\g<name>`, into Go's `${1}` form
+func pythonReplacement(repl string) string {
+	repl = strings.ReplaceAll(repl, "$", "$$")
+
+	return pythonGroupReference.ReplaceAllStringFunc(repl, func(reference string) string {
+		match := pythonGroupReference.FindStringSubmatch(reference)
+		if match[1] != "" {
+			return "${" + match[1] + "}"
+		}
+
+		return "${" + match[2] + "}"
+	})
+}
@@ -0,0 +1,294 @@
+package compiler
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"gopkg.in/yaml.v2"
+
+	"ddbt/compiler/dbtUtils"
+	"ddbt/compilerInterface"
+)
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/tojson
+func toJSONFunction(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("value"), dbtUtils.Param("sort_keys"), dbtUtils.Param("indent"))
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	var builder strings.Builder
+	if err := writeJSON(&builder, arguments[0]); err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	// Like Python, the indent can either be a number of spaces or the string to indent with
+	indentation := ""
+	switch indent := arguments[2].Unwrap(); indent.Type() {
+	case compilerInterface.NumberVal:
+		if indent.NumberValue > 0 {
+			indentation = strings.Repeat(" ", int(indent.NumberValue))
+		}
+	case compilerInterface.StringVal:
+		indentation = indent.StringValue
+	}
+
+	if indentation == "" {
+		return compilerInterface.NewString(builder.String()), nil
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, []byte(builder.String()), "", indentation); err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("unable to indent JSON: %s", err))
+	}
+
+	return compilerInterface.NewString(indented.String()), nil
+}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/fromjson
+func fromJSONFunction(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("string"), dbtUtils.Param("default"))
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(arguments[0].AsStringValue()), &parsed); err != nil {
+		return arguments[1], nil
+	}
+
+	value, err := compilerInterface.NewValueFromInterface(parsed)
+	if err != nil {
+		return arguments[1], nil
+	}
+
+	return value, nil
+}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/toyaml
+func toYAMLFunction(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("value"), dbtUtils.Param("sort_keys"))
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	bytes, err := yaml.Marshal(nativeValue(arguments[0]))
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("Unable to convert to YAML: %s", err))
+	}
+
+	return compilerInterface.NewString(string(bytes)), nil
+}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/fromyaml
+func fromYAMLFunction(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("string"), dbtUtils.Param("default"))
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	var parsed interface{}
+	if err := yaml.Unmarshal([]byte(arguments[0].AsStringValue()), &parsed); err != nil {
+		return arguments[1], nil
+	}
+
+	value, err := compilerInterface.NewValueFromInterface(parsed)
+	if err != nil {
+		return arguments[1], nil
+	}
+
+	return value, nil
+}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/as_native
+//
+// Strings are read as Python literals, such as `[1, 2]`, `{'a': True}` or `None`; anything else is returned as it is
+func asNativeFunction(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	if err := expectArgs(ec, caller, "as_native", 1, args); err != nil {
+		return nil, err
+	}
+
+	value := args[0].Value.Unwrap()
+	if value.Type() != compilerInterface.StringVal || value.MapValue != nil {
+		return value, nil
+	}
+
+	native, err := parsePythonLiteral(value.StringValue)
+	if err != nil {
+		return value, nil
+	}
+
+	return native, nil
+}
+
+// Converts a value into the Go types which encode to the same YAML
+func nativeValue(value *compilerInterface.Value) interface{} {
+	value = value.Unwrap()
+
+	switch value.Type() {
+	case compilerInterface.BooleanValue:
+		return value.BooleanValue
+
+	case compilerInterface.NumberVal:
+		if isWholeNumber(value.NumberValue) {
+			return int64(value.NumberValue)
+		}
+		return value.NumberValue
+
+	case compilerInterface.StringVal:
+		return value.StringValue
+
+	case compilerInterface.ListVal:
+		list := make([]interface{}, len(value.ListValue))
+		for i, item := range value.ListValue {
+			list[i] = nativeValue(item)
+		}
+		return list
+
+	case compilerInterface.MapVal:
+		m := make(map[string]interface{}, len(value.MapValue))
+		for key, item := range value.MapValue {
+			m[key] = nativeValue(item)
+		}
+		return m
+
+	default:
+		return nil
+	}
+}
+
+// Writes a value as JSON in the same format as Python's json.dumps, so `{"a": [1, 2]}` rather than `{"a":[1,2]}`.
+// As our maps are unordered, keys are always sorted
+func writeJSON(builder *strings.Builder, value *compilerInterface.Value) error {
+	value = value.Unwrap()
+
+	switch value.Type() {
+	case compilerInterface.BooleanValue:
+		if value.BooleanValue {
+			builder.WriteString("true")
+		} else {
+			builder.WriteString("false")
+		}
+
+	case compilerInterface.NumberVal:
+		switch {
+		case math.IsNaN(value.NumberValue):
+			builder.WriteString("NaN")
+		case math.IsInf(value.NumberValue, 0):
+			if value.NumberValue < 0 {
+				builder.WriteString("-")
+			}
+			builder.WriteString("Infinity")
+		case isWholeNumber(value.NumberValue):
+			builder.WriteString(strconv.FormatInt(int64(value.NumberValue), 10))
+		default:
+			builder.WriteString(strconv.FormatFloat(value.NumberValue, 'g', -1, 64))
+		}
+
+	case compilerInterface.StringVal:
+		writeJSONString(builder, value.StringValue)
+
+	case compilerInterface.ListVal:
+		builder.WriteString("[")
+		for i, item := range value.ListValue {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+
+			if err := writeJSON(builder, item); err != nil {
+				return err
+			}
+		}
+		builder.WriteString("]")
+
+	case compilerInterface.MapVal:
+		keys := make([]string, 0, len(value.MapValue))
+		for key := range value.MapValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		builder.WriteString("{")
+		for i, key := range keys {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+
+			writeJSONString(builder, key)
+			builder.WriteString(": ")
+
+			if err := writeJSON(builder, value.MapValue[key]); err != nil {
+				return err
+			}
+		}
+		builder.WriteString("}")
+
+	case compilerInterface.NullVal, compilerInterface.Undefined:
+		builder.WriteString("null")
+
+	default:
+		return fmt.Errorf("Object of type %s is not JSON serializable", value.Type())
+	}
+
+	return nil
+}
+
+// Writes a JSON string escaped in the same way as Python, which escapes everything outside of ASCII
+func writeJSONString(builder *strings.Builder, s string) {
+	builder.WriteByte('"')
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			builder.WriteString(`\"`)
+		case r == '\\':
+			builder.WriteString(`\\`)
+		case r == '\n':
+			builder.WriteString(`\n`)
+		case r == '\r':
+			builder.WriteString(`\r`)
+		case r == '\t':
+			builder.WriteString(`\t`)
+		case r == '\b':
+			builder.WriteString(`\b`)
+		case r == '\f':
+			builder.WriteString(`\f`)
+		case r < 0x20 || (r > 0x7e && r <= 0xffff):
+			builder.WriteString(fmt.Sprintf(`\u%04x`, r))
+		case r > 0xffff:
+			high, low := utf16.EncodeRune(r)
+			builder.WriteString(fmt.Sprintf(`\u%04x\u%04x`, high, low))
+		default:
+			builder.WriteRune(r)
+		}
+	}
+
+	builder.WriteByte('"')
+}
+
+func isWholeNumber(f float64) bool {
+	return f == math.Trunc(f) && math.Abs(f) < 1e15
+}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/invocation_id
+var invocationID = newUUID()
+
+// A random (version 4) UUID
+func newUUID() string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		panic(fmt.Sprintf("Unable to generate a UUID: %s", err))
+	}
+
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
@@ -0,0 +1,88 @@
+package compiler
+
+import (
+	"fmt"
+
+	"ddbt/compiler/dbtUtils"
+	"ddbt/compilerInterface"
+	"ddbt/config"
+)
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/var
+//
+// `var` is a function which also has a `has_var` method, so it is registered as a value rather than a function
+func newVarValue() *compilerInterface.Value {
+	value := compilerInterface.NewFunction(varFunction)
+	value.MapValue = map[string]*compilerInterface.Value{
+		"has_var": compilerInterface.NewFunction(hasVarFunction),
+	}
+
+	return value
+}
+
+func varFunction(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	if len(args) < 1 {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("var requires at least 1 parameter, got %d", len(args)))
+	}
+
+	arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("name"), dbtUtils.Param("default"))
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	name := arguments[0].AsStringValue()
+
+	value, found, err := lookupVar(ec, name)
+	if err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	if found {
+		return value, nil
+	}
+
+	// A default of `none` can't be told apart from no default being given, other than by the number of arguments
+	if len(args) > 1 {
+		return arguments[1], nil
+	}
+
+	return nil, ec.ErrorAt(caller, fmt.Sprintf("Required var `%s` not found; define it in `vars` in dbt_project.yml or pass it with `--vars`", name))
+}
+
+func hasVarFunction(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	values, err := requiredArgs(ec, caller, args, "var.has_var", compilerInterface.StringVal)
+	if err != nil {
+		return nil, err
+	}
+
+	_, found, err := lookupVar(ec, values[0].StringValue)
+	if err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	return compilerInterface.NewBoolean(found), nil
+}
+
+// Looks up a var, using the vars scoped to the folder of the file being compiled
+func lookupVar(ec compilerInterface.ExecutionContext, name string) (*compilerInterface.Value, bool, error) {
+	if config.GlobalCfg == nil {
+		return nil, false, nil
+	}
+
+	var folderVars map[string]interface{}
+	if e, ok := ec.(*ExecutionContext); ok {
+		folderVars = e.file.FolderConfig.Vars
+	}
+
+	value, found := config.GlobalCfg.LookupVar(name, folderVars)
+	if !found {
+		return nil, false, nil
+	}
+
+	converted, err := compilerInterface.NewValueFromInterface(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("Unable to read var `%s`: %s", name, err)
+	}
+
+	return converted, true, nil
+}
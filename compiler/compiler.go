@@ -27,18 +27,32 @@ func ParseFile(file *fs.File) error {
 }
 
 func CompileModel(file *fs.File, gc *GlobalContext, isExecuting bool) error {
+	return CompileModelWithRefOverrides(file, gc, isExecuting, nil)
+}
+
+// Compiles the model with any `ref` to another model replaced with the given override,
+// if overrides are given then every model referenced must have an override
+func CompileModelWithRefOverrides(file *fs.File, gc *GlobalContext, isExecuting bool, refOverrides map[string]string) error {
+	return compileModel(file, gc, isExecuting, refOverrides, false)
+}
+
+// Compiles the model with any `ref` to the given models replaced, refs to any other model are resolved as normal
+func CompileModelWithRefReplacements(file *fs.File, gc *GlobalContext, isExecuting bool, replacements map[string]string) error {
+	return compileModel(file, gc, isExecuting, replacements, true)
+}
+
+func compileModel(file *fs.File, gc *GlobalContext, isExecuting bool, refOverrides map[string]string, partialRefOverrides bool) error {
 	ec := NewExecutionContext(file, gc.fileSystem, isExecuting, gc, gc)
+	ec.refOverrides = refOverrides
+	ec.partialRefOverrides = partialRefOverrides
+	ec.packageName = file.Package
 
 	target, err := file.GetTarget()
 	if err != nil {
 		return err
 	}
 
-	ec.SetVariable("this", compilerInterface.NewMap(map[string]*compilerInterface.Value{
-		"schema": compilerInterface.NewString(target.DataSet),
-		"table":  compilerInterface.NewString(file.Name),
-		"name":   compilerInterface.NewString(file.Name),
-	}))
+	ec.SetVariable("this", newRelation(target.ProjectID, target.DataSet, file.Name, relationTypeFor(file.GetMaterialization())).value())
 
 	ec.SetVariable("target", compilerInterface.NewMap(map[string]*compilerInterface.Value{
 		"name":    compilerInterface.NewString(config.GlobalCfg.Target.Name),
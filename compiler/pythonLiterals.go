@@ -0,0 +1,377 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"ddbt/compilerInterface"
+)
+
+// Reads a Python literal in the same way as Python's `ast.literal_eval`; None, booleans, numbers, strings, lists,
+// tuples, dicts and sets are supported. An error is returned if the string isn't a single Python literal
+func parsePythonLiteral(s string) (*compilerInterface.Value, error) {
+	p := &pythonLiteralParser{input: s}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipWhitespace()
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("unexpected `%s` after the literal", p.input[p.pos:])
+	}
+
+	return value, nil
+}
+
+type pythonLiteralParser struct {
+	input string
+	pos   int
+}
+
+func (p *pythonLiteralParser) skipWhitespace() {
+	for p.pos < len(p.input) && strings.IndexByte(" \t\r\n", p.input[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// Consumes the given character (after any whitespace) if it's next
+func (p *pythonLiteralParser) consume(c byte) bool {
+	p.skipWhitespace()
+
+	if p.pos < len(p.input) && p.input[p.pos] == c {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *pythonLiteralParser) parseValue() (*compilerInterface.Value, error) {
+	p.skipWhitespace()
+	if p.pos >= len(p.input) {
+		return nil, errors.New("unexpected end of literal")
+	}
+
+	switch c := p.input[p.pos]; {
+	case c == '[':
+		p.pos++
+		items, _, err := p.parseItems(']')
+		if err != nil {
+			return nil, err
+		}
+		return compilerInterface.NewList(items), nil
+
+	case c == '(':
+		p.pos++
+		items, trailingComma, err := p.parseItems(')')
+		if err != nil {
+			return nil, err
+		}
+
+		// `(1)` is just a bracketed value, where as `(1,)` is a tuple
+		if len(items) == 1 && !trailingComma {
+			return items[0], nil
+		}
+		return compilerInterface.NewTuple(items...), nil
+
+	case c == '{':
+		p.pos++
+		return p.parseDictOrSet()
+
+	case c == '\'' || c == '"':
+		return p.parseStrings()
+
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+
+	default:
+		return p.parseName()
+	}
+}
+
+// Parses comma separated values up to the closing bracket, returning if the last value had a trailing comma
+func (p *pythonLiteralParser) parseItems(closing byte) ([]*compilerInterface.Value, bool, error) {
+	items := make([]*compilerInterface.Value, 0)
+	trailingComma := false
+
+	for !p.consume(closing) {
+		if len(items) > 0 && !trailingComma {
+			return nil, false, fmt.Errorf("expected `,` or `%c`", closing)
+		}
+
+		item, err := p.parseValue()
+		if err != nil {
+			return nil, false, err
+		}
+
+		items = append(items, item)
+		trailingComma = p.consume(',')
+	}
+
+	return items, trailingComma, nil
+}
+
+func (p *pythonLiteralParser) parseDictOrSet() (*compilerInterface.Value, error) {
+	if p.consume('}') {
+		return compilerInterface.NewMap(make(map[string]*compilerInterface.Value)), nil
+	}
+
+	first, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	// Sets don't have a type of their own, so are read as a list
+	if !p.consume(':') {
+		items := []*compilerInterface.Value{first}
+		if p.consume(',') {
+			rest, _, err := p.parseItems('}')
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, rest...)
+		} else if !p.consume('}') {
+			return nil, errors.New("expected `,` or `}`")
+		}
+
+		return compilerInterface.NewList(items), nil
+	}
+
+	dict := make(map[string]*compilerInterface.Value)
+	key := first
+
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		dict[key.AsStringValue()] = value
+
+		if !p.consume(',') {
+			if !p.consume('}') {
+				return nil, errors.New("expected `,` or `}`")
+			}
+			break
+		}
+
+		if p.consume('}') {
+			break
+		}
+
+		key, err = p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.consume(':') {
+			return nil, errors.New("expected `:`")
+		}
+	}
+
+	return compilerInterface.NewMap(dict), nil
+}
+
+// Parses one or more adjacent strings, which Python joins together
+func (p *pythonLiteralParser) parseStrings() (*compilerInterface.Value, error) {
+	var builder strings.Builder
+
+	for {
+		if err := p.parseString(&builder); err != nil {
+			return nil, err
+		}
+
+		p.skipWhitespace()
+		if p.pos >= len(p.input) || (p.input[p.pos] != '\'' && p.input[p.pos] != '"') {
+			return compilerInterface.NewString(builder.String()), nil
+		}
+	}
+}
+
+func (p *pythonLiteralParser) parseString(builder *strings.Builder) error {
+	quote := p.input[p.pos]
+	p.pos++
+
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+
+		switch {
+		case c == quote:
+			p.pos++
+			return nil
+
+		case c == '\n':
+			return errors.New("unterminated string")
+
+		case c == '\\':
+			if err := p.parseEscape(builder); err != nil {
+				return err
+			}
+
+		default:
+			r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+			builder.WriteRune(r)
+			p.pos += size
+		}
+	}
+
+	return errors.New("unterminated string")
+}
+
+func (p *pythonLiteralParser) parseEscape(builder *strings.Builder) error {
+	p.pos++ // consume the "\"
+	if p.pos >= len(p.input) {
+		return errors.New("unterminated string")
+	}
+
+	c := p.input[p.pos]
+	p.pos++
+
+	simple := map[byte]string{
+		'\\': `\`, '\'': `'`, '"': `"`, 'n': "\n", 't': "\t", 'r': "\r", 'a': "\a", 'b': "\b", 'f': "\f", 'v': "\v", '\n': "",
+	}
+	if escaped, found := simple[c]; found {
+		builder.WriteString(escaped)
+		return nil
+	}
+
+	digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}
+	if length, found := digits[c]; found {
+		if p.pos+length > len(p.input) {
+			return fmt.Errorf("truncated \\%c escape", c)
+		}
+
+		code, err := strconv.ParseUint(p.input[p.pos:p.pos+length], 16, 32)
+		if err != nil {
+			return fmt.Errorf("invalid \\%c escape", c)
+		}
+
+		builder.WriteRune(rune(code))
+		p.pos += length
+		return nil
+	}
+
+	// Unknown escapes are kept as they are
+	builder.WriteByte('\\')
+	builder.WriteByte(c)
+	return nil
+}
+
+func (p *pythonLiteralParser) parseNumber() (*compilerInterface.Value, error) {
+	start := p.pos
+
+	sign := 1.0
+	if c := p.input[p.pos]; c == '-' || c == '+' {
+		if c == '-' {
+			sign = -1
+		}
+		p.pos++
+		p.skipWhitespace()
+	}
+
+	numberStart := p.pos
+	for p.pos < len(p.input) && isPythonNumberChar(p.input, p.pos) {
+		p.pos++
+	}
+
+	number := p.input[numberStart:p.pos]
+	if number == "" {
+		return nil, fmt.Errorf("invalid number `%s`", p.input[start:p.pos])
+	}
+
+	value, err := parsePythonNumber(number)
+	if err != nil {
+		return nil, err
+	}
+
+	return compilerInterface.NewNumber(sign * value), nil
+}
+
+// Is the character part of a number, including the sign of an exponent such as `1e-3`
+func isPythonNumberChar(s string, i int) bool {
+	c := s[i]
+
+	switch {
+	case (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '.' || c == '_':
+		return true
+
+	case (c == '-' || c == '+') && i > 0 && (s[i-1] == 'e' || s[i-1] == 'E'):
+		// Hex digits can be an `e` too, which is never followed by a sign in a valid number
+		return true
+
+	default:
+		return false
+	}
+}
+
+// Parses a number in Python's syntax, where `010` isn't allowed and `1_000` is 1000
+func parsePythonNumber(number string) (float64, error) {
+	invalid := fmt.Errorf("invalid number `%s`", number)
+
+	// Underscores can only be between digits
+	if strings.HasPrefix(number, "_") || strings.HasSuffix(number, "_") || strings.Contains(number, "__") {
+		return 0, invalid
+	}
+
+	lower := strings.ToLower(number)
+	if len(lower) > 2 && lower[0] == '0' && strings.IndexByte("xob", lower[1]) >= 0 {
+		digits := strings.TrimPrefix(lower[2:], "_")
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[lower[1]]
+
+		value, err := strconv.ParseInt(strings.Replace(digits, "_", "", -1), base, 64)
+		if err != nil {
+			return 0, invalid
+		}
+		return float64(value), nil
+	}
+
+	digits := strings.Replace(lower, "_", "", -1)
+
+	// Only floats can have digits after a leading zero, such as `0.5` or `00.5`
+	isFloat := strings.ContainsAny(digits, ".e")
+	if !isFloat && len(digits) > 1 && digits[0] == '0' && strings.Trim(digits, "0") != "" {
+		return 0, invalid
+	}
+
+	for _, c := range digits {
+		if (c < '0' || c > '9') && c != '.' && c != 'e' && c != '-' && c != '+' {
+			return 0, invalid
+		}
+	}
+
+	value, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return 0, invalid
+	}
+
+	return value, nil
+}
+
+// Parses the names which are literals; None, True and False
+func (p *pythonLiteralParser) parseName() (*compilerInterface.Value, error) {
+	start := p.pos
+	for p.pos < len(p.input) && isPythonNameChar(p.input[p.pos]) {
+		p.pos++
+	}
+
+	switch name := p.input[start:p.pos]; name {
+	case "None":
+		return compilerInterface.NewNull(), nil
+	case "True":
+		return compilerInterface.NewBoolean(true), nil
+	case "False":
+		return compilerInterface.NewBoolean(false), nil
+	case "":
+		return nil, fmt.Errorf("unexpected `%c`", p.input[start])
+	default:
+		return nil, fmt.Errorf("`%s` is not a literal", name)
+	}
+}
+
+func isPythonNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
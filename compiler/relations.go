@@ -0,0 +1,446 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ddbt/bigquery"
+	"ddbt/compiler/dbtUtils"
+	"ddbt/compilerInterface"
+	"ddbt/config"
+)
+
+// https://docs.getdbt.com/reference/dbt-classes#relation
+type relation struct {
+	database     string
+	schema       string
+	identifier   string
+	relationType string
+
+	// Which parts of the name are rendered
+	includeDatabase   bool
+	includeSchema     bool
+	includeIdentifier bool
+}
+
+func newRelation(database, schema, identifier, relationType string) relation {
+	return relation{
+		database:          database,
+		schema:            schema,
+		identifier:        identifier,
+		relationType:      relationType,
+		includeDatabase:   true,
+		includeSchema:     true,
+		includeIdentifier: true,
+	}
+}
+
+// The relation type of a model with the given materialization
+func relationTypeFor(materialization string) string {
+	switch materialization {
+	case "view", "materialized_view":
+		return materialization
+	default:
+		return "table"
+	}
+}
+
+func (r relation) render() string {
+	parts := make([]string, 0, 3)
+
+	if r.includeDatabase && r.database != "" {
+		parts = append(parts, "`"+r.database+"`")
+	}
+	if r.includeSchema && r.schema != "" {
+		parts = append(parts, "`"+r.schema+"`")
+	}
+	if r.includeIdentifier && r.identifier != "" {
+		parts = append(parts, "`"+r.identifier+"`")
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// The relation as a value, which renders as its fully qualified name
+func (r relation) value() *compilerInterface.Value {
+	self := compilerInterface.NewStringWithProperties(r.render(), nil)
+
+	self.MapValue = map[string]*compilerInterface.Value{
+		"database":   compilerInterface.NewString(r.database),
+		"project":    compilerInterface.NewString(r.database),
+		"schema":     compilerInterface.NewString(r.schema),
+		"dataset":    compilerInterface.NewString(r.schema),
+		"identifier": compilerInterface.NewString(r.identifier),
+		"name":       compilerInterface.NewString(r.identifier),
+		"table":      compilerInterface.NewString(r.identifier),
+		"type":       compilerInterface.NewString(r.relationType),
+		"is_table":   compilerInterface.NewBoolean(r.relationType == "table"),
+		"is_view":    compilerInterface.NewBoolean(r.relationType == "view"),
+		"is_cte":     compilerInterface.NewBoolean(false),
+
+		"render": compilerInterface.NewFunction(func(_ compilerInterface.ExecutionContext, _ compilerInterface.AST, _ compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			return compilerInterface.NewString(r.render()), nil
+		}),
+
+		"quote": compilerInterface.NewFunction(func(_ compilerInterface.ExecutionContext, _ compilerInterface.AST, _ compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			return self, nil
+		}),
+
+		"include": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			arguments, err := dbtUtils.GetArgs(
+				args,
+				dbtUtils.ParamWithDefault("database", compilerInterface.NewBoolean(r.includeDatabase)),
+				dbtUtils.ParamWithDefault("schema", compilerInterface.NewBoolean(r.includeSchema)),
+				dbtUtils.ParamWithDefault("identifier", compilerInterface.NewBoolean(r.includeIdentifier)),
+			)
+			if err != nil {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+			}
+
+			included := r
+			included.includeDatabase = arguments[0].TruthyValue()
+			included.includeSchema = arguments[1].TruthyValue()
+			included.includeIdentifier = arguments[2].TruthyValue()
+
+			return included.value(), nil
+		}),
+
+		"incorporate": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("path"), dbtUtils.Param("type"))
+			if err != nil {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+			}
+
+			incorporated := r
+			if path := arguments[0]; path.Type() == compilerInterface.MapVal {
+				if value, found := path.MapValue["database"]; found {
+					incorporated.database = value.AsStringValue()
+				}
+				if value, found := path.MapValue["schema"]; found {
+					incorporated.schema = value.AsStringValue()
+				}
+				if value, found := path.MapValue["identifier"]; found {
+					incorporated.identifier = value.AsStringValue()
+				}
+			}
+			if relationType := arguments[1]; relationType.Type() == compilerInterface.StringVal {
+				incorporated.relationType = relationType.StringValue
+			}
+
+			return incorporated.value(), nil
+		}),
+	}
+
+	return self
+}
+
+// Reads a relation passed to a function, which can either be a relation value or the name of a table
+func relationFromValue(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, value *compilerInterface.Value) (relation, error) {
+	value = value.Unwrap()
+
+	if value.Type() != compilerInterface.StringVal {
+		return relation{}, ec.ErrorAt(caller, fmt.Sprintf("expected a relation, got %s", value.Type()))
+	}
+
+	if value.MapValue != nil {
+		r := newRelation(
+			value.MapValue["database"].AsStringValue(),
+			value.MapValue["schema"].AsStringValue(),
+			value.MapValue["identifier"].AsStringValue(),
+			value.MapValue["type"].AsStringValue(),
+		)
+
+		if r.identifier != "" {
+			return r, nil
+		}
+	}
+
+	target, err := ec.GetTarget()
+	if err != nil {
+		return relation{}, ec.ErrorAt(caller, fmt.Sprintf("Unable to get the target: %s", err))
+	}
+
+	parts := strings.Split(strings.ReplaceAll(value.StringValue, "`", ""), ".")
+	switch len(parts) {
+	case 3:
+		return newRelation(parts[0], parts[1], parts[2], ""), nil
+	case 2:
+		return newRelation(target.ProjectID, parts[0], parts[1], ""), nil
+	case 1:
+		return newRelation(target.ProjectID, target.DataSet, parts[0], ""), nil
+	default:
+		return relation{}, ec.ErrorAt(caller, fmt.Sprintf("`%s` is not a valid relation", value.StringValue))
+	}
+}
+
+// The target for the dataset of a relation, so BigQuery calls are made in the same way as for the model
+func relationTarget(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, r relation) (*config.Target, error) {
+	target, err := ec.GetTarget()
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("Unable to get the target: %s", err))
+	}
+
+	target = target.Copy()
+	target.ProjectID = r.database
+	target.DataSet = r.schema
+
+	return target, nil
+}
+
+// Reads the relation arguments of an adapter method and the target for each, which can only be used once we're
+// executing the model
+func relationArgs(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments, names ...string) ([]relation, []*config.Target, error) {
+	params := make([]compilerInterface.Argument, len(names))
+	for i, name := range names {
+		params[i] = dbtUtils.Param(name)
+	}
+
+	arguments, err := dbtUtils.GetArgs(args, params...)
+	if err != nil {
+		return nil, nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	relations := make([]relation, len(names))
+	targets := make([]*config.Target, len(names))
+	for i, argument := range arguments {
+		if relations[i], err = relationFromValue(ec, caller, argument); err != nil {
+			return nil, nil, err
+		}
+
+		if targets[i], err = relationTarget(ec, caller, relations[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return relations, targets, nil
+}
+
+// The columns of a table, as returned by get_columns_in_relation
+func columnsValue(schema bigquery.Schema) *compilerInterface.Value {
+	columns := make([]*compilerInterface.Value, 0, len(schema))
+
+	for _, column := range schema {
+		columns = append(columns, compilerInterface.NewMap(map[string]*compilerInterface.Value{
+			"name":      compilerInterface.NewString(column.Name),
+			"column":    compilerInterface.NewString(column.Name),
+			"data_type": compilerInterface.NewString(string(column.Type)),
+		}))
+	}
+
+	return compilerInterface.NewList(columns)
+}
+
+// Looks up the table behind a relation, returning nil if it doesn't exist
+func relationColumns(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, r relation, target *config.Target) (bigquery.Schema, bool, error) {
+	metadata, err := bigquery.GetTableMetadata(context.Background(), target, r.identifier)
+	if err != nil {
+		return nil, false, ec.ErrorAt(caller, err.Error())
+	}
+
+	if metadata == nil {
+		return nil, false, nil
+	}
+
+	return metadata.Schema, true, nil
+}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/adapter#get_relation
+func adapterGetRelation(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("database"), dbtUtils.Param("schema"), dbtUtils.Param("identifier"))
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	// Whether the relation exists can only be known once we're executing the model
+	if isOnlyCompilingSQL(ec) {
+		return ec.MarkAsDynamicSQL()
+	}
+
+	r := newRelation(arguments[0].AsStringValue(), arguments[1].AsStringValue(), arguments[2].AsStringValue(), "")
+
+	target, err := relationTarget(ec, caller, r)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := bigquery.GetTableMetadata(context.Background(), target, r.identifier)
+	if err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	if metadata == nil {
+		return compilerInterface.NewUndefined(), nil
+	}
+
+	r.relationType = bigquery.RelationType(metadata)
+	return r.value(), nil
+}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/adapter#drop_relation
+func adapterDropRelation(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	relations, targets, err := relationArgs(ec, caller, args, "relation")
+	if err != nil {
+		return nil, err
+	}
+
+	if isOnlyCompilingSQL(ec) {
+		return ec.MarkAsDynamicSQL()
+	}
+
+	if err := bigquery.DropTable(context.Background(), targets[0], relations[0].identifier); err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	return compilerInterface.NewUndefined(), nil
+}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/adapter#rename_relation
+func adapterRenameRelation(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	relations, targets, err := relationArgs(ec, caller, args, "from_relation", "to_relation")
+	if err != nil {
+		return nil, err
+	}
+
+	if isOnlyCompilingSQL(ec) {
+		return ec.MarkAsDynamicSQL()
+	}
+
+	if err := bigquery.RenameTable(context.Background(), targets[0], relations[0].identifier, targets[1], relations[1].identifier); err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	return compilerInterface.NewUndefined(), nil
+}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/adapter#get_missing_columns
+func adapterGetMissingColumns(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	relations, targets, err := relationArgs(ec, caller, args, "from_relation", "to_relation")
+	if err != nil {
+		return nil, err
+	}
+
+	if isOnlyCompilingSQL(ec) {
+		return ec.MarkAsDynamicSQL()
+	}
+
+	fromColumns, _, err := relationColumns(ec, caller, relations[0], targets[0])
+	if err != nil {
+		return nil, err
+	}
+	toColumns, _, err := relationColumns(ec, caller, relations[1], targets[1])
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]struct{}, len(toColumns))
+	for _, column := range toColumns {
+		existing[strings.ToLower(column.Name)] = struct{}{}
+	}
+
+	missing := make(bigquery.Schema, 0)
+	for _, column := range fromColumns {
+		if _, found := existing[strings.ToLower(column.Name)]; !found {
+			missing = append(missing, column)
+		}
+	}
+
+	return columnsValue(missing), nil
+}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/adapter#expand_target_column_types
+func adapterExpandTargetColumnTypes(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	relations, targets, err := relationArgs(ec, caller, args, "from_relation", "to_relation")
+	if err != nil {
+		return nil, err
+	}
+
+	if isOnlyCompilingSQL(ec) {
+		return ec.MarkAsDynamicSQL()
+	}
+
+	if err := bigquery.ExpandColumnTypes(context.Background(), targets[0], relations[0].identifier, targets[1], relations[1].identifier); err != nil {
+		return nil, ec.ErrorAt(caller, err.Error())
+	}
+
+	return compilerInterface.NewUndefined(), nil
+}
+
+// https://docs.getdbt.com/reference/dbt-jinja-functions/adapter#get_columns_in_table
+func adapterGetColumnsInTable(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("schema"), dbtUtils.Param("identifier"))
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	if isOnlyCompilingSQL(ec) {
+		return ec.MarkAsDynamicSQL()
+	}
+
+	target, err := ec.GetTarget()
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("Unable to get the target: %s", err))
+	}
+
+	r := newRelation(target.ProjectID, arguments[0].AsStringValue(), arguments[1].AsStringValue(), "")
+	if target, err = relationTarget(ec, caller, r); err != nil {
+		return nil, err
+	}
+
+	columns, _, err := relationColumns(ec, caller, r, target)
+	if err != nil {
+		return nil, err
+	}
+
+	return columnsValue(columns), nil
+}
+
+// Not listed in dbt's docs, but used by older macros: already_exists(schema, identifier)
+func adapterAlreadyExists(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	arguments, err := dbtUtils.GetArgs(args, dbtUtils.Param("schema"), dbtUtils.Param("identifier"))
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	if isOnlyCompilingSQL(ec) {
+		return ec.MarkAsDynamicSQL()
+	}
+
+	target, err := ec.GetTarget()
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("Unable to get the target: %s", err))
+	}
+
+	r := newRelation(target.ProjectID, arguments[0].AsStringValue(), arguments[1].AsStringValue(), "")
+	if target, err = relationTarget(ec, caller, r); err != nil {
+		return nil, err
+	}
+
+	_, exists, err := relationColumns(ec, caller, r, target)
+	if err != nil {
+		return nil, err
+	}
+
+	return compilerInterface.NewBoolean(exists), nil
+}
+
+// https://docs.getdbt.com/reference/dbt-classes#creating-relations
+func apiRelationCreate(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	arguments, err := dbtUtils.GetArgs(
+		args,
+		dbtUtils.Param("database"),
+		dbtUtils.Param("schema"),
+		dbtUtils.Param("identifier"),
+		dbtUtils.ParamWithDefault("type", compilerInterface.NewString("table")),
+	)
+	if err != nil {
+		return nil, ec.ErrorAt(caller, fmt.Sprintf("%s", err))
+	}
+
+	return newRelation(
+		arguments[0].AsStringValue(),
+		arguments[1].AsStringValue(),
+		arguments[2].AsStringValue(),
+		arguments[3].AsStringValue(),
+	).value(), nil
+}
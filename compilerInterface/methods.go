@@ -0,0 +1,638 @@
+package compilerInterface
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The methods of Python's str, list and dict which macros call, such as `"a,b".split(",")` or `my_list.append(1)`.
+// Methods which mutate a list or map do so in place, so every reference to it sees the change like in Python
+
+func stringMethods(v *Value) map[string]*Value {
+	s := v.StringValue
+
+	return map[string]*Value{
+		"upper": NewFunction(func(_ ExecutionContext, _ AST, _ Arguments) (*Value, error) {
+			return NewString(strings.ToUpper(s)), nil
+		}),
+
+		"lower": NewFunction(func(_ ExecutionContext, _ AST, _ Arguments) (*Value, error) {
+			return NewString(strings.ToLower(s)), nil
+		}),
+
+		"split": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			params, err := methodArgs(ec, caller, "split", args, 0, "sep", "maxsplit")
+			if err != nil {
+				return nil, err
+			}
+
+			maxSplit := -1
+			if !params[1].IsUndefined {
+				maxSplit = int(params[1].NumberValue)
+			}
+
+			if params[0].IsUndefined || params[0].IsNull {
+				return NewStringList(splitWhitespace(s, maxSplit)), nil
+			}
+
+			sep := params[0].AsStringValue()
+			if sep == "" {
+				return nil, ec.ErrorAt(caller, "empty separator")
+			}
+
+			n := -1
+			if maxSplit >= 0 {
+				n = maxSplit + 1
+			}
+
+			return NewStringList(strings.SplitN(s, sep, n)), nil
+		}),
+
+		"strip": stripMethod(s, "strip", strings.TrimSpace, strings.Trim),
+
+		"lstrip": stripMethod(s, "lstrip", func(s string) string { return strings.TrimLeftFunc(s, unicode.IsSpace) }, strings.TrimLeft),
+
+		"rstrip": stripMethod(s, "rstrip", func(s string) string { return strings.TrimRightFunc(s, unicode.IsSpace) }, strings.TrimRight),
+
+		"startswith": affixMethod(s, "startswith", strings.HasPrefix),
+
+		"endswith": affixMethod(s, "endswith", strings.HasSuffix),
+
+		"replace": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			params, err := methodArgs(ec, caller, "replace", args, 2, "old", "new", "count")
+			if err != nil {
+				return nil, err
+			}
+
+			count := -1
+			if !params[2].IsUndefined {
+				count = int(params[2].NumberValue)
+			}
+
+			return NewString(strings.Replace(s, params[0].AsStringValue(), params[1].AsStringValue(), count)), nil
+		}),
+
+		"format": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			formatted, err := formatString(s, args)
+			if err != nil {
+				return nil, ec.ErrorAt(caller, err.Error())
+			}
+
+			return NewString(formatted), nil
+		}),
+
+		"join": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			params, err := methodArgs(ec, caller, "join", args, 1, "iterable")
+			if err != nil {
+				return nil, err
+			}
+
+			var parts []string
+			switch iterable := params[0]; iterable.Type() {
+			case ListVal:
+				parts = make([]string, len(iterable.ListValue))
+				for i, item := range iterable.ListValue {
+					parts[i] = item.AsStringValue()
+				}
+
+			case MapVal:
+				parts = sortedKeys(iterable.MapValue)
+
+			case StringVal:
+				parts = strings.Split(iterable.StringValue, "")
+
+			default:
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("can only join an iterable, got %s", iterable.Type()))
+			}
+
+			return NewString(strings.Join(parts, s)), nil
+		}),
+	}
+}
+
+func listMethods(v *Value) map[string]*Value {
+	return map[string]*Value{
+		"items": NewFunction(func(_ ExecutionContext, _ AST, _ Arguments) (*Value, error) { return v, nil }),
+
+		"append": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			params, err := methodArgs(ec, caller, "append", args, 1, "object")
+			if err != nil {
+				return nil, err
+			}
+
+			v.ListValue = append(v.ListValue, params[0])
+			return v, nil
+		}),
+
+		"extend": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			params, err := methodArgs(ec, caller, "extend", args, 1, "iterable")
+			if err != nil {
+				return nil, err
+			}
+
+			if params[0].Type() != ListVal {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("can only extend a list with a list, got %s", params[0].Type()))
+			}
+
+			// Copied first, so a list can be extended with itself
+			v.ListValue = append(v.ListValue, append([]*Value{}, params[0].ListValue...)...)
+			return v, nil
+		}),
+
+		"pop": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			params, err := methodArgs(ec, caller, "pop", args, 0, "index")
+			if err != nil {
+				return nil, err
+			}
+
+			if len(v.ListValue) == 0 {
+				return nil, ec.ErrorAt(caller, "pop from empty list")
+			}
+
+			index := len(v.ListValue) - 1
+			if !params[0].IsUndefined {
+				if index, err = listIndex(params[0], len(v.ListValue)); err != nil {
+					return nil, ec.ErrorAt(caller, "pop index out of range")
+				}
+			}
+
+			item := v.ListValue[index]
+			v.ListValue = append(v.ListValue[:index], v.ListValue[index+1:]...)
+			return item, nil
+		}),
+
+		"index": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			params, err := methodArgs(ec, caller, "index", args, 1, "value")
+			if err != nil {
+				return nil, err
+			}
+
+			for i, item := range v.ListValue {
+				if item.Equals(params[0]) {
+					return NewNumber(float64(i)), nil
+				}
+			}
+
+			return nil, ec.ErrorAt(caller, fmt.Sprintf("%s is not in list", params[0].AsStringValue()))
+		}),
+
+		"count": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			params, err := methodArgs(ec, caller, "count", args, 1, "value")
+			if err != nil {
+				return nil, err
+			}
+
+			count := 0
+			for _, item := range v.ListValue {
+				if item.Equals(params[0]) {
+					count++
+				}
+			}
+
+			return NewNumber(float64(count)), nil
+		}),
+
+		"insert": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			params, err := methodArgs(ec, caller, "insert", args, 2, "index", "object")
+			if err != nil {
+				return nil, err
+			}
+
+			// Like Python, indexes outside of the list insert at the start or end
+			index := int(params[0].NumberValue)
+			if index < 0 {
+				index += len(v.ListValue)
+			}
+			index = int(math.Max(0, math.Min(float64(index), float64(len(v.ListValue)))))
+
+			v.ListValue = append(v.ListValue, nil)
+			copy(v.ListValue[index+1:], v.ListValue[index:])
+			v.ListValue[index] = params[1]
+			return v, nil
+		}),
+
+		"remove": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			params, err := methodArgs(ec, caller, "remove", args, 1, "value")
+			if err != nil {
+				return nil, err
+			}
+
+			for i, item := range v.ListValue {
+				if item.Equals(params[0]) {
+					v.ListValue = append(v.ListValue[:i], v.ListValue[i+1:]...)
+					return v, nil
+				}
+			}
+
+			return nil, ec.ErrorAt(caller, fmt.Sprintf("list.remove(x): %s not in list", params[0].AsStringValue()))
+		}),
+	}
+}
+
+// As our maps are unordered, keys(), values() and items() are sorted by key so they are stable
+func mapMethods(v *Value) map[string]*Value {
+	return map[string]*Value{
+		"keys": NewFunction(func(_ ExecutionContext, _ AST, _ Arguments) (*Value, error) {
+			return NewStringList(sortedKeys(v.MapValue)), nil
+		}),
+
+		"values": NewFunction(func(_ ExecutionContext, _ AST, _ Arguments) (*Value, error) {
+			keys := sortedKeys(v.MapValue)
+
+			values := make([]*Value, len(keys))
+			for i, key := range keys {
+				values[i] = v.MapValue[key]
+			}
+
+			return NewList(values), nil
+		}),
+
+		"items": NewFunction(func(_ ExecutionContext, _ AST, _ Arguments) (*Value, error) {
+			keys := sortedKeys(v.MapValue)
+
+			items := make([]*Value, len(keys))
+			for i, key := range keys {
+				items[i] = NewTuple(NewString(key), v.MapValue[key])
+			}
+
+			return NewList(items), nil
+		}),
+
+		"get": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			params, err := methodArgs(ec, caller, "get", args, 1, "key", "default")
+			if err != nil {
+				return nil, err
+			}
+
+			if value, found := v.MapValue[params[0].AsStringValue()]; found {
+				return value, nil
+			}
+
+			return params[1], nil
+		}),
+
+		"update": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			if v.MapValue == nil {
+				v.MapValue = make(map[string]*Value)
+			}
+
+			for _, arg := range args {
+				if arg.Name != "" {
+					v.MapValue[arg.Name] = arg.Value.Unwrap()
+					continue
+				}
+
+				other := arg.Value.Unwrap()
+				if other.Type() != MapVal {
+					return nil, ec.ErrorAt(caller, fmt.Sprintf("can only update a map with a map, got %s", other.Type()))
+				}
+
+				for key, value := range other.MapValue {
+					v.MapValue[key] = value
+				}
+			}
+
+			return NewUndefined(), nil
+		}),
+
+		"pop": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			params, err := methodArgs(ec, caller, "pop", args, 1, "key", "default")
+			if err != nil {
+				return nil, err
+			}
+
+			key := params[0].AsStringValue()
+			if value, found := v.MapValue[key]; found {
+				delete(v.MapValue, key)
+				return value, nil
+			}
+
+			if len(args) > 1 {
+				return params[1], nil
+			}
+
+			return nil, ec.ErrorAt(caller, fmt.Sprintf("KeyError: '%s'", key))
+		}),
+
+		"setdefault": NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+			params, err := methodArgs(ec, caller, "setdefault", args, 1, "key", "default")
+			if err != nil {
+				return nil, err
+			}
+
+			key := params[0].AsStringValue()
+			if value, found := v.MapValue[key]; found {
+				return value, nil
+			}
+
+			if v.MapValue == nil {
+				v.MapValue = make(map[string]*Value)
+			}
+
+			v.MapValue[key] = params[1]
+			return params[1], nil
+		}),
+	}
+}
+
+// Reads the arguments of a method by position or name, returning undefined for those which aren't given
+func methodArgs(ec ExecutionContext, caller AST, method string, args Arguments, required int, names ...string) ([]*Value, error) {
+	params := make([]*Value, len(names))
+
+	for i, arg := range args {
+		index := i
+		if arg.Name != "" {
+			index = -1
+			for j, name := range names {
+				if name == arg.Name {
+					index = j
+				}
+			}
+
+			if index < 0 {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("%s() got an unexpected keyword argument '%s'", method, arg.Name))
+			}
+		}
+
+		if index >= len(names) {
+			return nil, ec.ErrorAt(caller, fmt.Sprintf("%s() takes at most %d arguments (%d given)", method, len(names), len(args)))
+		}
+
+		params[index] = arg.Value.Unwrap()
+	}
+
+	for i := range params {
+		if params[i] == nil {
+			if i < required {
+				return nil, ec.ErrorAt(caller, fmt.Sprintf("%s() missing required argument '%s'", method, names[i]))
+			}
+
+			params[i] = NewUndefined()
+		}
+	}
+
+	return params, nil
+}
+
+// Converts a (possibly negative) Python index into an index of a list of the given length
+func listIndex(value *Value, length int) (int, error) {
+	index := int(value.NumberValue)
+	if index < 0 {
+		index += length
+	}
+
+	if index < 0 || index >= length {
+		return 0, fmt.Errorf("index out of range")
+	}
+
+	return index, nil
+}
+
+func stripMethod(s, method string, trimSpace func(string) string, trim func(string, string) string) *Value {
+	return NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+		params, err := methodArgs(ec, caller, method, args, 0, "chars")
+		if err != nil {
+			return nil, err
+		}
+
+		if params[0].IsUndefined || params[0].IsNull {
+			return NewString(trimSpace(s)), nil
+		}
+
+		return NewString(trim(s, params[0].AsStringValue())), nil
+	})
+}
+
+// startswith and endswith, which can be given a single string or a list of them
+func affixMethod(s, method string, hasAffix func(string, string) bool) *Value {
+	return NewFunction(func(ec ExecutionContext, caller AST, args Arguments) (*Value, error) {
+		params, err := methodArgs(ec, caller, method, args, 1, "prefix")
+		if err != nil {
+			return nil, err
+		}
+
+		affixes := []*Value{params[0]}
+		if params[0].Type() == ListVal {
+			affixes = params[0].ListValue
+		}
+
+		for _, affix := range affixes {
+			if hasAffix(s, affix.AsStringValue()) {
+				return NewBoolean(true), nil
+			}
+		}
+
+		return NewBoolean(false), nil
+	})
+}
+
+// Splits on runs of whitespace, as Python's split() does without a separator
+func splitWhitespace(s string, maxSplit int) []string {
+	if maxSplit < 0 {
+		return strings.Fields(s)
+	}
+
+	parts := make([]string, 0)
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	for s != "" && len(parts) < maxSplit {
+		end := strings.IndexFunc(s, unicode.IsSpace)
+		if end < 0 {
+			break
+		}
+
+		parts = append(parts, s[:end])
+		s = strings.TrimLeftFunc(s[end:], unicode.IsSpace)
+	}
+
+	if s != "" {
+		parts = append(parts, s)
+	}
+
+	return parts
+}
+
+func sortedKeys(m map[string]*Value) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Formats a string as Python's str.format does, with `{}`, `{0}` and `{name}` fields. Format specs support
+// fill, alignment, width, precision and the `s`, `d`, `f` and `%` types; such as `{:>8.2f}`
+func formatString(format string, args Arguments) (string, error) {
+	positional := make([]*Value, 0, len(args))
+	named := make(map[string]*Value)
+	for _, arg := range args {
+		if arg.Name != "" {
+			named[arg.Name] = arg.Value.Unwrap()
+		} else {
+			positional = append(positional, arg.Value.Unwrap())
+		}
+	}
+
+	var builder strings.Builder
+	nextIndex := 0
+
+	// As in Python, fields are either all numbered automatically (`{}`) or all numbered by hand (`{0}`)
+	automaticNumbering, manualNumbering := false, false
+
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+
+		switch {
+		case c == '{' && i+1 < len(format) && format[i+1] == '{':
+			builder.WriteByte('{')
+			i++
+
+		case c == '}' && i+1 < len(format) && format[i+1] == '}':
+			builder.WriteByte('}')
+			i++
+
+		case c == '}':
+			return "", fmt.Errorf("Single '}' encountered in format string")
+
+		case c == '{':
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("Single '{' encountered in format string")
+			}
+
+			field := format[i+1 : i+end]
+			i += end
+
+			spec := ""
+			if colon := strings.IndexByte(field, ':'); colon >= 0 {
+				field, spec = field[:colon], field[colon+1:]
+			}
+
+			var value *Value
+			if field == "" {
+				if manualNumbering {
+					return "", fmt.Errorf("ValueError: cannot switch from manual field specification to automatic field numbering")
+				}
+				automaticNumbering = true
+
+				if nextIndex >= len(positional) {
+					return "", fmt.Errorf("Replacement index %d out of range for positional args tuple", nextIndex)
+				}
+
+				value = positional[nextIndex]
+				nextIndex++
+			} else if index, err := strconv.Atoi(field); err == nil && index >= 0 {
+				if automaticNumbering {
+					return "", fmt.Errorf("ValueError: cannot switch from automatic field numbering to manual field specification")
+				}
+				manualNumbering = true
+
+				if index >= len(positional) {
+					return "", fmt.Errorf("Replacement index %d out of range for positional args tuple", index)
+				}
+
+				value = positional[index]
+			} else {
+				var found bool
+				if value, found = named[field]; !found {
+					return "", fmt.Errorf("KeyError: '%s'", field)
+				}
+			}
+
+			formatted, err := formatValue(value, spec)
+			if err != nil {
+				return "", err
+			}
+			builder.WriteString(formatted)
+
+		default:
+			builder.WriteByte(c)
+		}
+	}
+
+	return builder.String(), nil
+}
+
+// Formats a value with a format spec of `[[fill]align][width][.precision][type]`
+func formatValue(value *Value, spec string) (string, error) {
+	fill, align := " ", byte(0)
+	if len(spec) >= 2 && strings.IndexByte("<>^", spec[1]) >= 0 {
+		fill, align, spec = spec[:1], spec[1], spec[2:]
+	} else if len(spec) >= 1 && strings.IndexByte("<>^", spec[0]) >= 0 {
+		align, spec = spec[0], spec[1:]
+	}
+
+	widthEnd := 0
+	for widthEnd < len(spec) && spec[widthEnd] >= '0' && spec[widthEnd] <= '9' {
+		widthEnd++
+	}
+	width, _ := strconv.Atoi(spec[:widthEnd])
+	spec = spec[widthEnd:]
+
+	precision := -1
+	if strings.HasPrefix(spec, ".") {
+		precisionEnd := 1
+		for precisionEnd < len(spec) && spec[precisionEnd] >= '0' && spec[precisionEnd] <= '9' {
+			precisionEnd++
+		}
+		precision, _ = strconv.Atoi(spec[1:precisionEnd])
+		spec = spec[precisionEnd:]
+	}
+
+	var formatted string
+	switch spec {
+	case "", "s":
+		formatted = value.AsStringValue()
+		if precision >= 0 && utf8.RuneCountInString(formatted) > precision {
+			formatted = string([]rune(formatted)[:precision])
+		}
+
+	case "d", "f", "%":
+		number, err := value.AsNumberValue()
+		if err != nil {
+			return "", fmt.Errorf("Unknown format code '%s' for %s", spec, value.Type())
+		}
+
+		switch spec {
+		case "d":
+			formatted = strconv.FormatInt(int64(number), 10)
+		case "f":
+			if precision < 0 {
+				precision = 6
+			}
+			formatted = strconv.FormatFloat(number, 'f', precision, 64)
+		case "%":
+			if precision < 0 {
+				precision = 6
+			}
+			formatted = strconv.FormatFloat(number*100, 'f', precision, 64) + "%"
+		}
+
+		if align == 0 {
+			align = '>'
+		}
+
+	default:
+		return "", fmt.Errorf("Unsupported format spec '%s'", spec)
+	}
+
+	padding := width - utf8.RuneCountInString(formatted)
+	if padding <= 0 {
+		return formatted, nil
+	}
+
+	switch align {
+	case '>':
+		return strings.Repeat(fill, padding) + formatted, nil
+	case '^':
+		return strings.Repeat(fill, padding/2) + formatted + strings.Repeat(fill, padding-padding/2), nil
+	default:
+		return formatted + strings.Repeat(fill, padding), nil
+	}
+}
@@ -8,13 +8,15 @@ import (
 type ExecutionContext interface {
 	SetVariable(name string, value *Value)
 	GetVariable(name string) *Value
+	GetFilter(name string) *Value
 
 	ErrorAt(part AST, error string) error
 	NilResultFor(part AST) error
 	PushState() ExecutionContext
 	CopyVariablesInto(ec ExecutionContext)
 
-	RegisterMacro(name string, ec ExecutionContext, function FunctionDef)
+	RegisterMacro(name string, ec ExecutionContext, function FunctionDef) error
+	ImportMacros(path string) (*Value, error)
 	RegisterUpstreamAndGetRef(name string, fileType string) (*Value, error)
 
 	FileName() string
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
-	"strings"
 
 	"ddbt/jinja/lexer"
 )
@@ -35,7 +34,11 @@ type Value struct {
 	Function     FunctionDef
 	IsUndefined  bool
 	IsNull       bool
+	IsTuple      bool
 	ReturnValue  *Value
+
+	// The Go value behind values which model Python objects, such as the datetimes of `modules.datetime`
+	Object interface{}
 }
 
 func NewBoolean(value bool) *Value {
@@ -46,6 +49,12 @@ func NewString(value string) *Value {
 	return &Value{ValueType: StringVal, StringValue: value}
 }
 
+// NewStringWithProperties creates a value which renders as the string, but also has properties like a map; such
+// as dbt's Relations
+func NewStringWithProperties(value string, properties map[string]*Value) *Value {
+	return &Value{ValueType: StringVal, StringValue: value, MapValue: properties}
+}
+
 func NewNumber(value float64) *Value {
 	return &Value{ValueType: NumberVal, NumberValue: value}
 }
@@ -54,10 +63,41 @@ func NewMap(data map[string]*Value) *Value {
 	return &Value{ValueType: MapVal, MapValue: data}
 }
 
+// The Object of the values made by `namespace()`, which are the only maps whose attributes can be set
+type namespace struct{}
+
+// NewNamespace creates a map whose attributes can be set with `{% set ns.attribute = value %}`
+func NewNamespace(attributes map[string]*Value) *Value {
+	return &Value{ValueType: MapVal, MapValue: attributes, Object: namespace{}}
+}
+
+// IsNamespace reports if the value was made by `namespace()`
+func (v *Value) IsNamespace() bool {
+	_, ok := v.Object.(namespace)
+	return ok
+}
+
 func NewList(data []*Value) *Value {
 	return &Value{ValueType: ListVal, ListValue: data}
 }
 
+// NewNamedList creates a list whose items can also be looked up by name, like the rows and columns of a table
+func NewNamedList(names []string, data []*Value) *Value {
+	named := make(map[string]*Value, len(names))
+	for i, name := range names {
+		if i < len(data) {
+			named[name] = data[i]
+		}
+	}
+
+	return &Value{ValueType: ListVal, ListValue: data, MapValue: named}
+}
+
+// NewTuple creates a list which `{% for a, b in ... %}` loops unpack, like a Python tuple
+func NewTuple(data ...*Value) *Value {
+	return &Value{ValueType: ListVal, ListValue: data, IsTuple: true}
+}
+
 func NewStringList(data []string) *Value {
 	l := make([]*Value, len(data))
 
@@ -82,6 +122,10 @@ func NewUndefined() *Value {
 	}
 }
 
+func NewNull() *Value {
+	return &Value{ValueType: NullVal, IsNull: true}
+}
+
 func NewReturnValue(value *Value) *Value {
 	return &Value{
 		ValueType:   ReturnVal,
@@ -131,41 +175,51 @@ func (v *Value) Type() ValueType {
 func (v *Value) Properties(isForFunctionCall bool) map[string]*Value {
 	switch v.Type() {
 	case MapVal:
-		return v.MapValue
+		if !isForFunctionCall {
+			return v.MapValue
+		}
 
-	case ListVal:
-		extendFunc := NewFunction(func(_ ExecutionContext, _ AST, args Arguments) (*Value, error) {
-			for _, arg := range args[0].Value.ListValue {
-				if arg != v {
-					v.ListValue = append(v.ListValue, arg)
-				}
+		// Functions in a map, such as those of `adapter` or `modules.re`, are called in preference to dict methods
+		properties := mapMethods(v)
+		for name, value := range v.MapValue {
+			if _, found := properties[name]; !found || value.Type() == FunctionalVal {
+				properties[name] = value
 			}
-			return v, nil
-		})
+		}
+
+		return properties
 
-		return map[string]*Value{
-			"items":  NewFunction(func(_ ExecutionContext, _ AST, _ Arguments) (*Value, error) { return v, nil }),
-			"extend": extendFunc,
-			"append": extendFunc,
+	case ListVal:
+		properties := listMethods(v)
+
+		// Items in named lists can also be referenced as properties, taking priority over the list's methods so
+		// a column named `items` can still be reached
+		for name, value := range v.MapValue {
+			properties[name] = value
 		}
 
+		return properties
+
 	case ReturnVal:
 		return v.ReturnValue.Properties(isForFunctionCall)
 
 	case StringVal:
 		if !isForFunctionCall {
-			return nil
+			return v.MapValue
 		}
 
-		return map[string]*Value{
-			"upper": NewFunction(func(_ ExecutionContext, _ AST, _ Arguments) (*Value, error) {
-				return NewString(strings.ToUpper(v.StringValue)), nil
-			}),
-			"lower": NewFunction(func(_ ExecutionContext, _ AST, _ Arguments) (*Value, error) {
-				return NewString(strings.ToUpper(v.StringValue)), nil
-			}),
+		properties := stringMethods(v)
+
+		for name, value := range v.MapValue {
+			properties[name] = value
 		}
 
+		return properties
+
+	case FunctionalVal:
+		// Functions such as `var` can also have methods, such as `var.has_var`
+		return v.MapValue
+
 	default:
 		return nil
 	}
@@ -346,7 +400,7 @@ func ValueFromToken(t *lexer.Token) (*Value, error) {
 		return NewBoolean(false), nil
 
 	case lexer.NullToken:
-		return &Value{ValueType: NullVal, IsNull: true}, nil
+		return NewNull(), nil
 
 	case lexer.NoneToken:
 		return NewUndefined(), nil
@@ -377,6 +431,42 @@ func NewValueFromInterface(value interface{}) (*Value, error) {
 		return NewNumber(v), nil
 	case bool:
 		return NewBoolean(v), nil
+	case nil:
+		return NewNull(), nil
+
+	case []interface{}:
+		list := make([]*Value, len(v))
+		for i, item := range v {
+			value, err := NewValueFromInterface(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = value
+		}
+		return NewList(list), nil
+
+	case map[string]interface{}:
+		m := make(map[string]*Value, len(v))
+		for key, item := range v {
+			value, err := NewValueFromInterface(item)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return NewMap(m), nil
+
+	case map[interface{}]interface{}:
+		// As decoded from YAML
+		m := make(map[string]*Value, len(v))
+		for key, item := range v {
+			value, err := NewValueFromInterface(item)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(key)] = value
+		}
+		return NewMap(m), nil
 
 	default:
 		return nil, fmt.Errorf("Unknown value type %v", reflect.TypeOf(value))
@@ -1,10 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -17,6 +19,23 @@ type Config struct {
 	ModelGroups     map[string]*Target
 	ModelGroupsFile string
 
+	// Set by `--full-refresh`, rebuilds incremental models from scratch
+	FullRefresh bool
+
+	// Set by `--no-grants`, skips applying the grants config of models
+	NoGrants bool
+
+	// Where datasets which don't exist yet are created, and the default table expiration to create them with
+	DatasetLocation        string
+	DefaultTableExpiration time.Duration
+
+	// The `vars:` of `dbt_project.yml`, and those given to `--vars` which override them
+	Vars    map[string]interface{}
+	CLIVars map[string]interface{}
+
+	// The `dispatch:` of `dbt_project.yml`, the order `adapter.dispatch` searches projects for macros of a namespace
+	Dispatch map[string][]string
+
 	// seedConfig holds the seed (global) configurations
 	seedConfig map[string]*SeedConfig
 }
@@ -45,7 +64,7 @@ func (c *Config) GetTargetFor(path string) *Target {
 
 var GlobalCfg *Config
 
-func Read(targetProfile string, upstreamProfile string, threads int, customConfigPath string, strExecutor func(s string) (string, error)) (*Config, error) {
+func Read(targetProfile string, upstreamProfile string, threads int, customConfigPath string, cliVars map[string]interface{}, strExecutor func(s string) (string, error)) (*Config, error) {
 	project, err := readDBTProject(customConfigPath)
 	if err != nil {
 		return nil, err
@@ -92,6 +111,18 @@ func Read(targetProfile string, upstreamProfile string, threads int, customConfi
 			ProjectSubstitutions: make(map[string]map[string]string),
 			ExecutionProjects:    make([]string, 0),
 		},
+		CLIVars: cliVars,
+	}
+
+	// Vars are read first, as the rest of the config can use them
+	GlobalCfg.Vars, err = readProjectVars(project.Vars, project.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	GlobalCfg.Dispatch, err = readDispatchConfig(project.Dispatch)
+	if err != nil {
+		return nil, err
 	}
 
 	if upstreamProfile != "" {
@@ -112,6 +143,12 @@ func Read(targetProfile string, upstreamProfile string, threads int, customConfi
 		}
 	}
 
+	GlobalCfg.DatasetLocation = appConfig.Datasets.Location
+	GlobalCfg.DefaultTableExpiration, err = appConfig.Datasets.tableExpirationFor(targetProfile)
+	if err != nil {
+		return nil, err
+	}
+
 	if appConfig.ModelGroupsFile != "" {
 		modelGroups, err := readModelGroupConfig(appConfig.ModelGroupsFile, targetProfile, upstreamProfile, GlobalCfg.Target)
 		if err != nil {
@@ -130,6 +167,16 @@ func Read(targetProfile string, upstreamProfile string, threads int, customConfi
 		return nil, fmt.Errorf("no models config found, expected to find `models: %s:` in `dbt_project.yml`", project.Name)
 	}
 
+	for name, settings := range project.Models {
+		if name == project.Name {
+			continue
+		}
+
+		if err := readPackageFolderBasedConfig(name, settings, strExecutor); err != nil {
+			return nil, err
+		}
+	}
+
 	if seedCfg, found := project.Seeds[project.Name]; found {
 		cfg, err := readSeedCfg(seedCfg)
 		if err != nil {
@@ -148,10 +195,36 @@ func NumberThreads() int {
 }
 
 type dbtProject struct {
-	Name    string                            `yaml:"name"`
-	Profile string                            `yaml:"profile"`
-	Models  map[string]map[string]interface{} `yaml:"models"` // "Models[project_name][key]value"
-	Seeds   map[string]map[string]interface{} `yaml:"seeds"`  // "Seeds[project_name][key]value"
+	Name     string                            `yaml:"name"`
+	Profile  string                            `yaml:"profile"`
+	Models   map[string]map[string]interface{} `yaml:"models"` // "Models[project_name][key]value"
+	Seeds    map[string]map[string]interface{} `yaml:"seeds"`  // "Seeds[project_name][key]value"
+	Vars     map[string]interface{}            `yaml:"vars"`
+	Dispatch []dispatchConfig                  `yaml:"dispatch"`
+}
+
+// https://docs.getdbt.com/reference/project-configs/dispatch-config
+type dispatchConfig struct {
+	MacroNamespace string   `yaml:"macro_namespace"`
+	SearchOrder    []string `yaml:"search_order"`
+}
+
+func readDispatchConfig(configs []dispatchConfig) (map[string][]string, error) {
+	dispatch := make(map[string][]string, len(configs))
+
+	for _, cfg := range configs {
+		if cfg.MacroNamespace == "" {
+			return nil, errors.New("every `dispatch:` in `dbt_project.yml` needs a `macro_namespace`")
+		}
+
+		if len(cfg.SearchOrder) == 0 {
+			return nil, fmt.Errorf("the `dispatch:` of `%s` in `dbt_project.yml` needs a `search_order`", cfg.MacroNamespace)
+		}
+
+		dispatch[cfg.MacroNamespace] = cfg.SearchOrder
+	}
+
+	return dispatch, nil
 }
 
 func handleCustomConfigPath(customConfigPath string) (string, error) {
@@ -216,8 +289,29 @@ func readProfile(profileName string) (dbtProfile, error) {
 }
 
 type ddbtConfig struct {
-	ModelGroupsFile  string   `yaml:"model-groups-config"`
-	ProtectedTargets []string `yaml:"protected-targets"` // Targets that DDBT is not allowed to execute against
+	ModelGroupsFile  string         `yaml:"model-groups-config"`
+	ProtectedTargets []string       `yaml:"protected-targets"` // Targets that DDBT is not allowed to execute against
+	Datasets         datasetsConfig `yaml:"datasets"`
+}
+
+// How DDBT creates the datasets models are written to when they don't exist yet
+type datasetsConfig struct {
+	Location               string            `yaml:"location"`                 // Defaults to the location of the target
+	DefaultTableExpiration map[string]string `yaml:"default-table-expiration"` // Target name to a duration, such as `168h`
+}
+
+func (d datasetsConfig) tableExpirationFor(targetName string) (time.Duration, error) {
+	expiration, found := d.DefaultTableExpiration[targetName]
+	if !found {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(expiration)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to parse the default-table-expiration for `%s`: %s", targetName, err)
+	}
+
+	return duration, nil
 }
 
 func readDDBTConfig() (ddbtConfig, error) {
@@ -1,6 +1,13 @@
 package config
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
 
 func Test_handleCustomConfigPath(t *testing.T) {
 	type args struct {
@@ -44,3 +51,57 @@ func Test_handleCustomConfigPath(t *testing.T) {
 		})
 	}
 }
+
+func Test_tableExpirationFor(t *testing.T) {
+	datasets := datasetsConfig{
+		DefaultTableExpiration: map[string]string{
+			"dev":    "168h",
+			"broken": "a week",
+		},
+	}
+
+	tests := []struct {
+		name    string
+		target  string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "configured target", target: "dev", want: 7 * 24 * time.Hour},
+		{name: "target without an expiration", target: "prod", want: 0},
+		{name: "invalid duration", target: "broken", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := datasets.tableExpirationFor(tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("tableExpirationFor() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("tableExpirationFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadDispatchConfig(t *testing.T) {
+	dbtProjectYml := `
+name: ddbt
+dispatch:
+  - macro_namespace: dbt_utils
+    search_order: ['ddbt', 'dbt_utils']
+`
+
+	var project dbtProject
+	require.NoError(t, yaml.Unmarshal([]byte(dbtProjectYml), &project))
+
+	dispatch, err := readDispatchConfig(project.Dispatch)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"dbt_utils": {"ddbt", "dbt_utils"}}, dispatch)
+
+	_, err = readDispatchConfig([]dispatchConfig{{MacroNamespace: "dbt_utils"}})
+	assert.EqualError(t, err, "the `dispatch:` of `dbt_utils` in `dbt_project.yml` needs a `search_order`")
+
+	_, err = readDispatchConfig([]dispatchConfig{{SearchOrder: []string{"ddbt"}}})
+	assert.Error(t, err)
+}
@@ -28,7 +28,9 @@ type GeneralConfig struct {
 		Relation bool
 		Columns  bool
 	}
-	FullRefresh bool
+	FullRefresh *bool                  // nil unless set, in which case it overrides the `--full-refresh` flag
+	Grants      map[string][]string    // IAM role to the members which should be granted it
+	Vars        map[string]interface{} // Vars scoped to the folder, on top of those of its parent folders
 }
 
 var defaultConfig = ModelConfig{
@@ -70,6 +72,13 @@ func readGeneralFolderBasedConfig(m map[string]interface{}, strExecutor func(s s
 	return nil
 }
 
+// Reads the config of a package's models, which dbt_project.yml gives under the package's name
+func readPackageFolderBasedConfig(packageName string, m map[string]interface{}, strExecutor func(s string) (string, error)) error {
+	folder := fmt.Sprintf("%s%c%s%cmodels%c", PackagesFolder, os.PathSeparator, packageName, os.PathSeparator, os.PathSeparator)
+
+	return readSubFolder(folder, defaultConfig, m, strExecutor)
+}
+
 func readSubFolder(folderName string, config ModelConfig, m map[string]interface{}, strExecutor func(s string) (string, error)) error {
 	subFolders := make(map[string]map[string]interface{})
 
@@ -224,11 +233,25 @@ func readGeneralConfig(
 
 		case "full_refresh":
 			if b, ok := value.(bool); ok {
-				config.FullRefresh = b
+				config.FullRefresh = &b
 			} else {
 				return nil, fmt.Errorf("Unable to convert `full_refresh` to boolean, got: %v", reflect.TypeOf(value))
 			}
 
+		case "grants":
+			grants, err := readGrants(value, strExecutor)
+			if err != nil {
+				return nil, err
+			}
+			config.Grants = grants
+
+		case "vars":
+			vars, err := asVarsMap("vars", value)
+			if err != nil {
+				return nil, err
+			}
+			config.Vars = mergeVars(config.Vars, vars)
+
 		default:
 			// For any key not part of general configurations,
 			// copy to remaining to be processed later.
@@ -241,3 +264,32 @@ func readGeneralConfig(
 
 	return remaining, nil
 }
+
+// readGrants reads a map of IAM roles to a member, or list of members, to grant them to
+func readGrants(value interface{}, strExecutor func(s string) (string, error)) (map[string][]string, error) {
+	m, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Unable to convert `grants` into a map, got: %v", reflect.TypeOf(value))
+	}
+
+	grants := make(map[string][]string, len(m))
+	for role, members := range m {
+		roleStr, ok := role.(string)
+		if !ok {
+			return nil, fmt.Errorf("Unable to convert role `%v` in `grants` to a string", role)
+		}
+
+		if members == nil {
+			grants[roleStr] = []string{}
+			continue
+		}
+
+		list, err := strOrList("grants", members, strExecutor)
+		if err != nil {
+			return nil, err
+		}
+		grants[roleStr] = list
+	}
+
+	return grants, nil
+}
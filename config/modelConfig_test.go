@@ -48,3 +48,39 @@ models:
 	assert.Equal(t, []string(nil), folderBasedConfig["models/another_table_name"].Tags)
 	assert.Equal(t, "ephemeral", folderBasedConfig["models/another_table_name/"].Materialized)
 }
+
+func TestModelConfigGrants(t *testing.T) {
+	dbtProjectYml := `
+name: package_name
+version: '1.0'
+
+profile: ddbt
+models:
+  ddbt:
+    grants:
+      roles/bigquery.dataViewer: ["group:analysts@example.com", "user:someone@example.com"]
+    reporting:
+      grants:
+        roles/bigquery.dataViewer: group:reporting@example.com
+        roles/bigquery.dataEditor:
+`
+
+	var project dbtProject
+	require.NoError(t, yaml.Unmarshal([]byte(dbtProjectYml), &project))
+	err := readGeneralFolderBasedConfig(project.Models["ddbt"], func(s string) (string, error) { return s, nil })
+	require.NoError(t, err)
+
+	assert.Equal(
+		t,
+		map[string][]string{"roles/bigquery.dataViewer": {"group:analysts@example.com", "user:someone@example.com"}},
+		folderBasedConfig["models/"].Grants,
+	)
+	assert.Equal(
+		t,
+		map[string][]string{
+			"roles/bigquery.dataViewer": {"group:reporting@example.com"},
+			"roles/bigquery.dataEditor": {},
+		},
+		folderBasedConfig["models/reporting/"].Grants,
+	)
+}
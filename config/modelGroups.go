@@ -85,6 +85,7 @@ func updateTargetFromModelGroupConfig(target *Target, targetName string, targetC
 		switch v := targetCfg.Dataset.(type) {
 		case string:
 			target.DataSet = v
+			target.PerUserDataset = false
 
 		case map[interface{}]interface{}:
 			if b, ok := v["from_env"].(bool); ok && b {
@@ -98,6 +99,7 @@ func updateTargetFromModelGroupConfig(target *Target, targetName string, targetC
 				} else {
 					target.DataSet = fmt.Sprintf("dbt_%s_%s", u.Username, targetName)
 				}
+				target.PerUserDataset = true
 			} else {
 				return errors.New("expected dataset to be string or { 'from_env': true }")
 			}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// The folder `ddbt deps` installs packages into, and which their macros and models are read from
+const PackagesFolder = "dbt_packages"
+
+// A package listed in packages.yml, which is either a local folder or a tag (or branch) of a git repository
+//
+// https://docs.getdbt.com/docs/build/packages
+type Package struct {
+	Local    string `yaml:"local"`
+	Git      string `yaml:"git"`
+	Revision string `yaml:"revision"`
+
+	Hub string `yaml:"package"` // Packages from the dbt hub are not supported
+}
+
+type packagesFile struct {
+	Packages []Package `yaml:"packages"`
+}
+
+// The name a package is installed under if its dbt_project.yml doesn't give one
+func (p Package) DefaultName() string {
+	if p.Local != "" {
+		absPath, err := filepath.Abs(p.Local)
+		if err != nil {
+			return filepath.Base(p.Local)
+		}
+
+		return filepath.Base(absPath)
+	}
+
+	return strings.TrimSuffix(path.Base(p.Git), ".git")
+}
+
+func (p Package) String() string {
+	if p.Local != "" {
+		return p.Local
+	}
+
+	if p.Revision != "" {
+		return p.Git + "@" + p.Revision
+	}
+
+	return p.Git
+}
+
+// ReadPackages reads the packages listed in a packages.yml file, which doesn't need to exist
+func ReadPackages(fileName string) ([]Package, error) {
+	bytes, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file := packagesFile{}
+	if err := yaml.Unmarshal(bytes, &file); err != nil {
+		return nil, fmt.Errorf("Unable to parse %s: %s", fileName, err)
+	}
+
+	for i, pkg := range file.Packages {
+		switch {
+		case pkg.Hub != "":
+			return nil, fmt.Errorf("package `%s` is from the dbt hub, which isn't supported; use its `git:` repository instead", pkg.Hub)
+
+		case pkg.Local != "" && pkg.Git != "":
+			return nil, fmt.Errorf("package %d of %s has both a `local:` and a `git:` source", i+1, fileName)
+
+		case pkg.Local == "" && pkg.Git == "":
+			return nil, fmt.Errorf("package %d of %s needs either a `local:` or a `git:` source", i+1, fileName)
+		}
+	}
+
+	return file.Packages, nil
+}
+
+// ProjectName returns the name given in the dbt_project.yml of the given folder
+func ProjectName(folder string) (string, error) {
+	project, err := readDBTProject(folder)
+	if err != nil {
+		return "", err
+	}
+
+	if project.Name == "" {
+		return "", errors.New("no name in " + filepath.Join(folder, "dbt_project.yml"))
+	}
+
+	return project.Name, nil
+}
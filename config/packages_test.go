@@ -0,0 +1,51 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePackagesYml(t *testing.T, contents string) string {
+	fileName := filepath.Join(t.TempDir(), "packages.yml")
+	require.NoError(t, ioutil.WriteFile(fileName, []byte(contents), 0600))
+
+	return fileName
+}
+
+func TestReadPackages(t *testing.T) {
+	packages, err := ReadPackages(writePackagesYml(t, `
+packages:
+  - local: ../shared/macros_package
+  - git: "https://github.com/dbt-labs/dbt-utils.git"
+    revision: 0.8.0
+`))
+	require.NoError(t, err)
+
+	require.Len(t, packages, 2)
+	assert.Equal(t, "../shared/macros_package", packages[0].String())
+	assert.Equal(t, "macros_package", packages[0].DefaultName())
+	assert.Equal(t, "https://github.com/dbt-labs/dbt-utils.git@0.8.0", packages[1].String())
+	assert.Equal(t, "dbt-utils", packages[1].DefaultName())
+}
+
+func TestReadPackagesWithoutAFile(t *testing.T) {
+	packages, err := ReadPackages(filepath.Join(t.TempDir(), "packages.yml"))
+	require.NoError(t, err)
+	assert.Empty(t, packages)
+}
+
+func TestReadInvalidPackages(t *testing.T) {
+	for contents, expectedError := range map[string]string{
+		"packages:\n  - package: dbt-labs/dbt_utils\n    version: 0.8.0": "is from the dbt hub",
+		"packages:\n  - local: a\n    git: b":                            "has both a `local:` and a `git:` source",
+		"packages:\n  - revision: 0.8.0":                                 "needs either a `local:` or a `git:` source",
+	} {
+		_, err := ReadPackages(writePackagesYml(t, contents))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), expectedError)
+	}
+}
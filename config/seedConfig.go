@@ -23,6 +23,10 @@ func (c *Config) GetSeedConfig(path string) *SeedConfig {
 func (c *Config) GetFolderBasedSeedConfig(path string) *SeedConfig {
 	configPath := "data"
 	parentConfig := c.seedConfig[configPath]
+	if parentConfig == nil {
+		parentConfig = &SeedConfig{}
+	}
+
 	config := &SeedConfig{
 		GeneralConfig: GeneralConfig{
 			Enabled: parentConfig.Enabled,
@@ -10,6 +10,7 @@ type Target struct {
 	Threads              int
 	ProjectSubstitutions map[string]map[string]string
 	ExecutionProjects    []string
+	PerUserDataset       bool // The dataset is named after the user (`from_env` in the model groups), so only they write to it
 
 	ReadUpstream *Target // If the reference is outside this DAG, this is the target we should read from
 }
@@ -42,6 +43,7 @@ func (t *Target) Copy() *Target {
 		Threads:              t.Threads,
 		ProjectSubstitutions: projectSubs,
 		ExecutionProjects:    executionProjects,
+		PerUserDataset:       t.PerUserDataset,
 		ReadUpstream:         defaultUpstream,
 	}
 }
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ParseVars reads the dictionary of vars given to `--vars`, which can be written as YAML or JSON
+func ParseVars(raw string) (map[string]interface{}, error) {
+	vars := make(map[string]interface{})
+
+	if raw == "" {
+		return vars, nil
+	}
+
+	if err := yaml.Unmarshal([]byte(raw), &vars); err != nil {
+		return nil, fmt.Errorf("Unable to parse `--vars`, expected a YAML dictionary such as `{key: value}`: %s", err)
+	}
+
+	return vars, nil
+}
+
+// readProjectVars reads the `vars:` of `dbt_project.yml`. Vars nested under the project's name are scoped to the
+// project and take precedence over the global vars
+func readProjectVars(vars map[string]interface{}, projectName string) (map[string]interface{}, error) {
+	projectVars := make(map[string]interface{}, len(vars))
+
+	for key, value := range vars {
+		if key != projectName {
+			projectVars[key] = value
+		}
+	}
+
+	if scoped, found := vars[projectName]; found {
+		scopedVars, err := asVarsMap(projectName, scoped)
+		if err != nil {
+			return nil, err
+		}
+
+		projectVars = mergeVars(projectVars, scopedVars)
+	}
+
+	return projectVars, nil
+}
+
+// asVarsMap converts a YAML dictionary of vars into a map keyed by the var names
+func asVarsMap(name string, value interface{}) (map[string]interface{}, error) {
+	m, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Unable to convert `%s` into a map of vars, got: %v", name, reflect.TypeOf(value))
+	}
+
+	vars := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		keyStr, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("Unable to convert var `%v` in `%s` to a string", key, name)
+		}
+
+		vars[keyStr] = value
+	}
+
+	return vars, nil
+}
+
+// mergeVars returns a new map with the overrides applied on top of the base vars
+func mergeVars(base map[string]interface{}, overrides map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(overrides))
+
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, value := range overrides {
+		merged[key] = value
+	}
+
+	return merged
+}
+
+// LookupVar finds the value of a var; vars given to `--vars` take precedence over the vars of the model's folder,
+// which take precedence over the project's vars
+func (c *Config) LookupVar(name string, folderVars map[string]interface{}) (interface{}, bool) {
+	if value, found := c.CLIVars[name]; found {
+		return value, true
+	}
+
+	if value, found := folderVars[name]; found {
+		return value, true
+	}
+
+	value, found := c.Vars[name]
+	return value, found
+}
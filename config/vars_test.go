@@ -0,0 +1,71 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestProjectAndFolderVars(t *testing.T) {
+	dbtProjectYml := `
+name: ddbt
+profile: ddbt
+vars:
+  start_date: '2021-01-01'
+  feature_flag: false
+  ddbt:
+    feature_flag: true
+models:
+  ddbt:
+    vars:
+      region: eu
+      owner: data
+    finance:
+      vars:
+        region: us
+        currency: USD
+`
+
+	var project dbtProject
+	require.NoError(t, yaml.Unmarshal([]byte(dbtProjectYml), &project))
+
+	vars, err := readProjectVars(project.Vars, project.Name)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"start_date": "2021-01-01", "feature_flag": true}, vars, "vars scoped to the project should override global vars")
+
+	require.NoError(t, readGeneralFolderBasedConfig(project.Models["ddbt"], func(s string) (string, error) { return s, nil }))
+	assert.Equal(t, map[string]interface{}{"region": "eu", "owner": "data"}, folderBasedConfig["models/"].Vars)
+	assert.Equal(t, map[string]interface{}{"region": "us", "currency": "USD", "owner": "data"}, folderBasedConfig["models/finance/"].Vars, "folder vars should be merged onto those of the parent folder")
+
+	cfg := &Config{Vars: vars, CLIVars: map[string]interface{}{"start_date": "2021-06-01"}}
+
+	value, found := cfg.LookupVar("start_date", folderBasedConfig["models/finance/"].Vars)
+	assert.True(t, found)
+	assert.Equal(t, "2021-06-01", value, "vars given on the command line should override all others")
+
+	value, found = cfg.LookupVar("region", folderBasedConfig["models/finance/"].Vars)
+	assert.True(t, found)
+	assert.Equal(t, "us", value)
+
+	_, found = cfg.LookupVar("region", nil)
+	assert.False(t, found)
+}
+
+func TestParseVars(t *testing.T) {
+	vars, err := ParseVars(`{start_date: '2021-01-01', limit: 10}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"start_date": "2021-01-01", "limit": 10}, vars)
+
+	vars, err = ParseVars(`{"start_date": "2021-01-01"}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"start_date": "2021-01-01"}, vars)
+
+	vars, err = ParseVars("")
+	require.NoError(t, err)
+	assert.Empty(t, vars)
+
+	_, err = ParseVars("[1, 2]")
+	assert.Error(t, err)
+}
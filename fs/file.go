@@ -19,12 +19,14 @@ const (
 	ModelFile   FileType = "model"
 	MacroFile   FileType = "macro"
 	TestFile    FileType = "test"
+	SeedNode    FileType = "seed" // the node in the graph representing a SeedFile
 )
 
 type File struct {
-	Type FileType
-	Name string
-	Path string
+	Type    FileType
+	Name    string
+	Path    string
+	Package string // The package the file is from, or empty if it's part of the project
 
 	cfgMutex     sync.Mutex
 	FolderConfig config.ModelConfig
@@ -50,6 +52,8 @@ type File struct {
 	isInDAG       bool
 
 	IsView bool // true if the model should be materialised as a view and not a table
+
+	Seed *SeedFile // the seed this file represents in the graph, if the file is a SeedNode
 }
 
 func newFile(path string, fileType FileType) *File {
@@ -57,6 +61,7 @@ func newFile(path string, fileType FileType) *File {
 		Type:         fileType,
 		Name:         strings.TrimSuffix(filepath.Base(path), ".sql"),
 		Path:         path,
+		Package:      packageOf(path),
 		FolderConfig: config.GetFolderConfig(path),
 
 		config:      make(map[string]*compilerInterface.Value),
@@ -71,6 +76,20 @@ func (f *File) GetName() string {
 	return f.Name
 }
 
+// The name FileSystem.Macro finds this file by, which is prefixed with the package for files in packages
+func (f *File) MacroName() string {
+	if f.Package != "" {
+		return f.Package + "." + f.Name
+	}
+
+	return f.Name
+}
+
+// Whether the file was added by ddbt, such as the built in macros, rather than read from the project
+func (f *File) IsVirtual() bool {
+	return strings.HasPrefix(f.Path, virtualFolder+"/")
+}
+
 func (f *File) SetConfig(name string, value *compilerInterface.Value) {
 	f.configMutex.Lock()
 	defer f.configMutex.Unlock()
@@ -102,6 +121,24 @@ func (f *File) GetConfig(name string) *compilerInterface.Value {
 		case "materialized":
 			return compilerInterface.NewString(f.FolderConfig.Materialized)
 
+		case "full_refresh":
+			if f.FolderConfig.FullRefresh != nil {
+				return compilerInterface.NewBoolean(*f.FolderConfig.FullRefresh)
+			}
+
+			return compilerInterface.NewUndefined()
+
+		case "grants":
+			if f.FolderConfig.Grants == nil {
+				return compilerInterface.NewUndefined()
+			}
+
+			grants := make(map[string]*compilerInterface.Value, len(f.FolderConfig.Grants))
+			for role, members := range f.FolderConfig.Grants {
+				grants[role] = compilerInterface.NewStringList(members)
+			}
+			return compilerInterface.NewMap(grants)
+
 		default:
 			return compilerInterface.NewUndefined()
 		}
@@ -157,9 +194,7 @@ func (f *File) ConfigObject() *compilerInterface.Value {
 		if materialized := f.GetConfig("materialized"); materialized.Type() == compilerInterface.StringVal && materialized.StringValue != "" {
 			f.cfgMutex.Lock()
 			f.FolderConfig.Materialized = materialized.StringValue
-			if materialized.StringValue == "view" {
-				f.IsView = true
-			}
+			f.IsView = materialized.StringValue == "view"
 			f.cfgMutex.Unlock()
 		}
 
@@ -240,6 +275,10 @@ func (f *File) IsDynamicSQL() bool {
 }
 
 func (f *File) GetTarget() (*config.Target, error) {
+	if f.Seed != nil {
+		return f.Seed.GetTarget()
+	}
+
 	target := config.GlobalCfg.GetTargetFor(f.Path)
 
 	// Tests may not define these, so we can pull them from the model they are being tested against
@@ -277,6 +316,7 @@ func (f *File) GetTarget() (*config.Target, error) {
 		target = target.Copy()
 
 		target.DataSet = value.StringValue
+		target.PerUserDataset = false
 	}
 
 	// Through project tag substitution, do we need to replace the project id?
@@ -332,6 +372,16 @@ func (f *File) GetMaterialization() string {
 	return f.FolderConfig.Materialized
 }
 
+// IsFullRefresh returns true if the model should be rebuilt from scratch; a `full_refresh` config on the model
+// takes precedence over the `--full-refresh` flag, so expensive tables can be protected from accidental rebuilds
+func (f *File) IsFullRefresh() bool {
+	if fullRefresh := f.GetConfig("full_refresh"); fullRefresh.Type() == compilerInterface.BooleanValue {
+		return fullRefresh.BooleanValue
+	}
+
+	return config.GlobalCfg != nil && config.GlobalCfg.FullRefresh
+}
+
 func (f *File) GetTags() []string {
 	f.cfgMutex.Lock()
 	defer f.cfgMutex.Unlock()
@@ -364,3 +414,18 @@ func (f *File) HasTag(tag string) bool {
 
 	return false
 }
+
+// Creates a copy of this file which shares its parsed syntax tree, but none of its config or compiled state.
+// This allows the file to be compiled differently without affecting the original (such as for unit tests)
+func (f *File) VirtualCopy() *File {
+	f.Mutex.Lock()
+	defer f.Mutex.Unlock()
+
+	file := newFile(f.Path, f.Type)
+	file.Name = f.Name
+	file.Schema = f.Schema
+	file.SyntaxTree = f.SyntaxTree
+	file.PrereadFileContents = f.PrereadFileContents
+
+	return file
+}
@@ -7,38 +7,46 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
 	"ddbt/compilerInterface"
+	"ddbt/config"
 )
 
+// The folder of the files ddbt adds itself, such as the built in macros
+const virtualFolder = "§VIRTUAL§"
+
 type FileSystem struct {
-	files       map[string]*File       // path -> File
-	macroLookup map[string]*File       // macro name -> File
-	modelLookup map[string]*File       // model lookup name -> File
-	schemas     map[string]*SchemaFile // schema files
-	tests       map[string]*File       // Tests
-	seeds       map[string]*SeedFile   // Seed CSV files
-	Docs        map[string]*DocFile
-	testMutex   sync.Mutex
+	files         map[string]*File       // path -> File
+	macroFiles    map[string]*File       // path -> macro File
+	macroLookup   map[string][]*File     // macro file name -> Files, named with their package if they're in one
+	packageMacros map[string][]*File     // package name -> macro Files
+	modelLookup   map[string]*File       // model lookup name -> File
+	schemas       map[string]*SchemaFile // schema files
+	tests         map[string]*File       // Tests
+	seeds         map[string]*SeedFile   // Seed CSV files
+	Docs          map[string]*DocFile
+	testMutex     sync.Mutex
 }
 
 func ReadFileSystem(msgWriter io.Writer) (*FileSystem, error) {
 	fs := &FileSystem{
-		files:       make(map[string]*File),
-		macroLookup: make(map[string]*File),
-		modelLookup: make(map[string]*File),
-		schemas:     make(map[string]*SchemaFile),
-		tests:       make(map[string]*File),
-		seeds:       make(map[string]*SeedFile),
-		Docs:        make(map[string]*DocFile),
+		files:         make(map[string]*File),
+		macroFiles:    make(map[string]*File),
+		macroLookup:   make(map[string][]*File),
+		packageMacros: make(map[string][]*File),
+		modelLookup:   make(map[string]*File),
+		schemas:       make(map[string]*SchemaFile),
+		tests:         make(map[string]*File),
+		seeds:         make(map[string]*SeedFile),
+		Docs:          make(map[string]*DocFile),
 	}
 
-	// FIXME: disabled for a bit
-	//if err := fs.scanDBTModuleMacros(); err != nil {
-	//	return nil, err
-	//}
+	if err := fs.scanPackages(); err != nil {
+		return nil, err
+	}
 
 	if err := fs.scanDirectory("./macros/", MacroFile); err != nil {
 		return nil, err
@@ -63,8 +71,8 @@ func ReadFileSystem(msgWriter io.Writer) (*FileSystem, error) {
 	fmt.Fprintf(
 		msgWriter,
 		"🔎 Found %d models, %d macros, %d tests, %d schema, %d seed files, %d docs\n",
-		len(fs.files)-len(fs.macroLookup)-len(fs.tests),
-		len(fs.macroLookup),
+		len(fs.files)-len(fs.Macros())-len(fs.tests),
+		len(fs.Macros()),
 		len(fs.tests),
 		len(fs.schemas),
 		len(fs.seeds),
@@ -77,24 +85,35 @@ func ReadFileSystem(msgWriter io.Writer) (*FileSystem, error) {
 // Create a test file system with mock files
 func InMemoryFileSystem(models map[string]string) (*FileSystem, error) {
 	fs := &FileSystem{
-		files:       make(map[string]*File),
-		macroLookup: make(map[string]*File),
-		modelLookup: make(map[string]*File),
-		schemas:     make(map[string]*SchemaFile),
-		tests:       make(map[string]*File),
-		seeds:       make(map[string]*SeedFile),
-		Docs:        make(map[string]*DocFile),
+		files:         make(map[string]*File),
+		macroFiles:    make(map[string]*File),
+		macroLookup:   make(map[string][]*File),
+		packageMacros: make(map[string][]*File),
+		modelLookup:   make(map[string]*File),
+		schemas:       make(map[string]*SchemaFile),
+		tests:         make(map[string]*File),
+		seeds:         make(map[string]*SeedFile),
+		Docs:          make(map[string]*DocFile),
 	}
 
 	for filePath, contents := range models {
 		filePath = filepath.Clean(filePath)
 
-		file := newFile(filePath, ModelFile)
+		fileType := ModelFile
+		if fileTypeOf(filePath) == MacroFile {
+			fileType = MacroFile
+		}
+
+		file := newFile(filePath, fileType)
 		file.PrereadFileContents = contents
 
 		fs.files[filePath] = file
 
-		if err := fs.mapModelLookupOptions(file); err != nil {
+		if fileType == MacroFile {
+			if err := fs.mapMacroLookupOptions(file); err != nil {
+				return nil, err
+			}
+		} else if err := fs.mapModelLookupOptions(file); err != nil {
 			return nil, err
 		}
 	}
@@ -102,9 +121,9 @@ func InMemoryFileSystem(models map[string]string) (*FileSystem, error) {
 	return fs, nil
 }
 
-// Scan any macros in our dbt modules folder
-func (fs *FileSystem) scanDBTModuleMacros() error { //nolint:golint,unused
-	files, err := ioutil.ReadDir("./dbt_modules")
+// Scan the macros and models of the packages installed by `ddbt deps`, which are kept in their own namespaces
+func (fs *FileSystem) scanPackages() error {
+	folders, err := ioutil.ReadDir(config.PackagesFolder)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil
@@ -113,9 +132,20 @@ func (fs *FileSystem) scanDBTModuleMacros() error { //nolint:golint,unused
 		}
 	}
 
-	for _, f := range files {
-		if f.IsDir() {
-			if err := fs.scanDirectory("./dbt_modules/"+f.Name()+"/macros", MacroFile); err != nil {
+	for _, f := range folders {
+		// Hidden folders are packages which are part way through being installed
+		if !f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+
+		for folder, fileType := range map[string]FileType{"macros": MacroFile, "models": ModelFile} {
+			path := filepath.Join(config.PackagesFolder, f.Name(), folder)
+
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				continue
+			}
+
+			if err := fs.scanDirectory(path, fileType); err != nil {
 				return err
 			}
 		}
@@ -187,19 +217,67 @@ func (fs *FileSystem) recordDocFile(path string) error {
 	return nil
 }
 
-// Maps macros into our lookup options
+// Maps macros into our lookup options by their path and file name. Macros in packages are kept apart so they can
+// share names with the project's macros or those of other packages
 func (fs *FileSystem) mapMacroLookupOptions(file *File) error {
-	path := strings.TrimSuffix(filepath.Base(file.Path), ".sql")
+	if _, found := fs.macroFiles[file.Path]; found {
+		return errors.New("macro " + file.Path + " already in lookup")
+	}
+	fs.macroFiles[file.Path] = file
 
-	// Add the base path
-	if _, found := fs.macroLookup[path]; found {
-		return errors.New("macro " + path + " already in lookup")
+	name := file.MacroName()
+	fs.macroLookup[name] = append(fs.macroLookup[name], file)
+	sortFiles(fs.macroLookup[name])
+
+	if file.Package != "" {
+		fs.packageMacros[file.Package] = append(fs.packageMacros[file.Package], file)
+		sortFiles(fs.packageMacros[file.Package])
 	}
-	fs.macroLookup[path] = file
 
 	return nil
 }
 
+func sortFiles(files []*File) {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+}
+
+// Returns the package a file belongs to from its path (i.e. "dbt_packages/my_package/macros/x.sql" is in
+// "my_package"), or an empty string if it is part of the project
+func packageOf(path string) string {
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
+
+	if len(parts) > 2 && parts[0] == config.PackagesFolder {
+		return parts[1]
+	}
+
+	return ""
+}
+
+// Returns the type of file found at a path within the project or a package
+func fileTypeOf(path string) FileType {
+	path = filepath.ToSlash(filepath.Clean(path))
+
+	if pkg := packageOf(path); pkg != "" {
+		path = strings.SplitN(path, "/", 3)[2]
+	}
+
+	switch {
+	case strings.HasPrefix(path, "macros"):
+		return MacroFile
+
+	case strings.HasPrefix(path, "models"):
+		return ModelFile
+
+	case strings.HasPrefix(path, "tests"):
+		return TestFile
+
+	default:
+		return UnknownFile
+	}
+}
+
 func (fs *FileSystem) scanSeedDirectory(path string) error {
 	if _, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
@@ -271,6 +349,18 @@ func (fs *FileSystem) recordSeedFile(path string) error {
 // Mapping all the possible ways we could try
 // and look up the file by partial paths
 func (fs *FileSystem) mapModelLookupOptions(file *File) error {
+	// The project's models take the place of any models in packages with the same name
+	if existing, found := fs.modelLookup[file.Name]; found && existing.Package != file.Package {
+		switch {
+		case file.Package == "":
+			fs.removeModel(existing)
+
+		case existing.Package == "":
+			delete(fs.files, file.Path)
+			return nil
+		}
+	}
+
 	path := strings.TrimSuffix(file.Path, ".sql")
 
 	// Add the base path
@@ -290,9 +380,25 @@ func (fs *FileSystem) mapModelLookupOptions(file *File) error {
 		fs.modelLookup[path] = file
 	}
 
+	// Models in packages can also be found with their package, as with `ref('my_package', 'my_model')`
+	if file.Package != "" {
+		fs.modelLookup[file.Package+"."+file.Name] = file
+	}
+
 	return nil
 }
 
+// Removes a model from the file system, such as a package's model which the project replaces
+func (fs *FileSystem) removeModel(file *File) {
+	delete(fs.files, file.Path)
+
+	for path, model := range fs.modelLookup {
+		if model == file {
+			delete(fs.modelLookup, path)
+		}
+	}
+}
+
 // Map tests into our lookup options
 func (fs *FileSystem) mapTestLookupOptions(file *File) error {
 	fs.tests[file.Name] = file
@@ -314,7 +420,7 @@ func (fs *FileSystem) Model(name string) *File {
 
 // Returns a list of all the files
 func (fs *FileSystem) Models() []*File {
-	models := make([]*File, 0, len(fs.files)-len(fs.macroLookup))
+	models := make([]*File, 0, len(fs.files)-len(fs.macroFiles))
 
 	for _, file := range fs.files {
 		if file.Type == ModelFile {
@@ -325,24 +431,67 @@ func (fs *FileSystem) Models() []*File {
 	return models
 }
 
-// Returns a macro by name
+// Returns a macro by its file name, or nil if there is no such file or more than one. Macros in packages are named with
+// their package (i.e. "my_package.my_macro")
 func (fs *FileSystem) Macro(name string) *File {
+	if files := fs.macroLookup[name]; len(files) == 1 {
+		return files[0]
+	}
+
+	return nil
+}
+
+// Returns every macro file with the given file name, sorted by path
+func (fs *FileSystem) MacrosNamed(name string) []*File {
 	return fs.macroLookup[name]
 }
 
-// Returns a list of macros
+// Returns the macro file at the given path, which can be relative to the macros folder, or just the file name
+func (fs *FileSystem) MacroAt(path string) *File {
+	path = filepath.Clean(path)
+	if filepath.Ext(path) != ".sql" {
+		path += ".sql"
+	}
+
+	for _, option := range []string{path, filepath.Join("macros", path)} {
+		if file, found := fs.macroFiles[option]; found {
+			return file
+		}
+	}
+
+	return fs.Macro(strings.TrimSuffix(filepath.Base(path), ".sql"))
+}
+
+// Returns a list of macros, including those in packages
 func (fs *FileSystem) Macros() []*File {
-	macros := make([]*File, 0, len(fs.macroLookup))
-	for _, macro := range fs.macroLookup {
+	macros := make([]*File, 0, len(fs.macroFiles))
+	for _, macro := range fs.macroFiles {
 		macros = append(macros, macro)
 	}
 
 	return macros
 }
 
+// Returns the names of the packages with macros, sorted by name
+func (fs *FileSystem) Packages() []string {
+	packages := make([]string, 0, len(fs.packageMacros))
+	for name := range fs.packageMacros {
+		packages = append(packages, name)
+	}
+
+	sort.Strings(packages)
+
+	return packages
+}
+
+// Returns the macro files of a package, sorted by path, or nil if the package has no macros
+func (fs *FileSystem) PackageMacros(packageName string) []*File {
+	return fs.packageMacros[packageName]
+}
+
 // Adds a virtual macro file to the file system with the provided contents
 func (fs *FileSystem) AddMacroWithContents(fileName string, contents string) (*File, error) {
-	file := newFile(fmt.Sprintf("§VIRTUAL§/%s.sql", fileName), MacroFile)
+	file := newFile(fmt.Sprintf("%s/%s.sql", virtualFolder, fileName), MacroFile)
 	file.PrereadFileContents = contents
 
 	if err := fs.mapMacroLookupOptions(file); err != nil {
@@ -378,7 +527,7 @@ func (fs *FileSystem) AddTestWithContents(testName string, content string, isSch
 		return nil, fmt.Errorf("test %s already exists", testName)
 	}
 
-	file := newFile(fmt.Sprintf("§VIRTUAL§/%s.sql", testName), TestFile)
+	file := newFile(fmt.Sprintf("%s/%s.sql", virtualFolder, testName), TestFile)
 	fs.tests[testName] = file
 
 	file.PrereadFileContents = content
@@ -406,16 +555,7 @@ func (fs *FileSystem) File(path string, info os.FileInfo) (*File, error) {
 			return nil, nil
 		}
 
-		fileType := UnknownFile
-		if strings.HasPrefix(path, "macros") {
-			fileType = MacroFile
-		} else if strings.HasPrefix(path, "models") {
-			fileType = ModelFile
-		} else if strings.HasPrefix(path, "tests") {
-			fileType = TestFile
-		}
-
-		if err := fs.recordSQLFile(path, fileType); err != nil {
+		if err := fs.recordSQLFile(path, fileTypeOf(path)); err != nil {
 			return nil, err
 		}
 
@@ -435,6 +575,20 @@ func (fs *FileSystem) AllSchemas() []*SchemaFile {
 	return schemas
 }
 
+// Seed returns the seed with the given name, or nil if there isn't one
+func (fs *FileSystem) Seed(name string) *SeedFile {
+	return fs.seeds[name]
+}
+
+// Adds a seed file to the file system
+func (fs *FileSystem) AddSeedFile(path string) (*SeedFile, error) {
+	if err := fs.recordSeedFile(path); err != nil {
+		return nil, err
+	}
+
+	return fs.seeds[strings.TrimSuffix(filepath.Base(path), ".csv")], nil
+}
+
 func (fs *FileSystem) Seeds() []*SeedFile {
 	seeds := make([]*SeedFile, 0, len(fs.seeds))
 
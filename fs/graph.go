@@ -371,6 +371,91 @@ func (g *Graph) Execute(f func(file *File) error, numWorkers int, pb *utils.Prog
 	return firstErr
 }
 
+// ExecuteSkippingDownstreams runs every node in the graph like Execute, however when a node fails only the
+// nodes downstream of it are skipped, rather than stopping the whole graph.
+//
+// Returns the error of every node which failed and the nodes which were skipped
+func (g *Graph) ExecuteSkippingDownstreams(f func(file *File) error, numWorkers int, pb *utils.ProgressBar) (map[*File]error, []*File) {
+	var wait sync.WaitGroup
+
+	countOfUnqueued := g.NumberNodesNeedRerunning()
+	c := make(chan *Node, countOfUnqueued)
+
+	wait.Add(countOfUnqueued)
+
+	var resultsMutex sync.Mutex
+	failed := make(map[*File]error)
+	skipped := make([]*File, 0)
+
+	worker := func() {
+		statusRow := pb.NewStatusRow()
+
+		for node := range c {
+			statusRow.Update(fmt.Sprintf("Running %s", node.file.Name))
+
+			if err := f(node.file); err != nil {
+				resultsMutex.Lock()
+				failed[node.file] = err
+
+				for _, downstream := range node.skipDownstreams() {
+					skipped = append(skipped, downstream.file)
+					wait.Done()
+				}
+				resultsMutex.Unlock()
+			} else {
+				node.markNodeAsRun(c)
+			}
+
+			wait.Done()
+			statusRow.SetIdle()
+		}
+	}
+
+	for i := 0; i < numWorkers; i++ {
+		go worker()
+	}
+
+	for _, nodes := range g.nodes {
+		if nodes.allUpstreamsReady() {
+			nodes.queueForRun(c)
+		}
+	}
+
+	wait.Wait()
+	close(c)
+
+	return failed, skipped
+}
+
+// AddTestsAsGates makes the downstreams of every model in the graph wait on the tests of that model,
+// so a failing test stops its downstreams from running. Should be called after AddReferencingTests
+func (g *Graph) AddTestsAsGates() {
+	for _, testNode := range g.nodes {
+		if testNode.file.Type != TestFile {
+			continue
+		}
+
+		for modelNode := range testNode.upstreamNodes {
+			if modelNode.file.Type != ModelFile {
+				continue
+			}
+
+			for downstream := range modelNode.downstreamNodes {
+				if downstream.file.Type == TestFile {
+					continue
+				}
+
+				// If the test also references this downstream, gating it on the test would create a cycle
+				if downstream == testNode || testNode.upstreamContains(downstream) {
+					continue
+				}
+
+				g.edge(testNode, downstream)
+			}
+		}
+	}
+}
+
 func (n *Node) upstreamContains(other *Node) bool {
 	n.mutex.RLock()
 	defer n.mutex.RUnlock()
@@ -448,6 +533,34 @@ func (n *Node) markNodeAsRun(c chan *Node) {
 	}
 }
 
+// Marks every node downstream of this one, which is not yet queued to run, as never needing to run
+// and returns them
+func (n *Node) skipDownstreams() []*Node {
+	n.mutex.RLock()
+	downstreams := make([]*Node, 0, len(n.downstreamNodes))
+	for downstream := range n.downstreamNodes {
+		downstreams = append(downstreams, downstream)
+	}
+	n.mutex.RUnlock()
+
+	skipped := make([]*Node, 0)
+
+	for _, downstream := range downstreams {
+		downstream.mutex.Lock()
+		if downstream.queuedToRun {
+			downstream.mutex.Unlock()
+			continue
+		}
+		downstream.queuedToRun = true
+		downstream.mutex.Unlock()
+
+		skipped = append(skipped, downstream)
+		skipped = append(skipped, downstream.skipDownstreams()...)
+	}
+
+	return skipped
+}
+
 func (g *Graph) MarkGraphAsFullyRun() {
 	for _, node := range g.nodes {
 		node.queuedToRun = true
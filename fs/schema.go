@@ -45,8 +45,19 @@ func (s *SchemaFile) Parse(fs *FileSystem) error {
 		return err
 	}
 
+	// Schemas in packages describe the package's models, which may have been replaced by the project's own
+	pkg := packageOf(s.Path)
+
 	// Now attach it to the various models it references
 	for _, modelSchema := range s.Properties.Models {
+		if pkg != "" {
+			if model := fs.Model(pkg + "." + modelSchema.Name); model != nil {
+				model.Schema = modelSchema
+			}
+
+			continue
+		}
+
 		model := fs.Model(modelSchema.Name)
 		if model == nil {
 			model = fs.Test(modelSchema.Name)
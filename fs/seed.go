@@ -1,11 +1,14 @@
 package fs
 
 import (
+	"crypto/md5" //nolint:gosec
 	"encoding/csv"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"ddbt/config"
@@ -17,13 +20,22 @@ type SeedFile struct {
 	Path        string
 	Columns     []string
 	ColumnTypes map[string]string
+
+	File *File // represents this seed in the graph, so models can depend on it
 }
 
 func newSeedFile(path string) *SeedFile {
-	return &SeedFile{
+	seed := &SeedFile{
 		Name: strings.TrimSuffix(filepath.Base(path), ".csv"),
 		Path: path,
 	}
+
+	seed.File = newFile(path, SeedNode)
+	seed.File.Name = seed.Name
+	seed.File.FolderConfig.Materialized = "seed"
+	seed.File.Seed = seed
+
+	return seed
 }
 
 func (s *SeedFile) GetName() string {
@@ -91,6 +103,38 @@ func (s *SeedFile) readColumnTypes() error {
 	return nil
 }
 
+// Hash returns a hash of the seed's CSV file and configured column types, which changes whenever the seed
+// needs to be reloaded
+func (s *SeedFile) Hash() (string, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	cfg, err := s.GetConfig()
+	if err != nil {
+		return "", err
+	}
+
+	columns := make([]string, 0, len(cfg.ColumnTypes))
+	for column := range cfg.ColumnTypes {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	for _, column := range columns {
+		_, _ = fmt.Fprintf(h, "\n%s:%s", column, cfg.ColumnTypes[column])
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 func (s *SeedFile) HasSchema() bool {
 	return s.ColumnTypes != nil
 }
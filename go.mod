@@ -3,6 +3,7 @@ module ddbt
 go 1.16
 
 require (
+	cloud.google.com/go v0.60.0
 	cloud.google.com/go/bigquery v1.10.0
 	github.com/atotto/clipboard v0.1.2
 	github.com/fsnotify/fsnotify v1.4.9
@@ -138,7 +138,11 @@ func writeValue(ec compilerInterface.ExecutionContext, part compilerInterface.AS
 		builder.WriteRune('}')
 
 	case compilerInterface.Undefined, compilerInterface.NullVal:
-	// no-op as we can consume these without effect
+		// Within a list or map these are rendered as Python would, so they aren't lost (i.e. `[None]`), otherwise
+		// this is a no-op as we can consume these without effect
+		if wrapAndEscape {
+			builder.WriteString("None")
+		}
 
 	default:
 		return ec.ErrorAt(
@@ -0,0 +1,67 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+
+	"ddbt/compilerInterface"
+	"ddbt/jinja/lexer"
+)
+
+// A `{% filter upper %}...{% endfilter %}` block, which applies filters to the rendered body
+type FilterBlock struct {
+	position lexer.Position
+	filters  []*FunctionCall
+	body     *Body
+}
+
+var _ AST = &FilterBlock{}
+
+func NewFilterBlock(token *lexer.Token, filters []*FunctionCall) *FilterBlock {
+	return &FilterBlock{
+		position: token.Start,
+		filters:  filters,
+		body:     NewBody(token),
+	}
+}
+
+func (fb *FilterBlock) Position() lexer.Position {
+	return fb.position
+}
+
+func (fb *FilterBlock) Execute(ec compilerInterface.ExecutionContext) (*compilerInterface.Value, error) {
+	result, err := fb.body.Execute(ec)
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil {
+		return nil, ec.NilResultFor(fb.body)
+	}
+
+	for _, filter := range fb.filters {
+		result, err = filter.ExecuteFilterOn(ec, result.Unwrap())
+		if err != nil {
+			return nil, err
+		}
+
+		if result == nil {
+			return nil, ec.NilResultFor(filter)
+		}
+	}
+
+	return result, nil
+}
+
+func (fb *FilterBlock) String() string {
+	filters := make([]string, len(fb.filters))
+	for i, filter := range fb.filters {
+		filters[i] = filter.name
+	}
+
+	return fmt.Sprintf("{%% filter %s %%}%s{%% endfilter %%}", strings.Join(filters, " | "), fb.body.String())
+}
+
+func (fb *FilterBlock) AppendBody(node AST) {
+	fb.body.Append(node)
+}
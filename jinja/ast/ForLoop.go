@@ -14,8 +14,10 @@ type ForLoop struct {
 	position     lexer.Position
 	keyItrName   string
 	valueItrName string
-	list         *Variable
+	list         AST
+	filter       AST
 	body         *Body
+	isRecursive  bool
 }
 
 type ForLoopParameter struct {
@@ -25,7 +27,7 @@ type ForLoopParameter struct {
 
 var _ AST = &ForLoop{}
 
-func NewForLoop(valueItrToken *lexer.Token, keyItr string, list *Variable) *ForLoop {
+func NewForLoop(valueItrToken *lexer.Token, keyItr string, list AST) *ForLoop {
 	return &ForLoop{
 		position:     valueItrToken.Start,
 		keyItrName:   keyItr,
@@ -53,35 +55,55 @@ func (fl *ForLoop) Execute(ec compilerInterface.ExecutionContext) (*compilerInte
 		list = list.ReturnValue
 	}
 
+	return fl.iterate(ec, list, 1)
+}
+
+// Runs the loop over a list or map; recursive loops call this again for each `loop(children)` with a greater depth
+func (fl *ForLoop) iterate(ec compilerInterface.ExecutionContext, list *compilerInterface.Value, depth int) (*compilerInterface.Value, error) {
+	list = list.Unwrap()
+
 	switch list.Type() {
 	case compilerInterface.ListVal:
-		return fl.executeForList(list.ListValue, ec)
+		return fl.executeForList(list.ListValue, ec, depth)
 
 	case compilerInterface.MapVal:
-		return fl.executeForMap(list.MapValue, ec)
+		return fl.executeForMap(list.MapValue, ec, depth)
 
 	default:
 		return nil, ec.ErrorAt(fl, fmt.Sprintf("unable to run for each over %s", list.Type()))
 	}
 }
 
-func (fl *ForLoop) executeForList(list []*compilerInterface.Value, parentEC compilerInterface.ExecutionContext) (*compilerInterface.Value, error) {
+func (fl *ForLoop) executeForList(list []*compilerInterface.Value, parentEC compilerInterface.ExecutionContext, depth int) (*compilerInterface.Value, error) {
 	var builder strings.Builder
 
+	// Like Jinja, the filter is applied before looping so `loop` only describes the items which are kept
+	if fl.filter != nil {
+		filtered := make([]*compilerInterface.Value, 0, len(list))
+
+		for index, value := range list {
+			ec := parentEC.PushState()
+			fl.setListIterators(ec, index, value)
+
+			keep, err := fl.passesFilter(ec)
+			if err != nil {
+				return nil, err
+			}
+
+			if keep {
+				filtered = append(filtered, value)
+			}
+		}
+
+		list = filtered
+	}
+
 	for index, value := range list {
 		ec := parentEC.PushState()
 
 		// Set the loop variables
-		ec.SetVariable("loop", &compilerInterface.Value{
-			MapValue: map[string]*compilerInterface.Value{
-				"index": compilerInterface.NewNumber(float64(index + 1)), // Python loops start at 1!!!
-				"last":  compilerInterface.NewBoolean(index == (len(list) - 1)),
-			},
-		})
-		if fl.keyItrName != "" {
-			ec.SetVariable(fl.keyItrName, compilerInterface.NewNumber(float64(index)))
-		}
-		ec.SetVariable(fl.valueItrName, value)
+		ec.SetVariable("loop", fl.loopVariable(parentEC, list, index, depth))
+		fl.setListIterators(ec, index, value)
 
 		result, err := fl.body.Execute(ec)
 		if err != nil {
@@ -96,11 +118,9 @@ func (fl *ForLoop) executeForList(list []*compilerInterface.Value, parentEC comp
 	return &compilerInterface.Value{StringValue: builder.String()}, nil
 }
 
-func (fl *ForLoop) executeForMap(list map[string]*compilerInterface.Value, parentEC compilerInterface.ExecutionContext) (*compilerInterface.Value, error) {
+func (fl *ForLoop) executeForMap(list map[string]*compilerInterface.Value, parentEC compilerInterface.ExecutionContext, depth int) (*compilerInterface.Value, error) {
 	var builder strings.Builder
 
-	num := 0
-
 	// Sort keys so this loop excutes stably (i.e. the order doesn't change each time)
 	keys := make([]string, 0, len(list))
 	for key := range list {
@@ -108,20 +128,37 @@ func (fl *ForLoop) executeForMap(list map[string]*compilerInterface.Value, paren
 	}
 	sort.Strings(keys)
 
+	if fl.filter != nil {
+		filtered := make([]string, 0, len(keys))
+
+		for _, key := range keys {
+			ec := parentEC.PushState()
+			fl.setMapIterators(ec, key, list[key])
+
+			keep, err := fl.passesFilter(ec)
+			if err != nil {
+				return nil, err
+			}
+
+			if keep {
+				filtered = append(filtered, key)
+			}
+		}
+
+		keys = filtered
+	}
+
+	keyValues := make([]*compilerInterface.Value, len(keys))
+	for i, key := range keys {
+		keyValues[i] = compilerInterface.NewString(key)
+	}
+
 	for index, key := range keys {
 		ec := parentEC.PushState()
 
 		// Set the loop variables
-		ec.SetVariable("loop", &compilerInterface.Value{
-			MapValue: map[string]*compilerInterface.Value{
-				"last": compilerInterface.NewBoolean(index == (len(list) - 1)),
-			},
-		})
-
-		if fl.keyItrName != "" {
-			ec.SetVariable(fl.keyItrName, compilerInterface.NewString(key))
-		}
-		ec.SetVariable(fl.valueItrName, list[key])
+		ec.SetVariable("loop", fl.loopVariable(parentEC, keyValues, index, depth))
+		fl.setMapIterators(ec, key, list[key])
 
 		result, err := fl.body.Execute(ec)
 		if err != nil {
@@ -131,21 +168,114 @@ func (fl *ForLoop) executeForMap(list map[string]*compilerInterface.Value, paren
 		if err := writeValue(ec, fl.body, &builder, result, false); err != nil {
 			return nil, err
 		}
-
-		num++
 	}
 
 	return &compilerInterface.Value{StringValue: builder.String()}, nil
 }
 
+func (fl *ForLoop) setListIterators(ec compilerInterface.ExecutionContext, index int, value *compilerInterface.Value) {
+	if fl.keyItrName != "" {
+		if value.IsTuple && len(value.ListValue) == 2 {
+			// Pairs, such as those from the `dictsort` and `items` filters, are unpacked like Python does
+			ec.SetVariable(fl.keyItrName, value.ListValue[0])
+			value = value.ListValue[1]
+		} else {
+			ec.SetVariable(fl.keyItrName, compilerInterface.NewNumber(float64(index)))
+		}
+	}
+	ec.SetVariable(fl.valueItrName, value)
+}
+
+func (fl *ForLoop) setMapIterators(ec compilerInterface.ExecutionContext, key string, value *compilerInterface.Value) {
+	if fl.keyItrName != "" {
+		ec.SetVariable(fl.keyItrName, compilerInterface.NewString(key))
+	}
+	ec.SetVariable(fl.valueItrName, value)
+}
+
+// Does the current item pass the loop's filter (`{% for x in xs if x > 1 %}`)?
+func (fl *ForLoop) passesFilter(ec compilerInterface.ExecutionContext) (bool, error) {
+	result, err := fl.filter.Execute(ec)
+	if err != nil {
+		return false, err
+	}
+	if result == nil {
+		return false, ec.NilResultFor(fl.filter)
+	}
+
+	return result.TruthyValue(), nil
+}
+
+// The `loop` variable of an iteration, as described in https://jinja.palletsprojects.com/en/2.11.x/templates/#for
+func (fl *ForLoop) loopVariable(parentEC compilerInterface.ExecutionContext, items []*compilerInterface.Value, index int, depth int) *compilerInterface.Value {
+	length := len(items)
+
+	properties := map[string]*compilerInterface.Value{
+		"index":     compilerInterface.NewNumber(float64(index + 1)), // Python loops start at 1!!!
+		"index0":    compilerInterface.NewNumber(float64(index)),
+		"revindex":  compilerInterface.NewNumber(float64(length - index)),
+		"revindex0": compilerInterface.NewNumber(float64(length - index - 1)),
+		"first":     compilerInterface.NewBoolean(index == 0),
+		"last":      compilerInterface.NewBoolean(index == (length - 1)),
+		"length":    compilerInterface.NewNumber(float64(length)),
+		"depth":     compilerInterface.NewNumber(float64(depth)),
+		"depth0":    compilerInterface.NewNumber(float64(depth - 1)),
+
+		"cycle": compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+			if len(args) == 0 {
+				return nil, ec.ErrorAt(caller, "no items for cycling given")
+			}
+
+			return args[index%len(args)].Value, nil
+		}),
+	}
+
+	if index > 0 {
+		properties["previtem"] = items[index-1]
+	}
+	if index < length-1 {
+		properties["nextitem"] = items[index+1]
+	}
+
+	if !fl.isRecursive {
+		return compilerInterface.NewMap(properties)
+	}
+
+	// In recursive loops, `loop(children)` runs the loop again over the children
+	loop := compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+		if len(args) != 1 {
+			return nil, ec.ErrorAt(caller, fmt.Sprintf("loop expects 1 argument, got %d", len(args)))
+		}
+
+		return fl.iterate(parentEC, args[0].Value, depth+1)
+	})
+	loop.MapValue = properties
+
+	return loop
+}
+
 func (fl *ForLoop) String() string {
+	list := fl.list.String()
+	if fl.filter != nil {
+		list += " if " + fl.filter.String()
+	}
+
 	if fl.keyItrName != "" {
-		return fmt.Sprintf("\n{%% for %s, %s in %s %%}%s{%% endfor %%}", fl.keyItrName, fl.valueItrName, fl.list.String(), fl.body.String())
+		return fmt.Sprintf("\n{%% for %s, %s in %s %%}%s{%% endfor %%}", fl.keyItrName, fl.valueItrName, list, fl.body.String())
 	} else {
-		return fmt.Sprintf("\n{%% for %s in %s %%}%s{%% endfor %%}", fl.valueItrName, fl.list.String(), fl.body.String())
+		return fmt.Sprintf("\n{%% for %s in %s %%}%s{%% endfor %%}", fl.valueItrName, list, fl.body.String())
 	}
 }
 
+// Only loop over the items for which the filter is true
+func (fl *ForLoop) SetFilter(filter AST) {
+	fl.filter = filter
+}
+
+func (fl *ForLoop) SetRecursive() {
+	fl.isRecursive = true
+}
+
 func (fl *ForLoop) AppendBody(node AST) {
 	fl.body.Append(node)
 }
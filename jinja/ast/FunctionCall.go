@@ -12,6 +12,7 @@ type FunctionCall struct {
 	position  lexer.Position
 	name      string
 	arguments funcCallArgs
+	isFilter  bool
 }
 
 type funcCallArg struct {
@@ -31,6 +32,15 @@ func NewFunctionCall(token *lexer.Token, funcName string) *FunctionCall {
 	}
 }
 
+// NewFilterCall creates a call of a filter, such as `a | join(', ')`, which is called with the filtered value as
+// its first argument
+func NewFilterCall(token *lexer.Token, filterName string) *FunctionCall {
+	fc := NewFunctionCall(token, filterName)
+	fc.isFilter = true
+
+	return fc
+}
+
 func (fc *FunctionCall) Position() lexer.Position {
 	return fc.position
 }
@@ -41,9 +51,31 @@ func (fc *FunctionCall) Execute(ec compilerInterface.ExecutionContext) (*compile
 		return nil, err
 	}
 
-	function := ec.GetVariable(fc.name)
-	if function.IsUndefined {
-		return nil, ec.ErrorAt(fc, fmt.Sprintf("function `%s` not found", fc.name))
+	return fc.call(ec, args)
+}
+
+// ExecuteFilterOn calls the filter on the value, as `value | filter(args...)` would; such as for `{% filter %}` blocks
+func (fc *FunctionCall) ExecuteFilterOn(ec compilerInterface.ExecutionContext, value *compilerInterface.Value) (*compilerInterface.Value, error) {
+	args, err := fc.arguments.Execute(ec)
+	if err != nil {
+		return nil, err
+	}
+
+	return fc.call(ec, append(compilerInterface.Arguments{{Value: value}}, args...))
+}
+
+func (fc *FunctionCall) call(ec compilerInterface.ExecutionContext, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
+	var function *compilerInterface.Value
+	if fc.isFilter {
+		function = ec.GetFilter(fc.name)
+		if function.IsUndefined {
+			return nil, ec.ErrorAt(fc, fmt.Sprintf("filter `%s` not found", fc.name))
+		}
+	} else {
+		function = ec.GetVariable(fc.name)
+		if function.IsUndefined {
+			return nil, ec.ErrorAt(fc, fmt.Sprintf("function `%s` not found", fc.name))
+		}
 	}
 
 	if function.Type() != compilerInterface.FunctionalVal && function.Function == nil {
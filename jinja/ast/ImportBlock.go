@@ -0,0 +1,101 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+
+	"ddbt/compilerInterface"
+	"ddbt/jinja/lexer"
+)
+
+// An `{% import 'file.sql' as helpers %}` or `{% from 'file.sql' import a, b as c %}` block
+type ImportBlock struct {
+	position lexer.Position
+	path     AST
+	alias    string         // The name all the macros are imported as
+	names    []importedName // Or the macros which are imported by name
+}
+
+type importedName struct {
+	name  string
+	alias string
+}
+
+var _ AST = &ImportBlock{}
+
+func NewImportBlock(token *lexer.Token, path AST, alias string) *ImportBlock {
+	return &ImportBlock{
+		position: token.Start,
+		path:     path,
+		alias:    alias,
+	}
+}
+
+func NewFromImportBlock(token *lexer.Token, path AST) *ImportBlock {
+	return &ImportBlock{
+		position: token.Start,
+		path:     path,
+		names:    make([]importedName, 0),
+	}
+}
+
+func (ib *ImportBlock) Position() lexer.Position {
+	return ib.position
+}
+
+func (ib *ImportBlock) Execute(ec compilerInterface.ExecutionContext) (*compilerInterface.Value, error) {
+	path, err := ib.path.Execute(ec)
+	if err != nil {
+		return nil, err
+	}
+
+	if path == nil {
+		return nil, ec.NilResultFor(ib.path)
+	}
+
+	macros, err := ec.ImportMacros(path.AsStringValue())
+	if err != nil {
+		return nil, ec.ErrorAt(ib, err.Error())
+	}
+
+	if ib.alias != "" {
+		ec.SetVariable(ib.alias, macros)
+	}
+
+	for _, name := range ib.names {
+		macro, found := macros.MapValue[name.name]
+		if !found {
+			return nil, ec.ErrorAt(ib, fmt.Sprintf("the macro `%s` is not defined in %s", name.name, path.AsStringValue()))
+		}
+
+		ec.SetVariable(name.alias, macro)
+	}
+
+	return &compilerInterface.Value{IsUndefined: true}, nil
+}
+
+func (ib *ImportBlock) String() string {
+	if ib.alias != "" {
+		return fmt.Sprintf("{%% import %s as %s %%}", ib.path.String(), ib.alias)
+	}
+
+	names := make([]string, len(ib.names))
+	for i, name := range ib.names {
+		if name.alias != name.name {
+			names[i] = fmt.Sprintf("%s as %s", name.name, name.alias)
+		} else {
+			names[i] = name.name
+		}
+	}
+
+	return fmt.Sprintf("{%% from %s import %s %%}", ib.path.String(), strings.Join(names, ", "))
+}
+
+// Adds a macro to import by name, such as the `b as c` of `{% from 'file.sql' import a, b as c %}`
+func (ib *ImportBlock) AddName(name string, alias string) {
+	if alias == "" {
+		alias = name
+	}
+
+	ib.names = append(ib.names, importedName{name, alias})
+}
@@ -48,6 +48,28 @@ func (op *LogicalOp) Execute(ec compilerInterface.ExecutionContext) (*compilerIn
 
 	result := false
 
+	// Values which model Python objects, such as datetimes, compare themselves
+	if cmp, compared, err := op.compareObjects(ec, lhs, rhs); err != nil {
+		return nil, err
+	} else if compared {
+		switch op.op {
+		case lexer.IsEqualsToken:
+			result = cmp == 0
+		case lexer.NotEqualsToken:
+			result = cmp != 0
+		case lexer.LessThanToken:
+			result = cmp < 0
+		case lexer.LessThanEqualsToken:
+			result = cmp <= 0
+		case lexer.GreaterThanToken:
+			result = cmp > 0
+		case lexer.GreaterThanEqualsToken:
+			result = cmp >= 0
+		}
+
+		return compilerInterface.NewBoolean(result), nil
+	}
+
 	switch op.op {
 	case lexer.IsEqualsToken:
 		result = lhs.Equals(rhs)
@@ -56,6 +78,16 @@ func (op *LogicalOp) Execute(ec compilerInterface.ExecutionContext) (*compilerIn
 		result = !lhs.Equals(rhs)
 
 	case lexer.LessThanToken, lexer.LessThanEqualsToken, lexer.GreaterThanToken, lexer.GreaterThanEqualsToken:
+		// Strings which aren't numbers, such as dates, are compared in the same way as Python
+		if isNonNumericString(lhs) || isNonNumericString(rhs) {
+			if lhs.Unwrap().Type() != compilerInterface.StringVal || rhs.Unwrap().Type() != compilerInterface.StringVal {
+				return nil, ec.ErrorAt(op, fmt.Sprintf("Unable to compare a %s with a %s", lhs.Type(), rhs.Type()))
+			}
+
+			result = compareStrings(op.op, lhs.AsStringValue(), rhs.AsStringValue())
+			break
+		}
+
 		lhsNum, err := lhs.AsNumberValue()
 		if err != nil {
 			return nil, ec.ErrorAt(op.lhs, fmt.Sprintf("%s", err))
@@ -84,6 +116,57 @@ func (op *LogicalOp) Execute(ec compilerInterface.ExecutionContext) (*compilerIn
 	return compilerInterface.NewBoolean(result), nil
 }
 
+// Compares with `lhs.__cmp__(rhs)` if the lhs has such a method and can be compared with the rhs. As in Python, values
+// which can't be ordered, such as naive and aware datetimes, are simply not equal
+func (op *LogicalOp) compareObjects(ec compilerInterface.ExecutionContext, lhs, rhs *compilerInterface.Value) (cmp int, compared bool, err error) {
+	lhs = lhs.Unwrap()
+	if lhs.Type() != compilerInterface.StringVal {
+		return 0, false, nil
+	}
+
+	method, found := lhs.MapValue["__cmp__"]
+	if !found {
+		return 0, false, nil
+	}
+
+	result, err := method.Function(ec, op, compilerInterface.Arguments{{Value: rhs}})
+	if err != nil {
+		if op.op == lexer.IsEqualsToken || op.op == lexer.NotEqualsToken {
+			return 1, true, nil
+		}
+
+		return 0, false, err
+	}
+
+	if result.Type() != compilerInterface.NumberVal {
+		return 0, false, nil
+	}
+
+	return int(result.NumberValue), true, nil
+}
+
+func isNonNumericString(value *compilerInterface.Value) bool {
+	if value.Unwrap().Type() != compilerInterface.StringVal {
+		return false
+	}
+
+	_, err := value.AsNumberValue()
+	return err != nil
+}
+
+func compareStrings(op lexer.TokenType, lhs, rhs string) bool {
+	switch op {
+	case lexer.LessThanToken:
+		return lhs < rhs
+	case lexer.LessThanEqualsToken:
+		return lhs <= rhs
+	case lexer.GreaterThanToken:
+		return lhs > rhs
+	default:
+		return lhs >= rhs
+	}
+}
+
 func (op *LogicalOp) String() string {
 	return fmt.Sprintf("(%s %s %s)", op.lhs, op.op, op.rhs)
 }
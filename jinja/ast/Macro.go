@@ -39,7 +39,7 @@ func (m *Macro) Position() lexer.Position {
 }
 
 func (m *Macro) Execute(macroEC compilerInterface.ExecutionContext) (*compilerInterface.Value, error) {
-	macroEC.RegisterMacro(
+	err := macroEC.RegisterMacro(
 		m.name,
 		macroEC,
 		func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
@@ -110,6 +110,9 @@ func (m *Macro) Execute(macroEC compilerInterface.ExecutionContext) (*compilerIn
 			return result.Unwrap(), err
 		},
 	)
+	if err != nil {
+		return nil, macroEC.ErrorAt(m, err.Error())
+	}
 
 	return &compilerInterface.Value{IsUndefined: true}, nil
 }
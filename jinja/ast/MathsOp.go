@@ -36,10 +36,6 @@ func (op *MathsOp) Execute(ec compilerInterface.ExecutionContext) (*compilerInte
 	if lhs == nil {
 		return nil, ec.NilResultFor(op.lhs)
 	}
-	lhsNum, err := lhs.AsNumberValue()
-	if err != nil {
-		return nil, ec.ErrorAt(op.lhs, fmt.Sprintf("%s", err))
-	}
 
 	rhs, err := op.rhs.Execute(ec)
 	if err != nil {
@@ -48,6 +44,16 @@ func (op *MathsOp) Execute(ec compilerInterface.ExecutionContext) (*compilerInte
 	if rhs == nil {
 		return nil, ec.NilResultFor(op.rhs)
 	}
+
+	if method, other, found := op.operatorMethod(lhs, rhs); found {
+		return method.Function(ec, op, compilerInterface.Arguments{{Value: other}})
+	}
+
+	lhsNum, err := lhs.AsNumberValue()
+	if err != nil {
+		return nil, ec.ErrorAt(op.lhs, fmt.Sprintf("%s", err))
+	}
+
 	rhsNum, err := rhs.AsNumberValue()
 	if err != nil {
 		return nil, ec.ErrorAt(op.rhs, fmt.Sprintf("%s", err))
@@ -78,6 +84,37 @@ func (op *MathsOp) Execute(ec compilerInterface.ExecutionContext) (*compilerInte
 	return compilerInterface.NewNumber(result), nil
 }
 
+// Values which model Python objects, such as datetimes, implement operators as methods in the same way as Python; so
+// `a + b` calls `a.__add__(b)`, or `b.__radd__(a)` if `a` has no such method
+func (op *MathsOp) operatorMethod(lhs, rhs *compilerInterface.Value) (method *compilerInterface.Value, other *compilerInterface.Value, found bool) {
+	var name string
+
+	switch op.token.Type {
+	case lexer.PlusToken:
+		name = "add"
+	case lexer.MinusToken:
+		name = "sub"
+	case lexer.MultiplyToken:
+		name = "mul"
+	default:
+		return nil, nil, false
+	}
+
+	if lhs.Unwrap().Type() == compilerInterface.StringVal {
+		if method, found := lhs.Unwrap().MapValue["__"+name+"__"]; found {
+			return method, rhs, true
+		}
+	}
+
+	if rhs.Unwrap().Type() == compilerInterface.StringVal {
+		if method, found := rhs.Unwrap().MapValue["__r"+name+"__"]; found {
+			return method, lhs, true
+		}
+	}
+
+	return nil, nil, false
+}
+
 // The parse is a 1-token look ahead parser, so it will parse
 // `2 + 3 * 4 + 5` into `+(2, *(3, +(4, 5)))` when due to operator
 // precedence rules it should be `*(+(2, 3), +(4, 5))`.
@@ -10,6 +10,7 @@ import (
 type SetCall struct {
 	position      lexer.Position
 	variableToSet string
+	attribute     string // Set for `{% set ns.attribute = value %}`
 	condition     AST
 }
 
@@ -23,6 +24,14 @@ func NewSetCall(ident *lexer.Token, condition AST) *SetCall {
 	}
 }
 
+// NewSetAttributeCall creates a set of an attribute of a namespace, such as `{% set ns.found = true %}`
+func NewSetAttributeCall(ident *lexer.Token, attribute *lexer.Token, condition AST) *SetCall {
+	sc := NewSetCall(ident, condition)
+	sc.attribute = attribute.Value
+
+	return sc
+}
+
 func (sc *SetCall) Position() lexer.Position {
 	return sc.position
 }
@@ -37,11 +46,26 @@ func (sc *SetCall) Execute(ec compilerInterface.ExecutionContext) (*compilerInte
 		return nil, ec.NilResultFor(sc.condition)
 	}
 
-	ec.SetVariable(sc.variableToSet, result)
+	if sc.attribute != "" {
+		// Namespaces are maps, which are changed in place so that the change is visible outside of loops. Like Jinja,
+		// other maps can't be changed, as they may be shared (such as `target`) by every model being compiled
+		namespace := ec.GetVariable(sc.variableToSet).Unwrap()
+		if !namespace.IsNamespace() {
+			return nil, ec.ErrorAt(sc, fmt.Sprintf("can only set attributes of a namespace, `%s` is a %s", sc.variableToSet, namespace.Type()))
+		}
+
+		namespace.MapValue[sc.attribute] = result
+	} else {
+		ec.SetVariable(sc.variableToSet, result)
+	}
 
 	return &compilerInterface.Value{IsUndefined: true}, nil
 }
 
 func (sc *SetCall) String() string {
+	if sc.attribute != "" {
+		return fmt.Sprintf("{%% set %s.%s = %s %%}", sc.variableToSet, sc.attribute, sc.condition.String())
+	}
+
 	return fmt.Sprintf("{%% set %s = %s %%}", sc.variableToSet, sc.condition.String())
 }
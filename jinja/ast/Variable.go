@@ -12,8 +12,10 @@ type variableType = string
 
 const (
 	identVar          variableType = "IDENT"
+	valueVar          variableType = "VALUE"
 	propertyLookupVar variableType = "PROPERTY_LOOKUP"
 	indexLookupVar    variableType = "INDEX_LOOKUP"
+	sliceLookupVar    variableType = "SLICE_LOOKUP"
 	funcCallVar       variableType = "FUNC_CALL"
 )
 
@@ -23,7 +25,10 @@ type Variable struct {
 	subVariable *Variable
 
 	argCall         funcCallArgs
+	value           AST // The literal a value variable is, such as the string of `'a,b'.split(',')`
 	lookupKey       AST
+	sliceStop       AST
+	sliceStep       AST
 	isTemplateBlock bool
 }
 
@@ -37,6 +42,16 @@ func NewVariable(token *lexer.Token) *Variable {
 	}
 }
 
+// NewValueVariable creates a variable from a literal, so it can have methods called on it and be indexed into like
+// any other variable (i.e. `', '.join(list)` or `[1, 2, 3][-1]`)
+func NewValueVariable(token *lexer.Token, value AST) *Variable {
+	v := NewVariable(token)
+	v.varType = valueVar
+	v.value = value
+
+	return v
+}
+
 func (v *Variable) Position() lexer.Position {
 	return v.token.Start
 }
@@ -59,12 +74,26 @@ func (v *Variable) resolve(ec compilerInterface.ExecutionContext, isForFunctionC
 	case identVar:
 		return ec.GetVariable(v.token.Value), nil
 
+	case valueVar:
+		value, err := v.value.Execute(ec)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			return nil, ec.NilResultFor(v.value)
+		}
+
+		return value, nil
+
 	case indexLookupVar:
 		return v.resolveIndexLookup(ec, isForFunctionCall)
 
 	case propertyLookupVar:
 		return v.resolvePropertyLookup(ec, isForFunctionCall)
 
+	case sliceLookupVar:
+		return v.resolveSliceLookup(ec)
+
 	case funcCallVar:
 		return v.resolveFunctionCall(ec)
 
@@ -91,20 +120,39 @@ func (v *Variable) resolveIndexLookup(ec compilerInterface.ExecutionContext, isF
 	switch t {
 	case compilerInterface.ListVal:
 		lt := lookupKey.Type()
+
+		// Named lists can also be indexed by the name of an item
+		if lt == compilerInterface.StringVal && value.MapValue != nil {
+			if rtnValue, found := value.MapValue[lookupKey.StringValue]; found {
+				return rtnValue, nil
+			}
+
+			return nil, ec.ErrorAt(v.lookupKey, fmt.Sprintf("no item named `%s` in the list", lookupKey.StringValue))
+		}
+
 		if lt != compilerInterface.NumberVal && !(lookupKey.Type() == compilerInterface.StringVal && lookupKey.StringValue == "") {
 			return nil, ec.ErrorAt(v.lookupKey, fmt.Sprintf("Number required to index into a list, got %s", lt))
 		}
 
-		index := int(lookupKey.NumberValue)
+		index, err := v.sequenceIndex(ec, lookupKey, len(value.ListValue))
+		if err != nil {
+			return nil, err
+		}
+
+		return value.ListValue[index], nil
 
-		if index < 0 {
-			return nil, ec.ErrorAt(v.lookupKey, fmt.Sprintf("index below 0, got: %d", index))
+	case compilerInterface.StringVal:
+		if lookupKey.Type() != compilerInterface.NumberVal {
+			return nil, ec.ErrorAt(v.lookupKey, fmt.Sprintf("Number required to index into a string, got %s", lookupKey.Type()))
 		}
-		if index >= len(value.ListValue) {
-			return nil, ec.ErrorAt(v.lookupKey, fmt.Sprintf("index larger than cap %d, got: %d", len(value.ListValue), index))
+
+		runes := []rune(value.StringValue)
+		index, err := v.sequenceIndex(ec, lookupKey, len(runes))
+		if err != nil {
+			return nil, err
 		}
 
-		return value.ListValue[index], nil
+		return compilerInterface.NewString(string(runes[index])), nil
 
 	case compilerInterface.MapVal:
 		lt := lookupKey.Type()
@@ -130,6 +178,128 @@ func (v *Variable) resolveIndexLookup(ec compilerInterface.ExecutionContext, isF
 	}
 }
 
+// Returns the index of a list or string, where negative indexes count back from the end as in Python
+func (v *Variable) sequenceIndex(ec compilerInterface.ExecutionContext, lookupKey *compilerInterface.Value, length int) (int, error) {
+	index := int(lookupKey.NumberValue)
+
+	if index < 0 {
+		index += length
+	}
+
+	if index < 0 || index >= length {
+		return 0, ec.ErrorAt(v.lookupKey, fmt.Sprintf("index out of range, got %d for a length of %d", int(lookupKey.NumberValue), length))
+	}
+
+	return index, nil
+}
+
+func (v *Variable) resolveSliceLookup(ec compilerInterface.ExecutionContext) (*compilerInterface.Value, error) {
+	value, err := v.subVariable.resolve(ec, false)
+	if err != nil {
+		return nil, err
+	}
+	value = value.Unwrap()
+
+	// Each part of the slice is optional
+	parts := make([]*int, 3)
+	for i, part := range []AST{v.lookupKey, v.sliceStop, v.sliceStep} {
+		if part == nil {
+			continue
+		}
+
+		result, err := part.Execute(ec)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			return nil, ec.NilResultFor(part)
+		}
+
+		result = result.Unwrap()
+		if result.IsUndefined || result.IsNull {
+			continue
+		}
+		if result.Type() != compilerInterface.NumberVal {
+			return nil, ec.ErrorAt(part, fmt.Sprintf("slice indices must be numbers, got %s", result.Type()))
+		}
+
+		index := int(result.NumberValue)
+		parts[i] = &index
+	}
+
+	if parts[2] != nil && *parts[2] == 0 {
+		return nil, ec.ErrorAt(v, "slice step cannot be zero")
+	}
+
+	switch value.Type() {
+	case compilerInterface.ListVal:
+		indexes := sliceIndexes(len(value.ListValue), parts[0], parts[1], parts[2])
+
+		list := make([]*compilerInterface.Value, len(indexes))
+		for i, index := range indexes {
+			list[i] = value.ListValue[index]
+		}
+
+		return compilerInterface.NewList(list), nil
+
+	case compilerInterface.StringVal:
+		runes := []rune(value.StringValue)
+		indexes := sliceIndexes(len(runes), parts[0], parts[1], parts[2])
+
+		sliced := make([]rune, len(indexes))
+		for i, index := range indexes {
+			sliced[i] = runes[index]
+		}
+
+		return compilerInterface.NewString(string(sliced)), nil
+
+	default:
+		return nil, ec.ErrorAt(v, fmt.Sprintf("unable to slice a %s", value.Type()))
+	}
+}
+
+// Returns the indexes a Python slice of a sequence with the given length selects
+func sliceIndexes(length int, start, stop, step *int) []int {
+	by := 1
+	if step != nil {
+		by = *step
+	}
+
+	// Negative indexes count from the end, and the bounds are clamped to the sequence
+	bound := func(index *int, defaultValue, lower, upper int) int {
+		if index == nil {
+			return defaultValue
+		}
+
+		i := *index
+		if i < 0 {
+			i += length
+		}
+
+		if i < lower {
+			return lower
+		}
+		if i > upper {
+			return upper
+		}
+		return i
+	}
+
+	indexes := make([]int, 0)
+
+	if by > 0 {
+		for i := bound(start, 0, 0, length); i < bound(stop, length, 0, length); i += by {
+			indexes = append(indexes, i)
+		}
+	} else {
+		for i := bound(start, length-1, -1, length-1); i > bound(stop, -1, -1, length-1); i += by {
+			indexes = append(indexes, i)
+		}
+	}
+
+	return indexes
+}
+
 func (v *Variable) resolvePropertyLookup(ec compilerInterface.ExecutionContext, isForFunctionCall bool) (*compilerInterface.Value, error) {
 	value, err := v.subVariable.resolve(ec, isForFunctionCall)
 	if err != nil {
@@ -143,39 +313,7 @@ func (v *Variable) resolvePropertyLookup(ec compilerInterface.ExecutionContext,
 
 	rtnValue, found := data[v.token.Value]
 	if !found {
-		if isForFunctionCall && v.token.Value == "items" {
-			// If we're asking for the items of a map, return the map back
-			return compilerInterface.NewFunction(func(_ compilerInterface.ExecutionContext, _ compilerInterface.AST, _ compilerInterface.Arguments) (*compilerInterface.Value, error) {
-				return value, nil
-			}), nil
-		} else if isForFunctionCall && v.token.Value == "get" {
-			return compilerInterface.NewFunction(func(ec compilerInterface.ExecutionContext, caller compilerInterface.AST, args compilerInterface.Arguments) (*compilerInterface.Value, error) {
-				defaultValue := compilerInterface.NewUndefined()
-
-				switch len(args) {
-				case 1:
-				case 2:
-					defaultValue = args[1].Value.Unwrap()
-				default:
-					return nil, ec.ErrorAt(caller, fmt.Sprintf("expected 1 or 2 arguments, got %d", len(args)))
-				}
-
-				key := args[0].Value.Unwrap()
-				keyStr := key.AsStringValue()
-				if keyStr == "" {
-					return nil, ec.ErrorAt(caller, "key in the call to get() cannot be blank")
-				}
-
-				v, found := data[keyStr]
-				if !found {
-					return defaultValue, nil
-				} else {
-					return v, nil
-				}
-			}), nil
-		} else {
-			return &compilerInterface.Value{IsUndefined: true}, nil
-		}
+		return &compilerInterface.Value{IsUndefined: true}, nil
 	}
 
 	return rtnValue, nil
@@ -219,6 +357,9 @@ func (v *Variable) String() string {
 	case identVar:
 		builder.WriteString(v.token.Value)
 
+	case valueVar:
+		builder.WriteString(v.value.String())
+
 	case propertyLookupVar:
 		builder.WriteString(v.subVariable.String())
 		builder.WriteRune('.')
@@ -230,6 +371,20 @@ func (v *Variable) String() string {
 		builder.WriteString(v.lookupKey.String())
 		builder.WriteRune(']')
 
+	case sliceLookupVar:
+		builder.WriteString(v.subVariable.String())
+		builder.WriteRune('[')
+		for i, part := range []AST{v.lookupKey, v.sliceStop, v.sliceStep} {
+			if i > 0 {
+				builder.WriteRune(':')
+			}
+
+			if part != nil {
+				builder.WriteString(part.String())
+			}
+		}
+		builder.WriteRune(']')
+
 	case funcCallVar:
 		builder.WriteString(v.subVariable.String())
 		builder.WriteRune('(')
@@ -282,6 +437,14 @@ func (v *Variable) AsIndexLookup(key AST) *Variable {
 	return nv
 }
 
+func (v *Variable) AsSliceLookup(start, stop, step AST) *Variable {
+	nv := v.wrap(sliceLookupVar)
+	nv.lookupKey = start
+	nv.sliceStop = stop
+	nv.sliceStep = step
+	return nv
+}
+
 func (v *Variable) AsPropertyLookup(key *lexer.Token) *Variable {
 	nv := v.wrap(propertyLookupVar)
 	nv.token = key
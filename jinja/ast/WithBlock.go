@@ -0,0 +1,72 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+
+	"ddbt/compilerInterface"
+	"ddbt/jinja/lexer"
+)
+
+// A `{% with a = 1 %}...{% endwith %}` block, whose variables (and any set within it) are only visible inside it
+type WithBlock struct {
+	position  lexer.Position
+	variables []withVariable
+	body      *Body
+}
+
+type withVariable struct {
+	name  string
+	value AST
+}
+
+var _ AST = &WithBlock{}
+
+func NewWithBlock(token *lexer.Token) *WithBlock {
+	return &WithBlock{
+		position:  token.Start,
+		variables: make([]withVariable, 0),
+		body:      NewBody(token),
+	}
+}
+
+func (wb *WithBlock) Position() lexer.Position {
+	return wb.position
+}
+
+func (wb *WithBlock) Execute(parentEC compilerInterface.ExecutionContext) (*compilerInterface.Value, error) {
+	ec := parentEC.PushState()
+
+	// Like Jinja, the values are evaluated in the outer scope
+	for _, variable := range wb.variables {
+		value, err := variable.value.Execute(parentEC)
+		if err != nil {
+			return nil, err
+		}
+
+		if value == nil {
+			return nil, parentEC.NilResultFor(variable.value)
+		}
+
+		ec.SetVariable(variable.name, value)
+	}
+
+	return wb.body.Execute(ec)
+}
+
+func (wb *WithBlock) String() string {
+	variables := make([]string, len(wb.variables))
+	for i, variable := range wb.variables {
+		variables[i] = fmt.Sprintf("%s = %s", variable.name, variable.value.String())
+	}
+
+	return fmt.Sprintf("{%% with %s %%}%s{%% endwith %%}", strings.Join(variables, ", "), wb.body.String())
+}
+
+func (wb *WithBlock) AddVariable(name string, value AST) {
+	wb.variables = append(wb.variables, withVariable{name, value})
+}
+
+func (wb *WithBlock) AppendBody(node AST) {
+	wb.body.Append(node)
+}
@@ -5,12 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"regexp"
 	"strings"
 	"unicode"
 )
 
 const tabSize = 4
 
+// The rest of the tags which open and close raw blocks, after their `{%`
+var (
+	rawOpenPattern  = regexp.MustCompile(`^(-?)\s*raw\s*(-?)%}`)
+	rawClosePattern = regexp.MustCompile(`^(-?)\s*endraw\s*(-?)%}`)
+)
+
 type lexer struct {
 	reader *bufio.Reader
 
@@ -24,6 +31,9 @@ type lexer struct {
 
 	// lexer state tracking
 	inBlock bool
+
+	// Tokens which have already been lexed, such as those of a raw block, and are yet to be returned
+	pending []*Token
 }
 
 func LexFile(path string, file io.Reader) ([]*Token, error) {
@@ -109,6 +119,12 @@ func (l *lexer) consumeWhitespace() error {
 }
 
 func (l *lexer) NextToken() (*Token, error) {
+	if len(l.pending) > 0 {
+		t := l.pending[0]
+		l.pending = l.pending[1:]
+		return t, nil
+	}
+
 	// Read the next rune
 	if err := l.readRune(); err != nil {
 		return nil, err
@@ -149,6 +165,10 @@ func (l *lexer) nextTextModeToken() (*Token, error) {
 
 	if l.currentRune == '{' &&
 		l.nextRune == '%' {
+		if tag := l.peekTag(rawOpenPattern); tag != nil {
+			return l.readRawBlock(tag)
+		}
+
 		// Swap to being in a block
 		l.inBlock = true
 
@@ -215,6 +235,86 @@ func (l *lexer) nextTextModeToken() (*Token, error) {
 	return l.newTokenWithValue(TextToken, buf.String()), nil
 }
 
+// Checks if the `{%` we're at opens a tag matching the pattern, returning the submatches of the tag if so
+func (l *lexer) peekTag(pattern *regexp.Regexp) []string {
+	// The reader is positioned after the `%`, and tags such as `{%- endraw -%}` are short
+	upcoming, _ := l.reader.Peek(64)
+
+	return pattern.FindStringSubmatch(string(upcoming))
+}
+
+// Reads a `{% raw %}...{% endraw %}` block, where the contents is passed through as text without being lexed, as
+// is needed for JavaScript UDFs and regexes which contain `{{` or `{%`
+func (l *lexer) readRawBlock(openTag []string) (*Token, error) {
+	openToken := l.blockOpenToken(openTag)
+
+	// Consume the `%` and the rest of the opening tag
+	if err := l.consumeTag(openTag); err != nil {
+		return nil, err
+	}
+	l.pending = append(l.pending, l.newTokenWithValue(IdentToken, "raw"), l.blockCloseToken(openTag))
+
+	var buf strings.Builder
+	for {
+		if err := l.readRune(); err != nil {
+			return nil, err
+		}
+
+		if l.currentRune == 0 {
+			return l.newTokenWithValue(ErrorToken, "raw"), errors.New("raw block is missing {% endraw %}")
+		}
+
+		if l.currentRune == '{' && l.nextRune == '%' {
+			if closeTag := l.peekTag(rawClosePattern); closeTag != nil {
+				if buf.Len() > 0 {
+					l.pending = append(l.pending, l.newTokenWithValue(TextToken, buf.String()))
+				}
+
+				l.tokenPosition = l.runePosition
+				l.pending = append(l.pending, l.blockOpenToken(closeTag))
+
+				if err := l.consumeTag(closeTag); err != nil {
+					return nil, err
+				}
+				l.pending = append(l.pending, l.newTokenWithValue(IdentToken, "endraw"), l.blockCloseToken(closeTag))
+
+				break
+			}
+		}
+
+		buf.WriteRune(l.currentRune)
+	}
+
+	return openToken, nil
+}
+
+// Consumes the `%` of a tag we're at the `{` of, and the rest of the tag matched by peekTag
+func (l *lexer) consumeTag(tag []string) error {
+	for i := 0; i <= len(tag[0]); i++ {
+		if err := l.readRune(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *lexer) blockOpenToken(tag []string) *Token {
+	if tag[1] == "-" {
+		return l.newToken(ExpressionBlockOpenTrim)
+	}
+
+	return l.newToken(ExpressionBlockOpen)
+}
+
+func (l *lexer) blockCloseToken(tag []string) *Token {
+	if tag[2] == "-" {
+		return l.newToken(ExpressionBlockCloseTrim)
+	}
+
+	return l.newToken(ExpressionBlockClose)
+}
+
 // Get the next Token out of the code block we're in
 func (l *lexer) nextBlockToken() (*Token, error) {
 	if err := l.consumeWhitespace(); err != nil {
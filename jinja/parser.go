@@ -175,6 +175,15 @@ func (p *parser) parseExpressionBlock() (ast.AST, error) {
 		return nil, err
 	}
 
+	// These blocks can be opened without any arguments, so must be checked before atoms
+	switch t.Value {
+	case "raw":
+		return p.parseRawBlock(t)
+
+	case "with":
+		return p.parseWithBlock(t)
+	}
+
 	// Assuming that atom expression blocks are always end markers, return early
 	// i.e. {% endmacro %}  or {% endif %} or {% endfor %} or {% else %}
 	if p.peekIs(lexer.ExpressionBlockClose) || p.peekIs(lexer.ExpressionBlockCloseTrim) {
@@ -216,12 +225,21 @@ func (p *parser) parseExpressionBlock() (ast.AST, error) {
 
 		return ast.NewDoBlock(t, toRun), nil
 
+	case "filter":
+		return p.parseFilterBlock(t)
+
+	case "import":
+		return p.parseImportBlock(t)
+
+	case "from":
+		return p.parseFromImportBlock(t)
+
 	case "materialization":
 		// These are unsupported for now
 		return p.parseUnsupportedBlockType(t)
 
 	default:
-		return nil, p.errorAt(t, "Expected `macro`, `set`, `for`, `if`, `call` got "+t.Value)
+		return nil, p.errorAt(t, "Expected `macro`, `set`, `for`, `if`, `call`, `do`, `filter`, `with`, `raw`, `import` or `from` got "+t.Value)
 	}
 }
 
@@ -377,24 +395,22 @@ func (p *parser) parseValue() (ast.AST, error) {
 	var statement ast.AST
 
 	var err error
-	if p.peekIs(lexer.LeftParenthesesToken) {
-		_ = p.next() // consume the "("
+	if p.peekIs(lexer.LeftParenthesesToken) || p.peekIs(lexer.StringToken) || p.peekIs(lexer.LeftBracketToken) || p.peekIs(lexer.LeftBraceToken) {
+		token := p.peek()
 
-		statement, err = p.parseStatement()
+		statement, err = p.parseLiteral()
 		if err != nil {
 			return nil, err
 		}
 
-		_, err = p.expectedAndConsumeValue(lexer.RightParenthesesToken)
-		if err != nil {
-			return nil, err
+		// Literals can have methods called on them and be indexed into (`', '.join(list)` or `'abc'[1:]`)
+		if p.peekIs(lexer.PeriodToken) || p.peekIs(lexer.LeftBracketToken) {
+			statement, err = p.parseVariableSuffixes(ast.NewValueVariable(token, statement))
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		statement = ast.NewBracketGroup(statement)
-
-	} else if p.peekIs(lexer.StringToken) {
-		statement = ast.NewTextBlock(p.next())
-
 	} else if p.peekIs(lexer.NumberToken) {
 		token := p.next()
 		number, err := strconv.ParseFloat(token.Value, 64)
@@ -423,17 +439,6 @@ func (p *parser) parseValue() (ast.AST, error) {
 
 		statement = ast.NewUniaryMathsOp(op, subStatement)
 
-	} else if p.peekIs(lexer.LeftBracketToken) {
-		statement, err = p.parseList()
-		if err != nil {
-			return nil, err
-		}
-
-	} else if p.peekIs(lexer.LeftBraceToken) {
-		statement, err = p.parseMap()
-		if err != nil {
-			return nil, err
-		}
 	} else if p.peekIs(lexer.IdentToken) && p.peek().Value == "not" {
 		notToken := p.next()
 
@@ -454,6 +459,34 @@ func (p *parser) parseValue() (ast.AST, error) {
 	return statement, nil
 }
 
+// Parses a bracketed group, string, list or map
+func (p *parser) parseLiteral() (ast.AST, error) {
+	switch p.peek().Type {
+	case lexer.LeftParenthesesToken:
+		_ = p.next() // consume the "("
+
+		statement, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.expectedAndConsumeValue(lexer.RightParenthesesToken); err != nil {
+			return nil, err
+		}
+
+		return ast.NewBracketGroup(statement), nil
+
+	case lexer.StringToken:
+		return ast.NewTextBlock(p.next()), nil
+
+	case lexer.LeftBracketToken:
+		return p.parseList()
+
+	default:
+		return p.parseMap()
+	}
+}
+
 func (p *parser) parseStatement() (ast.AST, error) {
 	statement, err := p.parseValue()
 	if err != nil {
@@ -466,25 +499,9 @@ func (p *parser) parseStatement() (ast.AST, error) {
 		return nil, err
 	}
 
-	// Check for filters
-	for p.peekIs(lexer.PipeToken) {
-		pipeToken := p.next() // consume the "|"
-
-		ident, err := p.expectedAndConsumeValue(lexer.IdentToken)
-		if err != nil {
-			return nil, err
-		}
-
-		fc := ast.NewFunctionCall(pipeToken, ident.Value)
-		fc.AddArgument("", statement)
-
-		if p.peekIs(lexer.LeftParenthesesToken) {
-			if err := p.parseArgumentList(fc); err != nil {
-				return nil, err
-			}
-		}
-
-		statement = fc
+	statement, err = p.parsePossibleFilters(statement)
+	if err != nil {
+		return nil, err
 	}
 
 	for p.peekIs(lexer.TildeToken) {
@@ -568,6 +585,31 @@ func (p *parser) parseStatement() (ast.AST, error) {
 	return statement, nil
 }
 
+// Parses any filters applied to the statement (`statement | filter(arg) | other_filter`)
+func (p *parser) parsePossibleFilters(statement ast.AST) (ast.AST, error) {
+	for p.peekIs(lexer.PipeToken) {
+		pipeToken := p.next() // consume the "|"
+
+		ident, err := p.expectedAndConsumeValue(lexer.IdentToken)
+		if err != nil {
+			return nil, err
+		}
+
+		fc := ast.NewFilterCall(pipeToken, ident.Value)
+		fc.AddArgument("", statement)
+
+		if p.peekIs(lexer.LeftParenthesesToken) {
+			if err := p.parseArgumentList(fc); err != nil {
+				return nil, err
+			}
+		}
+
+		statement = fc
+	}
+
+	return statement, nil
+}
+
 func (p *parser) parsePossibleMathsOps(lhs ast.AST) (ast.AST, error) {
 	switch p.peek().Type {
 	case lexer.MultiplyToken, lexer.DivideToken, lexer.PlusToken, lexer.MinusToken, lexer.PowerToken:
@@ -595,7 +637,12 @@ func (p *parser) parseVariable(ident *lexer.Token) (*ast.Variable, error) {
 		}
 	}
 
-	variable := ast.NewVariable(ident)
+	return p.parseVariableSuffixes(ast.NewVariable(ident))
+}
+
+// Parses any function calls, indexes, slices and property lookups which follow a variable (`a.b(c)[d]`)
+func (p *parser) parseVariableSuffixes(variable *ast.Variable) (*ast.Variable, error) {
+	var err error
 
 	for {
 		switch p.peek().Type {
@@ -610,10 +657,21 @@ func (p *parser) parseVariable(ident *lexer.Token) (*ast.Variable, error) {
 		case lexer.LeftBracketToken:
 			_ = p.next() // consume "["
 
-			// This variable is being accesed like a map or array (`a["b"]`)
-			key, err := p.parseStatement()
-			if err != nil {
-				return nil, err
+			// This variable is being accesed like a map or array (`a["b"]`), or sliced (`a[1:-1]`)
+			var key ast.AST
+			if !p.peekIs(lexer.ColonToken) {
+				key, err = p.parseStatement()
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if p.peekIs(lexer.ColonToken) {
+				variable, err = p.parseSlice(variable, key)
+				if err != nil {
+					return nil, err
+				}
+				continue
 			}
 
 			if _, err := p.expectedAndConsumeValue(lexer.RightBracketToken); err != nil {
@@ -640,6 +698,30 @@ func (p *parser) parseVariable(ident *lexer.Token) (*ast.Variable, error) {
 	}
 }
 
+// Parses the rest of a slice, `[start:stop:step]`, after the start; any of which can be left out
+func (p *parser) parseSlice(variable *ast.Variable, start ast.AST) (*ast.Variable, error) {
+	parts := []ast.AST{start, nil, nil}
+
+	for i := 1; i < len(parts) && p.peekIs(lexer.ColonToken); i++ {
+		_ = p.next() // consume ":"
+
+		if !p.peekIs(lexer.ColonToken) && !p.peekIs(lexer.RightBracketToken) {
+			part, err := p.parseStatement()
+			if err != nil {
+				return nil, err
+			}
+
+			parts[i] = part
+		}
+	}
+
+	if _, err := p.expectedAndConsumeValue(lexer.RightBracketToken); err != nil {
+		return nil, err
+	}
+
+	return variable.AsSliceLookup(parts[0], parts[1], parts[2]), nil
+}
+
 func (p *parser) parseForLoop() (ast.AST, error) {
 	keyIteratorName := ""
 	valueIterator, err := p.expectedAndConsumeValue(lexer.IdentToken)
@@ -662,17 +744,45 @@ func (p *parser) parseForLoop() (ast.AST, error) {
 		return nil, err
 	}
 
-	list, err := p.parseVariable(nil)
+	// The list is parsed without a trailing `if`, as that filters the loop rather than being a ternary
+	list, err := p.parseValue()
 	if err != nil {
 		return nil, err
 	}
 
+	list, err = p.parsePossibleFilters(list)
+	if err != nil {
+		return nil, err
+	}
+
+	var filter ast.AST
+	if p.peekIs(lexer.IdentToken) && p.peek().Value == "if" {
+		_ = p.next() // consume the "if"
+
+		filter, err = p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	isRecursive := false
+	if p.peekIs(lexer.IdentToken) && p.peek().Value == "recursive" {
+		_ = p.next()
+		isRecursive = true
+	}
+
 	err = p.parseExpressionBlockClose()
 	if err != nil {
 		return nil, err
 	}
 
 	forLoop := ast.NewForLoop(valueIterator, keyIteratorName, list)
+	if filter != nil {
+		forLoop.SetFilter(filter)
+	}
+	if isRecursive {
+		forLoop.SetRecursive()
+	}
 
 	if err := p.parseBodyUntilAtom("endfor", forLoop); err != nil {
 		return nil, err
@@ -929,33 +1039,47 @@ func (p *parser) parseSetCall() (ast.AST, error) {
 		return nil, err
 	}
 
-	if p.peekIs(lexer.EqualsToken) {
-		// "{% set x = y %}" style
-		_, err = p.expectedAndConsumeValue(lexer.EqualsToken)
+	if p.peekIs(lexer.PeriodToken) {
+		// "{% set ns.x = y %}" style
+		_ = p.next()
+
+		attribute, err := p.expectedAndConsumeValue(lexer.IdentToken)
 		if err != nil {
 			return nil, err
 		}
 
-		condition, err := p.parseCondition()
+		condition, err := p.parseSetValue()
 		if err != nil {
 			return nil, err
 		}
 
-		err = p.parseExpressionBlockClose()
+		return ast.NewSetAttributeCall(ident, attribute, condition), nil
+	} else if p.peekIs(lexer.EqualsToken) {
+		// "{% set x = y %}" style
+		condition, err := p.parseSetValue()
 		if err != nil {
 			return nil, err
 		}
 
 		return ast.NewSetCall(ident, condition), nil
 	} else {
-		// "{% set x %}y{% endset %}" style
+		// "{% set x %}y{% endset %}" or "{% set x | upper %}y{% endset %}" style
+		var body ast.BodyHoldingAST = ast.NewBody(ident)
+
+		if p.peekIs(lexer.PipeToken) {
+			filters, err := p.parseFilterChain()
+			if err != nil {
+				return nil, err
+			}
+
+			body = ast.NewFilterBlock(ident, filters)
+		}
+
 		err = p.parseExpressionBlockClose()
 		if err != nil {
 			return nil, err
 		}
 
-		body := ast.NewBody(ident)
-
 		if err := p.parseBodyUntilAtom("endset", body); err != nil {
 			return nil, err
 		}
@@ -964,6 +1088,208 @@ func (p *parser) parseSetCall() (ast.AST, error) {
 	}
 }
 
+// Parses the "= y %}" of a "{% set x = y %}"
+func (p *parser) parseSetValue() (ast.AST, error) {
+	_, err := p.expectedAndConsumeValue(lexer.EqualsToken)
+	if err != nil {
+		return nil, err
+	}
+
+	condition, err := p.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.parseExpressionBlockClose()
+	if err != nil {
+		return nil, err
+	}
+
+	return condition, nil
+}
+
+// Parses the filters of a filter block or set block, i.e. the `upper | replace('a', 'b')` of
+// `{% filter upper | replace('a', 'b') %}`; a leading "|" is optional
+func (p *parser) parseFilterChain() ([]*ast.FunctionCall, error) {
+	filters := make([]*ast.FunctionCall, 0)
+
+	if p.peekIs(lexer.PipeToken) {
+		_ = p.next()
+	}
+
+	for {
+		ident, err := p.expectedAndConsumeValue(lexer.IdentToken)
+		if err != nil {
+			return nil, err
+		}
+
+		fc := ast.NewFilterCall(ident, ident.Value)
+
+		if p.peekIs(lexer.LeftParenthesesToken) {
+			if err := p.parseArgumentList(fc); err != nil {
+				return nil, err
+			}
+		}
+
+		filters = append(filters, fc)
+
+		if !p.peekIs(lexer.PipeToken) {
+			return filters, nil
+		}
+		_ = p.next()
+	}
+}
+
+func (p *parser) parseFilterBlock(token *lexer.Token) (ast.AST, error) {
+	filters, err := p.parseFilterChain()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.parseExpressionBlockClose(); err != nil {
+		return nil, err
+	}
+
+	fb := ast.NewFilterBlock(token, filters)
+
+	if err := p.parseBodyUntilAtom("endfilter", fb); err != nil {
+		return nil, err
+	}
+
+	return fb, nil
+}
+
+func (p *parser) parseWithBlock(token *lexer.Token) (ast.AST, error) {
+	wb := ast.NewWithBlock(token)
+
+	for p.peekIs(lexer.IdentToken) {
+		name := p.next()
+
+		if _, err := p.expectedAndConsumeValue(lexer.EqualsToken); err != nil {
+			return nil, err
+		}
+
+		value, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+
+		wb.AddVariable(name.Value, value)
+
+		if !p.peekIs(lexer.CommaToken) {
+			break
+		}
+		_ = p.next()
+	}
+
+	if err := p.parseExpressionBlockClose(); err != nil {
+		return nil, err
+	}
+
+	if err := p.parseBodyUntilAtom("endwith", wb); err != nil {
+		return nil, err
+	}
+
+	return wb, nil
+}
+
+// Parses `{% import 'file.sql' as helpers %}`
+func (p *parser) parseImportBlock(token *lexer.Token) (ast.AST, error) {
+	path, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectedAndConsumeIdentifier("as"); err != nil {
+		return nil, err
+	}
+
+	alias, err := p.expectedAndConsumeValue(lexer.IdentToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.parseImportContext(); err != nil {
+		return nil, err
+	}
+
+	return ast.NewImportBlock(token, path, alias.Value), nil
+}
+
+// Parses `{% from 'file.sql' import a, b as c %}`
+func (p *parser) parseFromImportBlock(token *lexer.Token) (ast.AST, error) {
+	path, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectedAndConsumeIdentifier("import"); err != nil {
+		return nil, err
+	}
+
+	block := ast.NewFromImportBlock(token, path)
+
+	for {
+		name, err := p.expectedAndConsumeValue(lexer.IdentToken)
+		if err != nil {
+			return nil, err
+		}
+
+		alias := ""
+		if p.peekIs(lexer.IdentToken) && p.peek().Value == "as" {
+			_ = p.next()
+
+			aliasToken, err := p.expectedAndConsumeValue(lexer.IdentToken)
+			if err != nil {
+				return nil, err
+			}
+			alias = aliasToken.Value
+		}
+
+		block.AddName(name.Value, alias)
+
+		if !p.peekIs(lexer.CommaToken) {
+			break
+		}
+		_ = p.next()
+	}
+
+	if err := p.parseImportContext(); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// Imported macros always run with the context they're called from, so a trailing `with context` or
+// `without context` is accepted but makes no difference
+func (p *parser) parseImportContext() error {
+	if p.peekIs(lexer.IdentToken) && (p.peek().Value == "with" || p.peek().Value == "without") {
+		_ = p.next()
+
+		if err := p.expectedAndConsumeIdentifier("context"); err != nil {
+			return err
+		}
+	}
+
+	return p.parseExpressionBlockClose()
+}
+
+// The lexer passes the contents of a raw block through as a single text token
+func (p *parser) parseRawBlock(token *lexer.Token) (ast.AST, error) {
+	if err := p.parseExpressionBlockClose(); err != nil {
+		return nil, err
+	}
+
+	body := ast.NewBody(token)
+
+	if err := p.parseBodyUntilAtom("endraw", body); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
 func (p *parser) parseList() (ast.AST, error) {
 	token, err := p.expectedAndConsumeValue(lexer.LeftBracketToken)
 	if err != nil {
@@ -12,11 +12,12 @@ const FileVersion = 2
 
 // Represents what can be held within a DBT properties file
 type File struct {
-	Version   int    `yaml:"version"`             // What version of the schema we're on (always 2)
-	Models    Models `yaml:"models,omitempty"`    // List of the model schemas defined in this file
-	Macros    Macros `yaml:"macros,omitempty"`    // List of the macro schemas defined in this file
-	Seeds     Models `yaml:"seeds,omitempty"`     // List of the seed schemas defined in this file (same structure as a model)
-	Snapshots Models `yaml:"snapshots,omitempty"` // List of the snapshot schemas defined in this file (same structure as a model)
+	Version   int       `yaml:"version"`              // What version of the schema we're on (always 2)
+	Models    Models    `yaml:"models,omitempty"`     // List of the model schemas defined in this file
+	Macros    Macros    `yaml:"macros,omitempty"`     // List of the macro schemas defined in this file
+	Seeds     Models    `yaml:"seeds,omitempty"`      // List of the seed schemas defined in this file (same structure as a model)
+	Snapshots Models    `yaml:"snapshots,omitempty"`  // List of the snapshot schemas defined in this file (same structure as a model)
+	UnitTests UnitTests `yaml:"unit_tests,omitempty"` // List of the unit tests defined in this file
 }
 
 // Unmarshals the file
@@ -87,18 +88,34 @@ type Models []*Model
 
 // A model/seed/snapshot schema
 type Model struct {
-	Name        string   `yaml:"name"`
-	Description string   `yaml:"description"`
-	Docs        Docs     `yaml:"docs,omitempty"`
-	Meta        MetaData `yaml:"meta,omitempty"`
-	Tests       Tests    `yaml:"tests,omitempty"`   // Model level tests
-	Columns     Columns  `yaml:"columns,omitempty"` // Columns
+	Name        string       `yaml:"name"`
+	Description string       `yaml:"description"`
+	Docs        Docs         `yaml:"docs,omitempty"`
+	Meta        MetaData     `yaml:"meta,omitempty"`
+	Config      *ModelConfig `yaml:"config,omitempty"`  // Model config set within the schema
+	Tests       Tests        `yaml:"tests,omitempty"`   // Model level tests
+	Columns     Columns      `yaml:"columns,omitempty"` // Columns
+}
+
+// Config for a model which can be set within the schema
+type ModelConfig struct {
+	Contract *Contract `yaml:"contract,omitempty"`
+}
+
+// A contract requires the model's output to exactly match the columns & data types declared in the schema
+type Contract struct {
+	Enforced bool `yaml:"enforced"`
+}
+
+// Is the contract for this model enforced
+func (m *Model) ContractEnforced() bool {
+	return m.Config != nil && m.Config.Contract != nil && m.Config.Contract.Enforced
 }
 
 func (m *Model) definedTests(tests map[string]string) error {
 	// Table level tests
 	for index, tableTest := range m.Tests {
-		testName := fmt.Sprintf("%s_%s_%d", tableTest.Name, m.Name, index)
+		testName := fmt.Sprintf("%s_%s_%d", tableTest.macroName(), m.Name, index)
 		jinja, err := tableTest.toTestJinja(m.Name, "")
 		if err != nil {
 			return err
@@ -110,7 +127,7 @@ func (m *Model) definedTests(tests map[string]string) error {
 	// Column level tests
 	for _, column := range m.Columns {
 		for index, tableTest := range column.Tests {
-			testName := fmt.Sprintf("%s_%s__%s_%d", tableTest.Name, m.Name, column.Name, index)
+			testName := fmt.Sprintf("%s_%s__%s_%d", tableTest.macroName(), m.Name, column.Name, index)
 			jinja, err := tableTest.toTestJinja(m.Name, column.Name)
 			if err != nil {
 				return err
@@ -130,6 +147,7 @@ type Column struct {
 	Name        string   `yaml:"name"`
 	Description string   `yaml:"description"`
 	Meta        MetaData `yaml:"meta,omitempty"`
+	DataType    string   `yaml:"data_type,omitempty"`
 	Quote       bool     `yaml:"quote,omitempty"`
 	Tests       Tests    `yaml:"tests"`
 	Tags        []string `yaml:"tags,omitempty,flow"`
@@ -119,3 +119,152 @@ macros:
 	require.NoError(t, err, "Unable to marshal properties file back to YAML")
 	assert.Equal(t, yml, string(bytes), "Output file didn't match")
 }
+
+func TestPropertiesTestConfigParse(t *testing.T) {
+	yml := `version: 2
+models:
+- name: model_name
+  description: ""
+  columns:
+  - name: column_name
+    description: ""
+    tests:
+    - not_null:
+        severity: warn
+        warn_if: '>10'
+        error_if: '>100'
+        where: column_b IS NOT NULL
+`
+	file := &File{}
+	require.NoError(t, yaml.Unmarshal([]byte(yml), file))
+
+	require.Len(t, file.Models, 1, "Invalid number of models")
+	require.Len(t, file.Models[0].Columns, 1, "Not enough columns")
+	require.Len(t, file.Models[0].Columns[0].Tests, 1, "Not enough tests on the column")
+
+	test := file.Models[0].Columns[0].Tests[0]
+	assert.Equal(t, "not_null", test.Name)
+	assert.Empty(t, test.Arguments)
+	assert.Equal(t, "warn", test.Severity)
+	assert.Equal(t, ">10", test.WarnIf)
+	assert.Equal(t, ">100", test.ErrorIf)
+	assert.Equal(t, "column_b IS NOT NULL", test.Where)
+
+	// Test output back
+	bytes, err := yaml.Marshal(file)
+	require.NoError(t, err, "Unable to marshal properties file back to YAML")
+	assert.Equal(t, yml, string(bytes), "Output file didn't match")
+
+	// The same config can also be nested under a config block
+	nested := &Test{}
+	require.NoError(t, yaml.Unmarshal([]byte(`not_null:
+  config:
+    error_if: '>5'
+`), nested))
+	assert.Equal(t, ">5", nested.ErrorIf)
+	assert.Empty(t, nested.Arguments)
+}
+
+func TestTestConfigJinja(t *testing.T) {
+	test := &Test{
+		Name:     "unique",
+		Severity: "warn",
+		Tags:     []string{"fast", "ci"},
+		ErrorIf:  ">10",
+	}
+
+	jinja, err := test.toTestJinja("model_a", "id")
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`{{ config(model="model_a", column_name="id", severity="warn", error_if=">10", tags=["fast", "ci"]) }}{{ test_unique( model=ref('model_a'), column_name='id') }}`,
+		jinja,
+	)
+}
+
+func TestUnitTestParse(t *testing.T) {
+	yml := `version: 2
+unit_tests:
+- name: test_uppercase
+  model: my_model
+  given:
+  - input: ref('upstream_model')
+    rows:
+    - {id: 1, value: 'it''s'}
+    - {id: 2, enabled: true}
+  - input: ref("other_model")
+    sql: SELECT 1 AS id
+  expect:
+    rows:
+    - {id: 1, value: 1.5}
+`
+	file := &File{}
+	require.NoError(t, yaml.Unmarshal([]byte(yml), file))
+	require.Len(t, file.UnitTests, 1, "Invalid number of unit tests")
+
+	unitTest := file.UnitTests[0]
+	assert.Equal(t, "test_uppercase", unitTest.Name)
+	assert.Equal(t, "my_model", unitTest.Model)
+	require.Len(t, unitTest.Given, 2, "Invalid number of inputs")
+
+	refName, err := unitTest.Given[0].RefName()
+	require.NoError(t, err)
+	assert.Equal(t, "upstream_model", refName)
+
+	sql, err := unitTest.Given[0].ToSQL()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1 AS id, 'it\\'s' AS value, NULL AS enabled\nUNION ALL\nSELECT 2 AS id, NULL AS value, TRUE AS enabled", sql)
+
+	refName, err = unitTest.Given[1].RefName()
+	require.NoError(t, err)
+	assert.Equal(t, "other_model", refName)
+
+	sql, err = unitTest.Given[1].ToSQL()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1 AS id", sql)
+
+	sql, err = unitTest.Expect.ToSQL()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1 AS id, 1.5 AS value", sql)
+
+	sql, err = unitTest.Expect.ToTypedSQL(map[string]string{"value": "NUMERIC", "other": "DATE"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1 AS id, CAST(1.5 AS NUMERIC) AS value", sql)
+
+	sql, err = unitTest.Given[0].ToTypedSQL(map[string]string{"enabled": "BOOL"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1 AS id, 'it\\'s' AS value, CAST(NULL AS BOOL) AS enabled\nUNION ALL\nSELECT 2 AS id, NULL AS value, CAST(TRUE AS BOOL) AS enabled", sql)
+
+	_, err = (&UnitTestInput{Input: "source('a', 'b')"}).RefName()
+	assert.Error(t, err)
+}
+
+func TestModelContractParse(t *testing.T) {
+	yml := `version: 2
+models:
+- name: model_name
+  description: ""
+  config:
+    contract:
+      enforced: true
+  columns:
+  - name: id
+    description: ""
+    data_type: int64
+    tests: []
+- name: another_model
+  description: ""
+`
+	file := &File{}
+	require.NoError(t, yaml.Unmarshal([]byte(yml), file))
+	require.Len(t, file.Models, 2, "Invalid number of models")
+
+	assert.True(t, file.Models[0].ContractEnforced())
+	assert.False(t, file.Models[1].ContractEnforced())
+	assert.Equal(t, "int64", file.Models[0].Columns[0].DataType)
+
+	// Test output back
+	bytes, err := yaml.Marshal(file)
+	require.NoError(t, err, "Unable to marshal properties file back to YAML")
+	assert.Equal(t, yml, string(bytes), "Output file didn't match")
+}
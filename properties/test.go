@@ -1,9 +1,11 @@
 package properties
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 
 	"gopkg.in/yaml.v2"
@@ -11,6 +13,9 @@ import (
 
 type Tests []*Test
 
+// Matches test arguments which are references to other models, such as `ref('my_model')`
+var refArgument = regexp.MustCompile(`^\s*ref\(\s*('[^']*'|"[^"]*")\s*\)\s*$`)
+
 // Represents a test we should perform against a Model or Column
 //
 // Due to how DBT encodes these within YAML we need to write a custom set
@@ -29,7 +34,12 @@ type Test struct {
 	Name      string // the name of the inbuilt test we want to run such as "not_null" or "unique"
 	Severity  string // "warn" or "error" are the only allowed values
 	Tags      []string
+	WarnIf    string // a condition on the number of failures such as ">10", which if true raises a warning
+	ErrorIf   string // a condition on the number of failures such as ">10", which if true raises an error
+	Where     string // a filter applied to the model before the test is run against it
 	Arguments TestArguments
+
+	StoreFailures bool // should the failing rows be stored in the audit dataset
 }
 
 // The arguments which a test requires
@@ -64,46 +74,27 @@ func (o *Test) UnmarshalYAML(unmarshal func(v interface{}) error) error {
 		arguments := make(TestArguments, 0)
 
 		for _, property := range properties {
-			// pull out the severity or tags key to the top level test object
-			switch property.Key {
-			case "severity":
-				switch v := property.Value.(type) {
-				case string:
-					if v != "warn" && v != "error" {
-						return fmt.Errorf("severity expected to be a `warn` or `error`, got %v", v)
-					}
-
-					o.Severity = v
-				default:
-					return fmt.Errorf("severity expected to be a `warn` or `error`, got %v", reflect.TypeOf(v))
-				}
-
-			case "tags":
-				switch v := property.Value.(type) {
-				case []interface{}:
-					tags := make([]string, 0, len(v))
+			// pull out the test config keys to the top level test object
+			str, ok := property.Key.(string)
+			if !ok {
+				return fmt.Errorf("unable to convert property key to string: %v", property.Key)
+			}
 
-					for _, tagI := range v {
-						if tag, ok := tagI.(string); ok {
-							tags = append(tags, tag)
-						} else {
-							return fmt.Errorf("expected tag value to be a string, got %v", reflect.TypeOf(tagI))
-						}
-					}
+			if str == "config" {
+				if err := o.readConfigBlock(property.Value); err != nil {
+					return err
+				}
 
-					o.Tags = tags
+				continue
+			}
 
-				default:
-					return fmt.Errorf("tags expected to be an array, got %v", reflect.TypeOf(v))
-				}
+			handled, err := o.readConfig(str, property.Value)
+			if err != nil {
+				return err
+			}
 
-			default:
+			if !handled {
 				// otherwise transpose the test arguments to our arguments struct
-				str, ok := property.Key.(string)
-				if !ok {
-					return fmt.Errorf("unable to convert property key to string: %v", property.Key)
-				}
-
 				arguments = append(arguments, TestArgument{
 					Name:  str,
 					Value: property.Value,
@@ -117,8 +108,116 @@ func (o *Test) UnmarshalYAML(unmarshal func(v interface{}) error) error {
 	return nil
 }
 
+// Reads the `config:` block of a test, which can hold the same keys as the test itself
+func (o *Test) readConfigBlock(value interface{}) error {
+	m, ok := value.(yaml.MapSlice)
+	if !ok {
+		return fmt.Errorf("test config expected to be a map, got %v", reflect.TypeOf(value))
+	}
+
+	for _, property := range m {
+		str, ok := property.Key.(string)
+		if !ok {
+			return fmt.Errorf("unable to convert test config key to string: %v", property.Key)
+		}
+
+		handled, err := o.readConfig(str, property.Value)
+		if err != nil {
+			return err
+		}
+
+		if !handled {
+			return fmt.Errorf("unknown test config `%s`", str)
+		}
+	}
+
+	return nil
+}
+
+// Reads a config key of the test, returning false if the key is not a config key
+func (o *Test) readConfig(key string, value interface{}) (bool, error) {
+	switch key {
+	case "severity":
+		switch v := value.(type) {
+		case string:
+			if v != "warn" && v != "error" {
+				return true, fmt.Errorf("severity expected to be a `warn` or `error`, got %v", v)
+			}
+
+			o.Severity = v
+		default:
+			return true, fmt.Errorf("severity expected to be a `warn` or `error`, got %v", reflect.TypeOf(v))
+		}
+
+	case "tags":
+		switch v := value.(type) {
+		case []interface{}:
+			tags := make([]string, 0, len(v))
+
+			for _, tagI := range v {
+				if tag, ok := tagI.(string); ok {
+					tags = append(tags, tag)
+				} else {
+					return true, fmt.Errorf("expected tag value to be a string, got %v", reflect.TypeOf(tagI))
+				}
+			}
+
+			o.Tags = tags
+
+		default:
+			return true, fmt.Errorf("tags expected to be an array, got %v", reflect.TypeOf(v))
+		}
+
+	case "warn_if":
+		str, err := conditionAsString(key, value)
+		if err != nil {
+			return true, err
+		}
+		o.WarnIf = str
+
+	case "error_if":
+		str, err := conditionAsString(key, value)
+		if err != nil {
+			return true, err
+		}
+		o.ErrorIf = str
+
+	case "where":
+		str, ok := value.(string)
+		if !ok {
+			return true, fmt.Errorf("where expected to be a string, got %v", reflect.TypeOf(value))
+		}
+		o.Where = str
+
+	case "store_failures":
+		b, ok := value.(bool)
+		if !ok {
+			return true, fmt.Errorf("store_failures expected to be a boolean, got %v", reflect.TypeOf(value))
+		}
+		o.StoreFailures = b
+
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// warn_if and error_if are normally strings such as ">10", but YAML will give us a number if
+// someone just writes a count, so we treat that as an equality check
+func conditionAsString(key string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int:
+		return fmt.Sprintf("=%d", v), nil
+	default:
+		return "", fmt.Errorf("%s expected to be a string such as `>10`, got %v", key, reflect.TypeOf(v))
+	}
+}
+
 func (o *Test) MarshalYAML() (interface{}, error) {
-	if len(o.Arguments) == 0 && len(o.Tags) == 0 && o.Severity == "" {
+	if len(o.Arguments) == 0 && len(o.Tags) == 0 && o.Severity == "" && !o.hasConfig() {
 		return o.Name, nil
 	}
 
@@ -139,21 +238,91 @@ func (o *Test) MarshalYAML() (interface{}, error) {
 		args = append(args, yaml.MapItem{Key: "severity", Value: o.Severity})
 	}
 
+	if o.WarnIf != "" {
+		args = append(args, yaml.MapItem{Key: "warn_if", Value: o.WarnIf})
+	}
+
+	if o.ErrorIf != "" {
+		args = append(args, yaml.MapItem{Key: "error_if", Value: o.ErrorIf})
+	}
+
+	if o.Where != "" {
+		args = append(args, yaml.MapItem{Key: "where", Value: o.Where})
+	}
+
+	if o.StoreFailures {
+		args = append(args, yaml.MapItem{Key: "store_failures", Value: o.StoreFailures})
+	}
+
 	return yaml.MapSlice{
 		{Key: o.Name, Value: args},
 	}, nil
 }
 
+// The name of the macro implementing this test, without any package prefix (such as `dbt_utils.`)
+func (o *Test) macroName() string {
+	return o.Name[strings.LastIndex(o.Name, ".")+1:]
+}
+
+// Does this test have any config set other than its severity and tags
+func (o *Test) hasConfig() bool {
+	return o.WarnIf != "" || o.ErrorIf != "" || o.Where != "" || o.StoreFailures
+}
+
 // Converts this test to a Jinja comptible format
 func (o *Test) toTestJinja(tableName, columnName string) (string, error) {
 	var builder strings.Builder
 
-	builder.WriteString("{{ test_")
-	builder.WriteString(o.Name)
+	// Pass the test config through to the test file, so the test runner knows how to treat and report on the result
+	configArgs := []string{"model=" + jinjaString(tableName)}
+
+	if columnName != "" {
+		configArgs = append(configArgs, "column_name="+jinjaString(columnName))
+	}
 
-	builder.WriteString("( model=ref('")
-	builder.WriteString(tableName)
-	builder.WriteString("')")
+	if o.Severity != "" {
+		configArgs = append(configArgs, "severity="+jinjaString(o.Severity))
+	}
+
+	if o.WarnIf != "" {
+		configArgs = append(configArgs, "warn_if="+jinjaString(o.WarnIf))
+	}
+
+	if o.ErrorIf != "" {
+		configArgs = append(configArgs, "error_if="+jinjaString(o.ErrorIf))
+	}
+
+	if o.StoreFailures {
+		configArgs = append(configArgs, "store_failures=true")
+	}
+
+	if len(o.Tags) > 0 {
+		tags := make([]string, len(o.Tags))
+		for i, tag := range o.Tags {
+			tags[i] = jinjaString(tag)
+		}
+
+		configArgs = append(configArgs, "tags=["+strings.Join(tags, ", ")+"]")
+	}
+
+	builder.WriteString("{{ config(")
+	builder.WriteString(strings.Join(configArgs, ", "))
+	builder.WriteString(") }}")
+
+	builder.WriteString("{{ test_")
+	builder.WriteString(o.macroName())
+
+	if o.Where != "" {
+		// Filter the model down before the test runs against it
+		builder.WriteString("( model=\"(SELECT * FROM \" ~ ref('")
+		builder.WriteString(tableName)
+		builder.WriteString("') ~ ")
+		builder.WriteString(jinjaString(" WHERE " + o.Where + ")"))
+	} else {
+		builder.WriteString("( model=ref('")
+		builder.WriteString(tableName)
+		builder.WriteString("')")
+	}
 
 	if columnName != "" {
 		builder.WriteString(", column_name='")
@@ -162,7 +331,16 @@ func (o *Test) toTestJinja(tableName, columnName string) (string, error) {
 	}
 
 	for _, arg := range o.Arguments {
-		jsonValue, err := json.Marshal(arg.Value)
+		// References to other models are passed through as Jinja, so they are resolved when the test is compiled
+		if str, ok := arg.Value.(string); ok && refArgument.MatchString(str) {
+			builder.WriteString(", ")
+			builder.WriteString(arg.Name)
+			builder.WriteRune('=')
+			builder.WriteString(strings.TrimSpace(str))
+			continue
+		}
+
+		jsonValue, err := jinjaValue(arg.Value)
 		if err != nil {
 			return "", fmt.Errorf(
 				"Unable to convert parameter for test %s on column %s of table %s: %s",
@@ -176,10 +354,35 @@ func (o *Test) toTestJinja(tableName, columnName string) (string, error) {
 		builder.WriteString(", ")
 		builder.WriteString(arg.Name)
 		builder.WriteRune('=')
-		builder.Write(jsonValue)
+		builder.WriteString(jsonValue)
 	}
 
 	builder.WriteString(") }}")
 
 	return builder.String(), nil
 }
+
+// Quotes a string so it can be used as a string literal inside a Jinja expression
+func jinjaString(str string) string {
+	str = strings.ReplaceAll(str, "\\", "\\\\")
+	str = strings.ReplaceAll(str, "\"", "\\\"")
+
+	return "\"" + str + "\""
+}
+
+// Converts a YAML value into a Jinja literal
+//
+// Note: JSON literals are valid Jinja, but we don't want the JSON encoder to escape HTML characters
+// such as `>` as our lexer doesn't understand unicode escapes
+func jinjaValue(value interface{}) (string, error) {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -0,0 +1,167 @@
+package properties
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+type UnitTests []*UnitTest
+
+// Represents a unit test of a model; where every `ref` used by the model is replaced
+// with the given fixture rows, and the output of the model is compared against the expected rows
+//
+//	unit_tests:
+//	- name: test_name
+//	  model: my_model
+//	  given:
+//	  - input: ref('upstream_model')
+//	    rows:
+//	    - {id: 1, value: 'a'}
+//	  expect:
+//	    rows:
+//	    - {id: 1, value: 'A'}
+type UnitTest struct {
+	Name        string          `yaml:"name"`
+	Model       string          `yaml:"model"`
+	Description string          `yaml:"description,omitempty"`
+	Given       []UnitTestInput `yaml:"given"`
+	Expect      UnitTestRows    `yaml:"expect"`
+}
+
+// A fixture for a single `ref` used by the model under test
+type UnitTestInput struct {
+	Input        string `yaml:"input"` // the ref being replaced, such as `ref('my_model')`
+	UnitTestRows `yaml:",inline"`
+}
+
+// Rows of data within a unit test, given either as a list of rows or as a SQL query
+type UnitTestRows struct {
+	Rows []yaml.MapSlice `yaml:"rows,omitempty"`
+	SQL  string          `yaml:"sql,omitempty"`
+}
+
+// Returns the name of the model this input replaces
+func (i *UnitTestInput) RefName() (string, error) {
+	matches := refArgument.FindStringSubmatch(i.Input)
+	if matches == nil {
+		return "", fmt.Errorf("unit test input `%s` should be in the form ref('model_name')", i.Input)
+	}
+
+	return matches[1][1 : len(matches[1])-1], nil
+}
+
+// The column names used by these rows, in the order they first appear
+func (r *UnitTestRows) Columns() ([]string, error) {
+	columns := make([]string, 0)
+	seen := make(map[string]struct{})
+
+	for _, row := range r.Rows {
+		for _, item := range row {
+			column, ok := item.Key.(string)
+			if !ok {
+				return nil, fmt.Errorf("unable to convert column name to string: %v", item.Key)
+			}
+
+			if _, found := seen[column]; !found {
+				seen[column] = struct{}{}
+				columns = append(columns, column)
+			}
+		}
+	}
+
+	return columns, nil
+}
+
+// Converts the rows into a SQL query which returns them, any column missing from a row is treated as NULL
+func (r *UnitTestRows) ToSQL() (string, error) {
+	return r.ToTypedSQL(nil)
+}
+
+// Converts the rows into a SQL query like ToSQL, casting the value of each column found in types (keyed by the lower
+// case column name) to that SQL type; as YAML values are untyped this lets `'2024-01-01'` be a DATE
+func (r *UnitTestRows) ToTypedSQL(types map[string]string) (string, error) {
+	if r.SQL != "" {
+		return r.SQL, nil
+	}
+
+	if len(r.Rows) == 0 {
+		return "", fmt.Errorf("unit test rows must be provided as either `rows` or `sql`")
+	}
+
+	columns, err := r.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+
+	for i, row := range r.Rows {
+		if i > 0 {
+			builder.WriteString("\nUNION ALL\n")
+		}
+
+		values := make(map[string]interface{}, len(row))
+		for _, item := range row {
+			values[item.Key.(string)] = item.Value
+		}
+
+		builder.WriteString("SELECT ")
+
+		for j, column := range columns {
+			if j > 0 {
+				builder.WriteString(", ")
+			}
+
+			literal, err := sqlLiteral(values[column])
+			if err != nil {
+				return "", fmt.Errorf("unable to convert column %s: %s", column, err)
+			}
+
+			if dataType, found := types[strings.ToLower(column)]; found {
+				literal = "CAST(" + literal + " AS " + dataType + ")"
+			}
+
+			builder.WriteString(literal)
+			builder.WriteString(" AS ")
+			builder.WriteString(column)
+		}
+	}
+
+	return builder.String(), nil
+}
+
+// Converts a YAML value into a BigQuery SQL literal
+func sqlLiteral(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+
+	case string:
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`).Replace(v) + "'", nil
+
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+
+	case int:
+		return strconv.Itoa(v), nil
+
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+
+	default:
+		return "", fmt.Errorf("unsupported value type %v", reflect.TypeOf(value))
+	}
+}
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"testing"
+)
+
+func TestRawBlock(t *testing.T) {
+	assertCompileOutput(t,
+		`SELECT '{{ not_a_var }}' AS a, REGEXP_CONTAINS(x, r'\d{2,3}') {% if %}`,
+		`SELECT {% raw %}'{{ not_a_var }}' AS a, REGEXP_CONTAINS(x, r'\d{2,3}') {% if %}{% endraw %}`)
+}
+
+func TestRawBlockTrimsWhitespace(t *testing.T) {
+	assertCompileOutput(t,
+		`a|b|c| b |d`,
+		`a|{% raw -%}  b  {%- endraw %}|c| {%- raw %} b {% endraw -%} |d`)
+}
+
+func TestFilterBlock(t *testing.T) {
+	assertCompileOutput(t,
+		`HELLO WORLD|X-B-C`,
+		`{% filter upper %}hello {{ 'world' }}{% endfilter %}|{% filter replace('a', 'x') | upper %}{% for c in ['a', 'b', 'c'] %}{{ c }}{% if not loop.last %}-{% endif %}{% endfor %}{% endfilter %}`)
+}
+
+func TestWithBlock(t *testing.T) {
+	assertCompileOutput(t,
+		`1,2|3|1`,
+		`
+{%- set a = 1 -%}
+{%- with b = a + 1, c = 3 -%}
+{{ a }},{{ b }}|{%- set a = c -%}{{ a }}|
+{%- endwith -%}
+{{ a }}`)
+}
+
+func TestSetBlockWithFilters(t *testing.T) {
+	assertCompileOutput(t,
+		`SELECT 1|select 1`,
+		`
+{%- set query | upper -%}select 1{%- endset -%}
+{%- set original -%}select 1{%- endset -%}
+{{ query }}|{{ original }}`)
+}
+
+func TestNamespace(t *testing.T) {
+	assertCompileOutput(t,
+		`TRUE|3|x`,
+		`
+{%- set ns = namespace(found=false, count=0) -%}
+{%- set other = namespace({'name': 'x'}) -%}
+{%- for item in ['a', 'b', 'c'] -%}
+{%- if item == 'b' -%}{%- set ns.found = true -%}{%- endif -%}
+{%- set ns.count = ns.count + 1 -%}
+{%- endfor -%}
+{{ ns.found }}|{{ ns.count }}|{{ other.name }}`)
+}
+
+func TestSetAttributeOfNonNamespace(t *testing.T) {
+	assertCompileError(t, `{% set s = 'abc' %}{% set s.x = 1 %}`, "can only set attributes of a namespace")
+	assertCompileError(t, `{% set m = {'a': 1} %}{% set m.a = 2 %}`, "can only set attributes of a namespace, `m` is a Map")
+	assertCompileError(t, `{% set flags.FULL_REFRESH = true %}`, "can only set attributes of a namespace, `flags` is a Map")
+}
+
+func TestLoopVariables(t *testing.T) {
+	assertCompileOutput(t,
+		`1/0/3/2/TRUE/FALSE/3/odd/-/b;2/1/2/1/FALSE/FALSE/3/even/a/c;3/2/1/0/FALSE/TRUE/3/odd/b/-;`,
+		`
+{%- for item in ['a', 'b', 'c'] -%}
+{{ loop.index }}/{{ loop.index0 }}/{{ loop.revindex }}/{{ loop.revindex0 }}/{{ loop.first }}/{{ loop.last }}/{{ loop.length }}/{{ loop.cycle('odd', 'even') }}/{{ loop.previtem | default('-') }}/{{ loop.nextitem | default('-') }};
+{%- endfor -%}`)
+}
+
+func TestRecursiveLoop(t *testing.T) {
+	assertCompileOutput(t,
+		`a@1(b@2(d@3)c@2)e@1`,
+		`
+{%- set tree = [
+	{'name': 'a', 'children': [
+		{'name': 'b', 'children': [{'name': 'd', 'children': []}]},
+		{'name': 'c', 'children': []},
+	]},
+	{'name': 'e', 'children': []},
+] -%}
+{%- for node in tree recursive -%}
+{{ node.name }}@{{ loop.depth }}{%- if node.children -%}({{ loop(node.children) }}){%- endif -%}
+{%- endfor -%}`)
+}
+
+func TestLoopFilter(t *testing.T) {
+	assertCompileOutput(t,
+		`1:b/2;2:c/2;|a=1;c=3;|b;`,
+		`
+{%- for item in ['c', 'a', 'b'] | sort if item != 'a' -%}
+{{ loop.index }}:{{ item }}/{{ loop.length }};
+{%- endfor -%}|
+{%- for key, value in {'a': 1, 'b': 2, 'c': 3} if value is odd -%}
+{{ key }}={{ value }};
+{%- endfor -%}|
+{%- for item in ['a', 'b'] if item == 'b' recursive -%}
+{{ item }};
+{%- endfor -%}`)
+}
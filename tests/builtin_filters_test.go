@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"testing"
+)
+
+func TestListFilters(t *testing.T) {
+	assertCompileOutput(t,
+		`a, b, c|3|3|a|c|abc|c,b,a|a,B,c|a,b,c|B,a,c|3`,
+		`
+{%- set list = ['c', 'a', 'B', 'a'] -%}
+{{ ['a', 'b', 'c'] | join(', ') }}|{{ ['a', 'b', 'c'] | length }}|{{ 'abc' | count }}|{{ 'abc' | first }}|{{ ['a', 'b', 'c'] | last }}|{{ 'abc' | list | join }}|{{ ['a', 'b', 'c'] | sort(reverse=true) | join(',') }}|{{ list | unique | sort | join(',') }}|{{ list | map('lower') | unique | sort | join(',') }}|{{ list | sort(case_sensitive=true) | unique | join(',') }}|{{ list | unique(case_sensitive=true) | length }}`)
+}
+
+func TestReplaceFilter(t *testing.T) {
+	assertCompileOutput(t,
+		`b-b-b|b-a-a`,
+		`{{ 'a-a-a' | replace('a', 'b') }}|{{ 'a-a-a' | replace('a', 'b', 1) }}`)
+}
+
+func TestAttributeFilters(t *testing.T) {
+	assertCompileOutput(t,
+		`ann,bob,cat|cat,bob,ann|ann,cat|bob|ann|cat|60|a:ann,cat;b:bob;`,
+		`
+{%- set people = [
+	{'name': 'cat', 'age': 30, 'team': 'a', 'admin': true},
+	{'name': 'ann', 'age': 10, 'team': 'a', 'admin': true},
+	{'name': 'bob', 'age': 20, 'team': 'b', 'admin': false},
+] -%}
+{%- set youngest = people | min(attribute='age') -%}
+{%- set last_by_name = people | max(attribute='name') -%}
+{{ people | map(attribute='name') | sort | join(',') }}|{{ people | sort(attribute='age', reverse=true) | map(attribute='name') | join(',') }}|{{ people | selectattr('admin') | map(attribute='name') | sort | join(',') }}|{{ people | rejectattr('admin') | join(',', attribute='name') }}|{{ youngest.name }}|{{ last_by_name.name }}|{{ people | sum(attribute='age') }}|
+{%- for group in people | groupby('team') -%}
+{{ group.grouper }}:{{ group.list | map(attribute='name') | sort | join(',') }};
+{%- endfor -%}
+`)
+}
+
+func TestSelectFilters(t *testing.T) {
+	assertCompileOutput(t,
+		`1,3,5|2,4|4,5|1,2`,
+		`
+{%- set numbers = [1, 2, 3, 4, 5] -%}
+{{ numbers | select('odd') | join(',') }}|{{ numbers | reject('odd') | join(',') }}|{{ numbers | select('gt', 3) | join(',') }}|{{ [1, 0, 2, none] | select | join(',') }}`)
+}
+
+func TestStringFilters(t *testing.T) {
+	assertCompileOutput(t,
+		`abc|xabcx|Hello World-Foo (Bar)|Hello world|a
+    b
+
+    c`,
+		`{{ '  abc  ' | trim }}|{{ 'xxabcxx' | trim('x') | replace('abc', 'xabcx') }}|{{ 'hello wORLD-foo (bar)' | title }}|{{ 'hELLO World' | capitalize }}|{{ 'a
+b
+
+c' | indent }}`)
+}
+
+func TestWordwrapFilter(t *testing.T) {
+	assertCompileOutput(t,
+		`the quick
+brown fox
+jumps|abcd
+ef`,
+		`{{ 'the quick brown fox jumps' | wordwrap(10) }}|{{ 'abcdef' | wordwrap(4) }}`)
+}
+
+func TestNumberFilters(t *testing.T) {
+	assertCompileOutput(t,
+		`42|3|7|0|255|1.5|0|5|3|2|2.5|1.24|1.23|6|1`,
+		`{{ '42' | int }}|{{ 3.9 | int }}|{{ 'x' | int(7) }}|{{ 'x' | int }}|{{ 'ff' | int(base=16) }}|{{ '1.5' | float }}|{{ 'x' | float }}|{{ -5 | abs }}|{{ 2.7 | round }}|{{ 2.5 | round }}|{{ 2.5 | round(method='floor', precision=1) }}|{{ 1.231 | round(2, 'ceil') }}|{{ 1.239 | round(2, 'floor') }}|{{ [1, 2, 3] | sum }}|{{ [3, 1, 2] | min }}`)
+}
+
+func TestConversionFilters(t *testing.T) {
+	assertCompileOutput(t,
+		`['a', 1]|True|{'a': [1, 2]}|12`,
+		`{{ ['a', 1] | string }}|{{ true | string }}|{{ {'a': [1, 2]} | string }}|{{ 12 | string }}`)
+}
+
+func TestBatchAndSliceFilters(t *testing.T) {
+	assertCompileOutput(t,
+		`1,2,3;4,5,x;|1,2,3;4,5,x;6,7,x;|1,2;3,4;5;`,
+		`
+{%- for row in [1, 2, 3, 4, 5] | batch(3, 'x') -%}{{ row | join(',') }};{%- endfor -%}|
+{%- for column in [1, 2, 3, 4, 5, 6, 7] | slice(3, 'x') -%}{{ column | join(',') }};{%- endfor -%}|
+{%- for column in [1, 2, 3, 4, 5] | slice(3) -%}{{ column | join(',') }};{%- endfor -%}
+`)
+}
+
+func TestDictFilters(t *testing.T) {
+	assertCompileOutput(t,
+		`a=3,B=1,c=2,|B=1,c=2,a=3,|a=3,c=2,B=1,|B=1,a=3,c=2,`,
+		`
+{%- set d = {'c': 2, 'a': 3, 'B': 1} -%}
+{%- for key, value in d | dictsort -%}{{ key }}={{ value }},{%- endfor -%}|
+{%- for key, value in d | dictsort(by='value') -%}{{ key }}={{ value }},{%- endfor -%}|
+{%- for key, value in d | dictsort(false, 'value', true) -%}{{ key }}={{ value }},{%- endfor -%}|
+{%- for key, value in d | items -%}{{ key }}={{ value }},{%- endfor -%}
+`)
+}
+
+func TestDefaultFilter(t *testing.T) {
+	assertCompileOutput(t,
+		`x|5|x|`,
+		`{{ undefined_var | default('x') }}|{{ undefined_var | default(5) }}|{{ '' | default('x', true) }}|{{ undefined_var | default }}`)
+}
+
+func TestMapMissingAttribute(t *testing.T) {
+	assertCompileOutput(t,
+		`[None, 2]|2|[0, 2]`,
+		`
+{%- set rows = [{'a': 1}, {'b': 2}] -%}
+{{ rows | map(attribute='b') | list }}|{{ rows | map(attribute='b') | list | length }}|{{ rows | map(attribute='b', default=0) | list }}`)
+}
@@ -1,6 +1,13 @@
 package tests
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/config"
+)
 
 func TestIsBoolean(t *testing.T) {
 	assertCompileOutput(t,
@@ -409,3 +416,169 @@ func TestReplace(t *testing.T) {
 {%- if c == "hello world" -%}Pass{% else %}Fail{% endif -%}
 `)
 }
+
+func TestToJSON(t *testing.T) {
+	assertCompileOutput(t,
+		`{"a": [1, 2.5, "three"], "b": true, "c": null, "d": "caf\u00e9 \"quoted\""}`,
+		`{{ tojson({"b": true, "a": [1, 2.5, "three"], "c": none, "d": 'café "quoted"'}) }}`,
+	)
+}
+
+func TestToJSONWithIndent(t *testing.T) {
+	assertCompileOutput(t,
+		"{\n  \"a\": [\n    1,\n    \"two\"\n  ],\n  \"b\": {}\n}|{\n--\"a\": 1\n}",
+		`{{ tojson({"b": {}, "a": [1, "two"]}, indent=2) }}|{{ tojson({"a": 1}, indent='--') }}`,
+	)
+}
+
+func TestFromJSON(t *testing.T) {
+	assertCompileOutput(t,
+		`1|two|TRUE|3|default`,
+		`
+{%- set parsed = fromjson('{"a": 1, "b": ["two", true], "c": {"d": 3}}') -%}
+{{ parsed.a }}|{{ parsed.b[0] }}|{{ parsed.b[1] }}|{{ parsed.c.d }}|{{ fromjson('not json', 'default') }}`,
+	)
+}
+
+func TestToYAML(t *testing.T) {
+	assertCompileOutput(t,
+		"a: 1\nb:\n- x\n- \"y\"\n",
+		`{{ toyaml({"b": ["x", "y"], "a": 1}) }}`,
+	)
+}
+
+func TestFromYAML(t *testing.T) {
+	assertCompileOutput(t,
+		`1|two|default`,
+		`
+{%- set parsed = fromyaml('{a: 1, b: [two]}') -%}
+{{ parsed.a }}|{{ parsed.b[0] }}|{{ fromyaml('a: [', 'default') }}`,
+	)
+}
+
+func TestAsNative(t *testing.T) {
+	assertCompileOutput(t,
+		`PassPassPassPassPass`,
+		`
+{%- if as_native('[1, 2]')[1] == 2 -%}Pass{% else %}Fail{% endif -%}
+{%- if as_native('3') + 1 == 4 -%}Pass{% else %}Fail{% endif -%}
+{%- if as_native('True') is boolean -%}Pass{% else %}Fail{% endif -%}
+{%- if as_native('None') is none -%}Pass{% else %}Fail{% endif -%}
+{%- if as_native('hello') == 'hello' -%}Pass{% else %}Fail{% endif -%}
+`)
+
+	// Only Python literals are converted; unlike in YAML, `yes`, `NO` and `a: b` stay strings and `010` isn't a number
+	assertCompileOutput(t,
+		`yes|NO|a: b|010|1000|-2.5|8|a b|TRUE|3`,
+		`{{ as_native('yes') }}|{{ as_native('NO') }}|{{ as_native('a: b') }}|{{ as_native('010') }}|{{ as_native('1_000') }}|{{ as_native(' -2.5 ') }}|{{ as_native('0o10') }}|{{ as_native("'a' ' b'") }}|{{ as_native('True') }}|{{ as_native('(1, 2, 3)') | length }}`)
+
+	assertCompileOutput(t,
+		`two|3|1|1|TRUE|0|x`,
+		`{{ as_native("[1, 'two', None]")[1] }}|{{ as_native("[1, 'two', None]") | length }}|{{ as_native('(1,)') | length }}|{{ as_native('(1)') }}|{{ as_native("{'a': True, }").a }}|{{ as_native('[]') | length }}|{{ as_native('x') }}`)
+
+	assertCompileOutput(t,
+		`[1, 2|{'a': 1|[1 2]||0x|'unterminated`,
+		`{{ as_native('[1, 2') }}|{{ as_native("{'a': 1") }}|{{ as_native('[1 2]') }}|{{ as_native('None') }}|{{ as_native('0x') }}|{{ as_native("'unterminated") }}`)
+}
+
+func TestInvocationIDAndRunStartedAt(t *testing.T) {
+	_, _, output := CompileFromRaw(t, `{{ invocation_id }}|{{ run_started_at.tzinfo }}|{{ run_started_at.year > 2000 }}`)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}\|UTC\|TRUE$`, output)
+}
+
+func TestFlags(t *testing.T) {
+	assertCompileOutput(t, `FALSE`, `{{ flags.FULL_REFRESH }}`)
+}
+
+func TestDatetime(t *testing.T) {
+	assertCompileOutput(t,
+		`2021-03-04 05:06:07|2021-03-04T05:06:07|2021|Thursday 04/03/21 05:06|3|4`,
+		`
+{%- set dt = modules.datetime.datetime(2021, 3, 4, 5, 6, 7) -%}
+{{ dt }}|{{ dt.isoformat() }}|{{ dt.year }}|{{ dt.strftime('%A %d/%m/%y %H:%M') }}|{{ dt.weekday() }}|{{ dt.isoweekday() }}`,
+	)
+}
+
+func TestDatetimeArithmetic(t *testing.T) {
+	assertCompileOutput(t,
+		`2021-03-01 12:00:00|2021-02-28 00:00:00|1 day, 12:00:00|2021-03-05|14 days, 0:00:00|-1 day, 23:00:00|TRUE`,
+		`
+{%- set dt = modules.datetime.datetime(2021, 2, 28) -%}
+{%- set later = dt + modules.datetime.timedelta(days=1, hours=12) -%}
+{%- set date = modules.datetime.date(2021, 2, 26) + modules.datetime.timedelta(weeks=1) -%}
+{{ later }}|{{ later - modules.datetime.timedelta(hours=36) }}|{{ later - dt }}|{{ date }}|
+{%- set fortnight = modules.datetime.timedelta(days=7) * 2 -%}
+{{ fortnight }}|{{ modules.datetime.timedelta(hours=-1) }}|{{ dt < later }}`,
+	)
+}
+
+func TestDatetimeParsing(t *testing.T) {
+	assertCompileOutput(t,
+		`2021-03-04 05:06:07|2021-03-04 05:06:07+01:00|2021-03-04 00:00:00|2021-03-04|2021-03-01`,
+		`
+{%- set parsed = modules.datetime.datetime.strptime('04/03/2021 05:06:07', '%d/%m/%Y %H:%M:%S') -%}
+{%- set iso = modules.datetime.datetime.fromisoformat('2021-03-04T05:06:07+01:00') -%}
+{{ parsed }}|{{ iso }}|{{ modules.datetime.datetime.fromisoformat('2021-03-04') }}|
+{%- set date = modules.datetime.datetime(2021, 3, 4, 5).date() -%}
+{{ date }}|{{ date.replace(day=1) }}`,
+	)
+}
+
+func TestDatetimeTime(t *testing.T) {
+	assertCompileOutput(t, `01:02:03`, `{{ modules.datetime.time(1, 2, 3) }}`)
+}
+
+func TestPytz(t *testing.T) {
+	assertCompileOutput(t,
+		`2021-07-01 12:00:00+01:00|Europe/London|2021-07-01 11:00:00+00:00|2021-01-01 00:00:00+05:30`,
+		`
+{%- set london = modules.pytz.timezone('Europe/London') -%}
+{%- set dt = london.localize(modules.datetime.datetime(2021, 7, 1, 12)) -%}
+{%- set india = modules.datetime.datetime(2021, 1, 1, tzinfo=modules.pytz.FixedOffset(330)) -%}
+{{ dt }}|{{ dt.tzinfo }}|{{ dt.astimezone(modules.pytz.utc) }}|{{ india }}`,
+	)
+}
+
+func TestRe(t *testing.T) {
+	assertCompileOutput(t,
+		`PassPassPass|2021|03|a-b-c|1,2,3|x_y_z|a,b,c|a-c|2021-03`,
+		`
+{%- if modules.re.match('[a-z]+', 'abc123') -%}Pass{% else %}Fail{% endif -%}
+{%- if not modules.re.match('[0-9]+', 'abc123') -%}Pass{% else %}Fail{% endif -%}
+{%- if modules.re.search('[0-9]+', 'abc123') -%}Pass{% else %}Fail{% endif -%}
+{%- set m = modules.re.search('(?P<year>\\d{4})-(\\d{2})', 'date: 2021-03') -%}
+|{{ m.group('year') }}|{{ m.group(2) }}|{{ modules.re.sub('\\s+', '-', 'a  b c') }}|
+{%- for n in modules.re.findall('\\d', 'a1b2c3') %}{{ n }}{% if not loop.last %},{% endif %}{% endfor -%}
+|{{ modules.re.compile('[.-]').sub('_', 'x.y-z') }}|
+{%- for part in modules.re.split('[,;]\\s*', 'a, b;c') %}{{ part }}{% if not loop.last %},{% endif %}{% endfor -%}
+|{{ modules.re.sub('B', '-', 'abc', flags=modules.re.IGNORECASE) }}|{{ modules.re.sub('(\\d{4})-(\\d{2})-\\d{2}', '\\1-\\2', '2021-03-04') }}`,
+	)
+}
+
+func TestDatetimeComparisons(t *testing.T) {
+	assertCompileOutput(t,
+		`TRUE|TRUE|TRUE|FALSE|TRUE|TRUE|FALSE|TRUE|TRUE`,
+		`
+{%- set datetime = modules.datetime -%}
+{%- set london = modules.pytz.timezone('Europe/London') -%}
+{%- set naive = datetime.datetime(2021, 1, 1) -%}
+{%- set aware = datetime.datetime(2021, 1, 1, tzinfo=modules.pytz.utc) -%}
+{{ datetime.timedelta(days=10) > datetime.timedelta(days=9) }}|{{ datetime.timedelta(days=1) > datetime.timedelta(hours=2) }}|{{ datetime.timedelta(hours=24) == datetime.timedelta(days=1) }}|{{ datetime.datetime(2021, 1, 1, 12, tzinfo=modules.pytz.FixedOffset(60)) > datetime.datetime(2021, 1, 1, 11, 30, tzinfo=modules.pytz.utc) }}|{{ datetime.datetime(2021, 7, 1, 11, tzinfo=modules.pytz.utc) == london.localize(datetime.datetime(2021, 7, 1, 12)) }}|{{ datetime.date(2021, 1, 9) < datetime.date(2021, 1, 10) }}|{{ naive == aware }}|{{ naive != aware }}|{{ datetime.datetime(2021, 1, 1, 9) <= datetime.datetime(2021, 1, 1, 10) }}`,
+	)
+}
+
+func TestInvalidDatetimes(t *testing.T) {
+	for template, expectedError := range map[string]string{
+		`{{ modules.datetime.datetime(2021, 13, 1) }}`:                                                                 "month must be in 1..12",
+		`{{ modules.datetime.datetime(2021, 2, 29) }}`:                                                                 "day is out of range for month",
+		`{{ modules.datetime.datetime(2021, 1, 1, 24) }}`:                                                              "hour must be in 0..23",
+		`{{ modules.datetime.date(2021, 4, 31) }}`:                                                                     "day is out of range for month",
+		`{{ modules.datetime.time(1, 60) }}`:                                                                           "minute must be in 0..59",
+		`{{ modules.datetime.datetime(2021, 1, 1).replace(second=60) }}`:                                               "second must be in 0..59",
+		`{{ modules.datetime.datetime(2021, 1, 1) < modules.datetime.datetime(2021, 1, 1, tzinfo=modules.pytz.utc) }}`: "can't compare offset-naive and offset-aware datetimes",
+	} {
+		_, err := compileTargetModelWithConfig(t, &config.Config{}, map[string]string{"models/target_model.sql": template})
+		require.Error(t, err, template)
+		assert.Contains(t, err.Error(), expectedError, template)
+	}
+}
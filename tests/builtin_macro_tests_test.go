@@ -175,3 +175,475 @@ SELECT COUNT(*) as num_errors FROM test_data
 `,
 	)
 }
+
+func TestTestWithWhereAndConfig(t *testing.T) {
+	schema := &properties.File{}
+	require.NoError(t, schema.Unmarshal([]byte(`version: 2
+models:
+  - name: target_model
+    columns:
+      - name: column_a
+        tests:
+          - not_null:
+              severity: warn
+              config:
+                warn_if: ">10"
+                where: "column_b = \"x\""
+`)), "Unable to parse schema YAML")
+
+	tests, err := schema.DefinedTests()
+	require.NoError(t, err)
+	require.Len(t, tests, 1, "Only expected 1 test to be generated")
+
+	fileSystem, gc, _ := CompileFromRaw(t, "SELECT 1 as column_a")
+
+	for testName, testContents := range tests {
+		file, err := fileSystem.AddTestWithContents(testName, testContents, true)
+		require.NoError(t, err, "Unable to add test file")
+		require.NoError(t, compiler.ParseFile(file), "Unable to parse test file")
+		require.NoError(t, compiler.CompileModel(file, gc, true), "Unable to compile test file")
+
+		assert.Equal(t, `
+WITH test_data AS (
+	SELECT
+	column_a AS value
+	
+	FROM (SELECT * FROM `+testTableRef+` WHERE column_b = "x")
+	
+	WHERE column_a IS NULL
+)
+
+SELECT COUNT(*) as num_errors FROM test_data
+`, file.CompiledContents, "Compiled test doesn't match")
+
+		assert.Equal(t, "warn", file.GetConfig("severity").AsStringValue())
+		assert.Equal(t, ">10", file.GetConfig("warn_if").AsStringValue())
+	}
+}
+
+func TestTestStoreFailures(t *testing.T) {
+	assertTestSchema(t,
+		`version: 2
+models:
+  - name: target_model
+    columns:
+      - name: column_a
+        tests:
+          - not_null:
+              store_failures: true
+`,
+		`
+WITH test_data AS (
+	SELECT
+	column_a AS value
+	
+	FROM `+testTableRef+`
+	
+	WHERE column_a IS NULL
+)
+
+SELECT * FROM test_data
+`,
+	)
+}
+
+func TestTestNotNullWhere(t *testing.T) {
+	assertTestSchema(t,
+		`version: 2
+models:
+  - name: target_model
+    columns:
+      - name: column_a
+        tests:
+          - not_null_where:
+              where: "column_b > 1"
+`,
+		`
+WITH test_data AS (
+	SELECT
+	column_a AS value
+	
+	FROM (SELECT * FROM `+testTableRef+` WHERE column_b > 1)
+	
+	WHERE column_a IS NULL
+)
+
+SELECT COUNT(*) as num_errors FROM test_data
+`,
+	)
+}
+
+func TestTestUniqueCombinationOfColumns(t *testing.T) {
+	assertTestSchema(t,
+		`version: 2
+models:
+  - name: target_model
+    tests:
+      - dbt_utils.unique_combination_of_columns:
+          combination_of_columns:
+            - column_a
+            - column_b
+`,
+		`
+WITH test_data AS (
+	SELECT
+	column_a,
+	column_b,
+	COUNT(*) AS count
+
+	FROM `+testTableRef+`
+
+	GROUP BY column_a, column_b
+
+	HAVING COUNT(*) > 1
+)
+
+SELECT COUNT(*) as num_errors FROM test_data
+`,
+	)
+}
+
+func TestTestExpressionIsTrue(t *testing.T) {
+	assertTestSchema(t,
+		`version: 2
+models:
+  - name: target_model
+    tests:
+      - expression_is_true:
+          expression: "column_a > column_b"
+`,
+		`
+WITH test_data AS (
+	SELECT
+	*
+
+	FROM `+testTableRef+`
+
+	WHERE NOT(column_a > column_b)
+)
+
+SELECT COUNT(*) as num_errors FROM test_data
+`,
+	)
+}
+
+func TestTestExpressionIsTrueOnColumn(t *testing.T) {
+	assertTestSchema(t,
+		`version: 2
+models:
+  - name: target_model
+    columns:
+      - name: column_a
+        tests:
+          - expression_is_true:
+              expression: ">= 0"
+`,
+		`
+WITH test_data AS (
+	SELECT
+	*
+
+	FROM `+testTableRef+`
+
+	WHERE NOT(column_a >= 0)
+)
+
+SELECT COUNT(*) as num_errors FROM test_data
+`,
+	)
+}
+
+func TestTestRecency(t *testing.T) {
+	assertTestSchema(t,
+		`version: 2
+models:
+  - name: target_model
+    tests:
+      - recency:
+          field: created_at
+          datepart: day
+          interval: 1
+`,
+		`
+WITH recency AS (
+	SELECT
+	MAX(CAST(created_at AS DATETIME)) AS most_recent
+
+	FROM `+testTableRef+`
+),
+
+test_data AS (
+	SELECT
+	most_recent
+
+	FROM recency
+
+	WHERE most_recent IS NULL OR most_recent < DATETIME_SUB(CURRENT_DATETIME(), INTERVAL 1 day)
+)
+
+SELECT COUNT(*) as num_errors FROM test_data
+`,
+	)
+}
+
+func TestTestAtLeastOne(t *testing.T) {
+	assertTestSchema(t,
+		`version: 2
+models:
+  - name: target_model
+    columns:
+      - name: column_a
+        tests:
+          - at_least_one
+`,
+		`
+WITH test_data AS (
+	SELECT
+	COUNT(column_a) AS count
+
+	FROM `+testTableRef+`
+
+	HAVING COUNT(column_a) = 0
+)
+
+SELECT COUNT(*) as num_errors FROM test_data
+`,
+	)
+}
+
+func TestTestNotConstant(t *testing.T) {
+	assertTestSchema(t,
+		`version: 2
+models:
+  - name: target_model
+    columns:
+      - name: column_a
+        tests:
+          - not_constant
+`,
+		`
+WITH test_data AS (
+	SELECT
+	COUNT(DISTINCT column_a) AS count
+
+	FROM `+testTableRef+`
+
+	HAVING COUNT(DISTINCT column_a) = 1
+)
+
+SELECT COUNT(*) as num_errors FROM test_data
+`,
+	)
+}
+
+func TestTestEqualRowcount(t *testing.T) {
+	assertTestSchema(t,
+		`version: 2
+models:
+  - name: target_model
+    tests:
+      - equal_rowcount:
+          compare_model: ref('target_model')
+`,
+		`
+WITH model_count AS (
+	SELECT COUNT(*) AS count FROM `+testTableRef+`
+),
+
+compare_model_count AS (
+	SELECT COUNT(*) AS count FROM `+testTableRef+`
+),
+
+test_data AS (
+	SELECT
+	model_count.count AS model_count,
+	compare_model_count.count AS compare_model_count
+
+	FROM model_count
+	CROSS JOIN compare_model_count
+
+	WHERE model_count.count != compare_model_count.count
+)
+
+SELECT COUNT(*) as num_errors FROM test_data
+`,
+	)
+}
+
+func TestTestAcceptedRange(t *testing.T) {
+	assertTestSchema(t,
+		`version: 2
+models:
+  - name: target_model
+    columns:
+      - name: column_a
+        tests:
+          - accepted_range:
+              min_value: 0
+              max_value: 10
+`,
+		`
+WITH test_data AS (
+	SELECT
+	column_a AS value
+
+	FROM `+testTableRef+`
+
+	WHERE 1 = 2
+	OR NOT(column_a >= 0)
+	OR NOT(column_a <= 10)
+)
+
+SELECT COUNT(*) as num_errors FROM test_data
+`,
+	)
+}
+
+func TestTestAcceptedRangeExclusive(t *testing.T) {
+	assertTestSchema(t,
+		`version: 2
+models:
+  - name: target_model
+    columns:
+      - name: column_a
+        tests:
+          - accepted_range:
+              min_value: 0
+              inclusive: false
+`,
+		`
+WITH test_data AS (
+	SELECT
+	column_a AS value
+
+	FROM `+testTableRef+`
+
+	WHERE 1 = 2
+	OR NOT(column_a > 0)
+)
+
+SELECT COUNT(*) as num_errors FROM test_data
+`,
+	)
+}
+
+func TestTestMutuallyExclusiveRanges(t *testing.T) {
+	assertTestSchema(t,
+		`version: 2
+models:
+  - name: target_model
+    tests:
+      - mutually_exclusive_ranges:
+          lower_bound_column: started_at
+          upper_bound_column: ended_at
+          partition_by: customer_id
+          gaps: not_allowed
+`,
+		`
+WITH window_functions AS (
+	SELECT
+	customer_id AS partition_by_col,
+	started_at AS lower_bound,
+	ended_at AS upper_bound,
+
+	LEAD(started_at) OVER (
+		PARTITION BY customer_id ORDER BY started_at, ended_at
+	) AS next_lower_bound,
+
+	ROW_NUMBER() OVER (
+		PARTITION BY customer_id ORDER BY started_at DESC, ended_at DESC
+	) = 1 AS is_last_record
+
+	FROM `+testTableRef+`
+),
+
+calc AS (
+	SELECT
+	*,
+	COALESCE(lower_bound < upper_bound, FALSE) AS lower_bound_less_than_upper_bound,
+	COALESCE(upper_bound = next_lower_bound, is_last_record, FALSE) AS upper_bound_before_next_lower_bound
+
+	FROM window_functions
+),
+
+test_data AS (
+	SELECT
+	*
+
+	FROM calc
+
+	WHERE NOT(lower_bound_less_than_upper_bound AND upper_bound_before_next_lower_bound)
+)
+
+SELECT COUNT(*) as num_errors FROM test_data
+`,
+	)
+}
+
+func TestTestSequentialValues(t *testing.T) {
+	assertTestSchema(t,
+		`version: 2
+models:
+  - name: target_model
+    columns:
+      - name: column_a
+        tests:
+          - sequential_values:
+              interval: 2
+`,
+		`
+WITH windowed AS (
+	SELECT
+	column_a AS value,
+	LAG(column_a) OVER (ORDER BY column_a) AS previous_value
+
+	FROM `+testTableRef+`
+),
+
+test_data AS (
+	SELECT
+	*
+
+	FROM windowed
+
+	WHERE NOT(value = previous_value + 2)
+)
+
+SELECT COUNT(*) as num_errors FROM test_data
+`,
+	)
+}
+
+func TestTestSequentialDates(t *testing.T) {
+	assertTestSchema(t,
+		`version: 2
+models:
+  - name: target_model
+    columns:
+      - name: column_a
+        tests:
+          - sequential_values:
+              datepart: day
+`,
+		`
+WITH windowed AS (
+	SELECT
+	column_a AS value,
+	LAG(column_a) OVER (ORDER BY column_a) AS previous_value
+
+	FROM `+testTableRef+`
+),
+
+test_data AS (
+	SELECT
+	*
+
+	FROM windowed
+
+	WHERE NOT(CAST(value AS DATETIME) = DATETIME_ADD(CAST(previous_value AS DATETIME), INTERVAL 1 day))
+)
+
+SELECT COUNT(*) as num_errors FROM test_data
+`,
+	)
+}
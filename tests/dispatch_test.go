@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/config"
+)
+
+const dispatchingMacro = `{% macro cast_int(x) %}{{ return(adapter.dispatch('cast_int')(x)) }}{% endmacro %}`
+
+func TestDispatchToDefaultImplementation(t *testing.T) {
+	model := compileTargetModel(t, map[string]string{
+		"models/target_model.sql": `{{ cast_int('a') }}`,
+		"macros/cast_int.sql":     dispatchingMacro + `{% macro default__cast_int(x) %}CAST({{ x }} AS INT){% endmacro %}`,
+	})
+
+	assert.Equal(t, "CAST(a AS INT)", model.CompiledContents)
+}
+
+func TestDispatchPrefersTheAdapterImplementation(t *testing.T) {
+	model := compileTargetModel(t, map[string]string{
+		"models/target_model.sql": `{{ cast_int('a') }}`,
+		"macros/cast_int.sql":     dispatchingMacro + `{% macro default__cast_int(x) %}CAST({{ x }} AS INT){% endmacro %}{% macro bigquery__cast_int(x) %}CAST({{ x }} AS INT64){% endmacro %}`,
+	})
+
+	assert.Equal(t, "CAST(a AS INT64)", model.CompiledContents)
+}
+
+var dispatchPackageFiles = map[string]string{
+	"models/target_model.sql":                   `{{ team_utils.star() }}|{{ adapter.dispatch('star')() }}`,
+	"macros/bigquery__star.sql":                 `{% macro bigquery__star() %}project{% endmacro %}`,
+	"dbt_packages/team_utils/macros/star.sql":   `{% macro star() %}{{ return(adapter.dispatch('star', 'team_utils')()) }}{% endmacro %}{% macro default__star() %}*{% endmacro %}`,
+	"dbt_packages/team_utils/macros/unique.sql": `{% macro unique() %}{{ return(adapter.dispatch('unique', macro_namespace='team_utils')()) }}{% endmacro %}`,
+}
+
+func TestDispatchSearchesTheNamespace(t *testing.T) {
+	model := compileTargetModel(t, dispatchPackageFiles)
+
+	// Without a namespace the project is searched first
+	assert.Equal(t, "*|project", model.CompiledContents)
+}
+
+func TestDispatchSearchOrderFromConfig(t *testing.T) {
+	model, err := compileTargetModelWithConfig(t, &config.Config{
+		Dispatch: map[string][]string{"team_utils": {"Unit Test", "team_utils"}},
+	}, dispatchPackageFiles)
+	require.NoError(t, err)
+
+	assert.Equal(t, "project|project", model.CompiledContents)
+}
+
+func TestDispatchWithoutAnImplementation(t *testing.T) {
+	files := map[string]string{"models/target_model.sql": `{{ team_utils.unique() }}`}
+	for name, contents := range dispatchPackageFiles {
+		if name != "models/target_model.sql" {
+			files[name] = contents
+		}
+	}
+
+	_, err := compileTargetModelWithConfig(t, &config.Config{}, files)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "In dispatch: No macro named 'unique' found within namespace: 'team_utils'; searched for: 'team_utils.bigquery__unique', 'team_utils.default__unique'")
+}
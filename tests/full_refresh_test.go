@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"ddbt/config"
+)
+
+func TestIsMarkedForFullRefresh(t *testing.T) {
+	assertCompileOutput(t, "FALSE", `{{ is_marked_for_full_refresh() }}`)
+	assertCompileOutput(t, "TRUE", `{{ config(full_refresh=true) }}{{ is_marked_for_full_refresh() }}`)
+	assertCompileOutput(t, "FALSE", `{{ config(full_refresh=false) }}{{ is_marked_for_full_refresh() }}`)
+}
+
+func TestFullRefreshFlag(t *testing.T) {
+	fileSystem, _, _ := CompileFromRaw(t, `SELECT 1`)
+	model := fileSystem.Model("target_model")
+
+	assert.False(t, model.IsFullRefresh())
+
+	config.GlobalCfg.FullRefresh = true
+	defer func() { config.GlobalCfg.FullRefresh = false }()
+
+	assert.True(t, model.IsFullRefresh(), "--full-refresh should apply to models without a full_refresh config")
+
+	fileSystem, _, _ = CompileFromRaw(t, `{{ config(full_refresh=false) }}SELECT 1`)
+	protected := fileSystem.Model("target_model")
+
+	config.GlobalCfg.FullRefresh = true
+	assert.False(t, protected.IsFullRefresh(), "full_refresh=false should protect the model from --full-refresh")
+}
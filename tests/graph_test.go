@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/compiler"
+	"ddbt/config"
+	"ddbt/fs"
+	"ddbt/utils"
+)
+
+// Builds a graph of the models and tests, with the tests gating the downstreams of the models they test
+func buildTestGraph(t *testing.T, models map[string]string, tests map[string]string) *fs.Graph {
+	fileSystem, err := fs.InMemoryFileSystem(models)
+	require.NoError(t, err, "Unable to construct in memory file system")
+
+	for name, contents := range tests {
+		_, err := fileSystem.AddTestWithContents(name, contents, false)
+		require.NoError(t, err)
+	}
+
+	config.GlobalCfg = &config.Config{
+		Name: "Unit Test",
+		Target: &config.Target{
+			Name:      "unit_test",
+			ProjectID: "unit_test_project",
+			DataSet:   "unit_test_dataset",
+			Location:  "US",
+			Threads:   4,
+		},
+	}
+	gc, err := compiler.NewGlobalContext(config.GlobalCfg, fileSystem)
+	require.NoError(t, err, "Unable to create global context")
+
+	files := append(fileSystem.AllFiles(), fileSystem.Tests()...)
+	for _, file := range files {
+		require.NoError(t, parseFile(file), "Unable to parse %s", file.Name)
+	}
+
+	for _, file := range files {
+		if file.Type == fs.ModelFile || file.Type == fs.TestFile {
+			require.NoError(t, compiler.CompileModel(file, gc, false), "Unable to compile %s", file.Name)
+		}
+	}
+
+	graph := fs.NewGraph()
+	require.NoError(t, graph.AddAllModels(fileSystem))
+	graph.AddReferencingTests()
+	graph.AddTestsAsGates()
+
+	return graph
+}
+
+func fileNames(files []*fs.File) []string {
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file.Name
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestExecuteSkippingDownstreams(t *testing.T) {
+	graph := buildTestGraph(
+		t,
+		map[string]string{
+			"models/model_a.sql": "SELECT 1",
+			"models/model_b.sql": "SELECT * FROM {{ ref('model_a') }}",
+			"models/model_c.sql": "SELECT * FROM {{ ref('model_b') }}",
+			"models/model_d.sql": "SELECT * FROM {{ ref('model_a') }}",
+		},
+		map[string]string{
+			"test_b": "SELECT * FROM {{ ref('model_b') }}",
+		},
+	)
+
+	var m sync.Mutex
+	ran := make([]*fs.File, 0)
+
+	pb := utils.NewProgressBar("Testing", graph.Len())
+	failed, skipped := graph.ExecuteSkippingDownstreams(func(file *fs.File) error {
+		m.Lock()
+		ran = append(ran, file)
+		m.Unlock()
+
+		if file.Name == "test_b" {
+			return errors.New("test failed")
+		}
+
+		return nil
+	}, 2, pb)
+	pb.Stop()
+
+	require.Len(t, failed, 1)
+	for file, err := range failed {
+		assert.Equal(t, "test_b", file.Name)
+		assert.EqualError(t, err, "test failed")
+	}
+
+	// model_c is downstream of model_b, so must wait for model_b's tests to pass
+	assert.Equal(t, []string{"model_c"}, fileNames(skipped))
+	assert.Equal(t, []string{"model_a", "model_b", "model_d", "test_b"}, fileNames(ran))
+}
+
+func TestTestGatesDoNotCreateCycles(t *testing.T) {
+	// The relationship test references both models, so can't gate model_b
+	graph := buildTestGraph(
+		t,
+		map[string]string{
+			"models/model_a.sql": "SELECT 1",
+			"models/model_b.sql": "SELECT * FROM {{ ref('model_a') }}",
+		},
+		map[string]string{
+			"test_relationship": "SELECT * FROM {{ ref('model_a') }} JOIN {{ ref('model_b') }} USING (id)",
+		},
+	)
+
+	pb := utils.NewProgressBar("Testing", graph.Len())
+	failed, skipped := graph.ExecuteSkippingDownstreams(func(file *fs.File) error {
+		return nil
+	}, 2, pb)
+	pb.Stop()
+
+	assert.Empty(t, failed)
+	assert.Empty(t, skipped)
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/compiler"
+	"ddbt/config"
+	"ddbt/fs"
+)
+
+var helperMacros = `{% macro greet(name) %}hi {{ name }}{% endmacro %}{% macro shout(name) %}{{ name | upper }}!{% endmacro %}`
+
+func TestImportMacroFile(t *testing.T) {
+	model := compileTargetModel(t, map[string]string{
+		"models/target_model.sql":  `{% import 'helpers.sql' as h %}{{ h.greet('bob') }}|{{ h.shout('bob') }}`,
+		"macros/utils/helpers.sql": helperMacros,
+	})
+
+	assert.Equal(t, "hi bob|BOB!", model.CompiledContents)
+}
+
+func TestFromImportMacros(t *testing.T) {
+	model := compileTargetModel(t, map[string]string{
+		"models/target_model.sql": `{% from 'macros/helpers.sql' import greet, shout as yell with context %}{{ greet('ann') }}|{{ yell('ann') }}`,
+		"macros/helpers.sql":      helperMacros,
+	})
+
+	assert.Equal(t, "hi ann|ANN!", model.CompiledContents)
+}
+
+func TestImportsInMacroFiles(t *testing.T) {
+	model := compileTargetModel(t, map[string]string{
+		"models/target_model.sql": `{{ welcome('cat') }}`,
+		"macros/helpers.sql":      helperMacros,
+		"macros/welcome.sql":      `{% import 'helpers' as h %}{% macro welcome(name) %}{{ h.greet(name) }}{% endmacro %}`,
+	})
+
+	assert.Equal(t, "hi cat", model.CompiledContents)
+}
+
+func TestImportErrors(t *testing.T) {
+	files := map[string]string{"macros/helpers.sql": helperMacros}
+
+	files["models/target_model.sql"] = `{% import 'missing.sql' as m %}`
+	_, err := compileTargetModelWithConfig(t, &config.Config{}, files)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to find macro file `missing.sql`")
+
+	files["models/target_model.sql"] = `{% from 'helpers.sql' import whisper %}`
+	_, err = compileTargetModelWithConfig(t, &config.Config{}, files)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the macro `whisper` is not defined in helpers.sql")
+}
+
+func TestPackageMacrosHaveTheirOwnNamespace(t *testing.T) {
+	model := compileTargetModel(t, map[string]string{
+		"models/target_model.sql":                 `{{ helper() }}|{{ team_a.helper() }}|{{ team_b.helper() }}|{{ team_a.describe() }}|{{ team_b.shared() }}`,
+		"macros/helper.sql":                       `{% macro helper() %}root{% endmacro %}`,
+		"dbt_packages/team_a/macros/helper.sql":   `{% macro helper() %}a{% endmacro %}`,
+		"dbt_packages/team_a/macros/describe.sql": `{% macro describe() %}team {{ helper() }}{% endmacro %}`,
+		"dbt_packages/team_b/macros/helper.sql":   `{% macro helper() %}b{% endmacro %}{% macro shared() %}shared {{ helper() }}{% endmacro %}`,
+	})
+
+	assert.Equal(t, "root|a|b|team a|shared b", model.CompiledContents)
+}
+
+func TestPackageMacrosAreNotGlobal(t *testing.T) {
+	model := compileTargetModel(t, map[string]string{
+		"models/target_model.sql":              `{% if only_in_package is defined %}global{% else %}packaged{% endif %}|{{ my_package.only_in_package() }}`,
+		"dbt_packages/my_package/macros/x.sql": `{% macro only_in_package() %}x{% endmacro %}`,
+	})
+
+	assert.Equal(t, "packaged|x", model.CompiledContents)
+}
+
+func TestInstalledPackageReplacesBuiltInNamespace(t *testing.T) {
+	model := compileTargetModel(t, map[string]string{
+		"models/target_model.sql":                  `{{ dbt_utils.date_spine() }}|{{ dbt_utils.group_by(2) }}`,
+		"dbt_packages/dbt_utils/macros/spine.sql":  `{% macro date_spine() %}spine{% endmacro %}`,
+		"dbt_packages/dbt_utils/macros/unused.sql": `{% macro unused() %}{% endmacro %}`,
+	})
+
+	// Built in functions the package doesn't define are still available
+	assert.Equal(t, "spine| GROUP BY 1, 2 ", model.CompiledContents)
+}
+
+func TestMacroFilesWithTheSameName(t *testing.T) {
+	model := compileTargetModel(t, map[string]string{
+		"models/target_model.sql":   `{% import 'team_a/helpers.sql' as a %}{% import 'team_b/helpers.sql' as b %}{{ a.helper() }}|{{ b.other_helper() }}`,
+		"macros/team_a/helpers.sql": `{% macro helper() %}a{% endmacro %}`,
+		"macros/team_b/helpers.sql": `{% macro other_helper() %}b{% endmacro %}`,
+	})
+
+	assert.Equal(t, "a|b", model.CompiledContents)
+}
+
+// Parses the files and creates a global context, without compiling anything
+func newMacroTestContext(t *testing.T, files map[string]string) (*fs.FileSystem, *compiler.GlobalContext) {
+	fileSystem, err := fs.InMemoryFileSystem(files)
+	require.NoError(t, err)
+
+	for _, file := range fileSystem.AllFiles() {
+		require.NoError(t, parseFile(file))
+	}
+
+	config.GlobalCfg = &config.Config{Name: "Unit Test", Target: &config.Target{ProjectID: "unit_test_project", DataSet: "unit_test_dataset"}}
+	gc, err := compiler.NewGlobalContext(config.GlobalCfg, fileSystem)
+	require.NoError(t, err)
+
+	return fileSystem, gc
+}
+
+func TestDuplicateMacroNames(t *testing.T) {
+	fileSystem, gc := newMacroTestContext(t, map[string]string{
+		"macros/a/cents.sql": `{% macro cents(x) %}{{ x }} * 100{% endmacro %}`,
+		"macros/b/cents.sql": `{% macro cents(x) %}{{ x }} / 100{% endmacro %}`,
+	})
+
+	require.NoError(t, compiler.CompileModel(fileSystem.MacroAt("macros/a/cents.sql"), gc, false))
+
+	err := compiler.CompileModel(fileSystem.MacroAt("macros/b/cents.sql"), gc, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the macro cents is defined in both macros/a/cents.sql and macros/b/cents.sql")
+}
+
+func TestProjectMacrosReplaceBuiltInMacros(t *testing.T) {
+	fileSystem, gc := newMacroTestContext(t, map[string]string{
+		"models/target_model.sql": `{{ test_result('cte') }}`,
+		"macros/test_result.sql":  `{% macro test_result(cte) %}SELECT 1 FROM {{ cte }}{% endmacro %}`,
+	})
+
+	// The built in macros are compiled after the project's
+	require.NoError(t, compiler.CompileModel(fileSystem.Macro("test_result"), gc, false))
+	require.NoError(t, compiler.CompileModel(fileSystem.Macro("built-in-macros"), gc, false))
+
+	model := fileSystem.Model("target_model")
+	require.NoError(t, compiler.CompileModel(model, gc, false))
+	assert.Equal(t, "SELECT 1 FROM cte", model.CompiledContents)
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/config"
+)
+
+func assertCompileError(t *testing.T, input, expectedError string) {
+	_, err := compileTargetModelWithConfig(t, &config.Config{}, map[string]string{"models/target_model.sql": input})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), expectedError)
+}
+
+func TestStringMethods(t *testing.T) {
+	assertCompileOutput(t,
+		`ABC|abc|a;b;c|a;b c|x;y|abc|abc  |abc|TRUEFALSETRUE|FALSETRUE|b-b-b|b-a-a|a, b|a-b-c`,
+		`
+{%- set s = 'aBc' -%}
+{{ s.upper() }}|{{ s.lower() }}|{{ 'a  b c'.split() | join(';') }}|{{ 'a  b c'.split(none, 1) | join(';') }}|{{ 'x,y'.split(',') | join(';') }}|
+{%- set padded = '  abc  ' -%}
+{{ padded.strip() }}|{{ padded.lstrip() }}|{{ 'xxabcxx'.strip('x') }}|
+{%- set file = 'model.sql' -%}
+{{ file.startswith('model') }}{{ file.startswith('sql') }}{{ file.endswith('.sql') }}|{{ file.endswith(['.csv', '.yml']) }}{{ file.endswith(['.csv', '.sql']) }}|
+{%- set dashes = 'a-a-a' -%}
+{{ dashes.replace('a', 'b') }}|{{ 'a-a-a'.replace('a', 'b', 1) }}|{{ ', '.join(['a', 'b']) }}|{{ '-'.join('abc') }}`)
+}
+
+func TestStringFormat(t *testing.T) {
+	assertCompileOutput(t,
+		`a and b|b then a|x=1, y=2|{literal}|3.14|  7|7  |0042|50.0%|ab`,
+		`
+{%- set braces = '{{literal}}' -%}
+{{ '{} and {}'.format('a', 'b') }}|{{ '{1} then {0}'.format('a', 'b') }}|{{ 'x={x}, y={y}'.format(y=2, x=1) }}|{{ braces.format() }}|{{ '{:.2f}'.format(3.14159) }}|{{ '{:>3}'.format(7) }}|{{ '{:<3}'.format(7) }}|{{ '{:0>4}'.format(42) }}|{{ '{:.1%}'.format(0.5) }}|{{ '{:.2}'.format('abc') }}`)
+}
+
+func TestStringFormatErrors(t *testing.T) {
+	assertCompileError(t, `{{ '{0} {}'.format('a', 'b') }}`, "cannot switch from manual field specification to automatic field numbering")
+	assertCompileError(t, `{{ '{} {0}'.format('a', 'b') }}`, "cannot switch from automatic field numbering to manual field specification")
+}
+
+func TestMethodsOnLiterals(t *testing.T) {
+	assertCompileOutput(t,
+		`ABC|bc|c|b|3|b|a|2|AB`,
+		`{{ 'abc'.upper() }}|{{ 'abc'[1:] }}|{{ 'abc'[-1] }}|{{ ['a', 'b', 'c'][1] }}|{{ [1, 2, 3][-1] }}|{{ {'a': 'b'}['a'] }}|{{ {'a': 1}.keys() | first }}|{{ ['a', 'b'].index('b') + 1 }}|{{ ('a' ~ 'b').upper() }}`)
+}
+
+func TestNegativeIndexes(t *testing.T) {
+	assertCompileOutput(t,
+		`c|a|z`,
+		`{% set l = ['a', 'b', 'c'] %}{% set s = 'xyz' %}{{ l[-1] }}|{{ l[-3] }}|{{ s[-1] }}`)
+
+	assertCompileError(t, `{% set l = ['a'] %}{{ l[-2] }}`, "index out of range, got -2 for a length of 1")
+	assertCompileError(t, `{% set l = ['a'] %}{{ l[1] }}`, "index out of range, got 1 for a length of 1")
+}
+
+func TestStringSlicing(t *testing.T) {
+	assertCompileOutput(t,
+		`bc|ab|cd|db|dcba|abcd|`,
+		`
+{%- set s = 'abcd' -%}
+{{ s[1:3] }}|{{ s[:2] }}|{{ s[-2:] }}|{{ s[::-2] }}|{{ s[::-1] }}|{{ s[:] }}|{{ s[10:] }}`)
+}
+
+func TestListMethods(t *testing.T) {
+	assertCompileOutput(t,
+		`a,b,c,d|d|a,b,c|a|b,c|1|2|x,b,y,c,z|x,y,c,z|y,c`,
+		`
+{%- set list = ['a', 'b'] -%}
+{%- set alias = list -%}
+{%- do list.append('c') -%}
+{%- do alias.extend(['d']) -%}
+{{ list | join(',') }}|{{ list.pop() }}|{{ alias | join(',') }}|{{ list.pop(0) }}|{{ alias | join(',') }}|{{ list.index('c') }}|
+{%- do list.append('c') -%}
+{{ list.count('c') }}|
+{%- do list.remove('c') -%}
+{%- do list.insert(0, 'x') -%}
+{%- do list.insert(-1, 'y') -%}
+{%- do list.insert(100, 'z') -%}
+{{ list | join(',') }}|
+{%- do list.remove('b') -%}
+{{ list | join(',') }}|{{ list[1:3] | join(',') }}`)
+}
+
+func TestListMethodErrors(t *testing.T) {
+	assertCompileError(t, `{% set l = [] %}{{ l.pop() }}`, "pop from empty list")
+	assertCompileError(t, `{% set l = ['a'] %}{{ l.index('b') }}`, "b is not in list")
+	assertCompileError(t, `{% set l = ['a'] %}{% do l.remove('b') %}`, "b not in list")
+}
+
+func TestMapMethods(t *testing.T) {
+	assertCompileOutput(t,
+		`a,b|1,2|a=1,b=2,|1|none|3|a,b,c,d|2|a,c,d|4|x|x|a,c,d,e`,
+		`
+{%- set m = {'b': 2, 'a': 1} -%}
+{%- set alias = m -%}
+{{ m.keys() | join(',') }}|{{ m.values() | join(',') }}|
+{%- for key, value in m.items() -%}{{ key }}={{ value }},{%- endfor -%}|{{ m.get('a') }}|{{ m.get('z', 'none') }}|
+{%- do m.update({'c': 3}, d=4) -%}
+{{ alias.c }}|{{ alias.keys() | join(',') }}|{{ m.pop('b') }}|{{ alias.keys() | join(',') }}|{{ m.pop('b', 4) }}|{{ m.setdefault('e', 'x') }}|{{ m.setdefault('e', 'y') }}|{{ alias.keys() | join(',') }}`)
+}
+
+func TestMapPopOfMissingKeyIsAnError(t *testing.T) {
+	assertCompileError(t, `{% set m = {'a': 1} %}{{ m.pop('b') }}`, "KeyError: 'b'")
+}
@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/fs"
+)
+
+var packageModelFiles = map[string]string{
+	"models/target_model.sql":                   `{{ ref('users') }}|{{ ref('my_package', 'events') }}|{{ ref('events') }}|{{ ref('Unit Test', 'users') }}`,
+	"models/users.sql":                          `SELECT 'project'`,
+	"dbt_packages/my_package/models/users.sql":  `SELECT 'package'`,
+	"dbt_packages/my_package/models/events.sql": `SELECT * FROM {{ ref('users') }}`,
+}
+
+func TestProjectModelsReplacePackageModels(t *testing.T) {
+	// The in memory file system adds the files in a random order, so try a few times
+	for i := 0; i < 10; i++ {
+		fileSystem, err := fs.InMemoryFileSystem(packageModelFiles)
+		require.NoError(t, err)
+
+		require.NotNil(t, fileSystem.Model("users"))
+		assert.Equal(t, "", fileSystem.Model("users").Package)
+		assert.Nil(t, fileSystem.Model("my_package.users"))
+		assert.Len(t, fileSystem.Models(), 3)
+
+		require.NotNil(t, fileSystem.Model("my_package.events"))
+		assert.Equal(t, fileSystem.Model("events"), fileSystem.Model("my_package.events"))
+	}
+}
+
+func TestRefPackageModels(t *testing.T) {
+	model := compileTargetModel(t, packageModelFiles)
+
+	assert.Equal(
+		t,
+		"`unit_test_project`.`unit_test_dataset`.`users`|`unit_test_project`.`unit_test_dataset`.`events`|`unit_test_project`.`unit_test_dataset`.`events`|`unit_test_project`.`unit_test_dataset`.`users`",
+		model.CompiledContents,
+	)
+}
+
+func TestModelsInTwoPackagesWithTheSameName(t *testing.T) {
+	_, err := fs.InMemoryFileSystem(map[string]string{
+		"dbt_packages/package_a/models/users.sql": `SELECT 1`,
+		"dbt_packages/package_b/models/users.sql": `SELECT 2`,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already in lookup")
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/compiler"
+	"ddbt/config"
+	"ddbt/fs"
+)
+
+// Compiles target_model the same way the compiler does for a run, so `this` is defined
+func compileTargetModel(t *testing.T, files map[string]string) *fs.File {
+	model, err := compileTargetModelWithConfig(t, &config.Config{}, files)
+	require.NoError(t, err)
+
+	return model
+}
+
+func compileTargetModelWithConfig(t *testing.T, cfg *config.Config, files map[string]string) (*fs.File, error) {
+	fileSystem, err := fs.InMemoryFileSystem(files)
+	require.NoError(t, err)
+
+	for _, file := range fileSystem.AllFiles() {
+		require.NoError(t, parseFile(file))
+	}
+
+	cfg.Name = "Unit Test"
+	cfg.Target = &config.Target{
+		Name:      "unit_test",
+		ProjectID: "unit_test_project",
+		DataSet:   "unit_test_dataset",
+		Location:  "US",
+		Threads:   4,
+	}
+	config.GlobalCfg = cfg
+
+	gc, err := compiler.NewGlobalContext(config.GlobalCfg, fileSystem)
+	require.NoError(t, err)
+
+	model := fileSystem.Model("target_model")
+	return model, compiler.CompileModel(model, gc, false)
+}
+
+func TestThisIsARelation(t *testing.T) {
+	model := compileTargetModel(t, map[string]string{
+		"models/target_model.sql": "{{ this }}|{{ this.schema }}.{{ this.table }}|{{ this.database }}|{{ this.type }}|{{ this.is_table }}",
+	})
+
+	assert.Equal(t, "`unit_test_project`.`unit_test_dataset`.`target_model`|unit_test_dataset.target_model|unit_test_project|table|TRUE", model.CompiledContents)
+}
+
+func TestRefIsARelation(t *testing.T) {
+	model := compileTargetModel(t, map[string]string{
+		"models/target_model.sql": "{% set upstream = ref('upstream') %}{{ upstream }}|{{ upstream.identifier }}|{{ upstream.is_table }}",
+		"models/upstream.sql":     "SELECT 1",
+	})
+
+	assert.Equal(t, "`unit_test_project`.`unit_test_dataset`.`upstream`|upstream|TRUE", model.CompiledContents)
+}
+
+func TestRelationRender(t *testing.T) {
+	relation := `{% set relation = api.Relation.create(database="project", schema="dataset", identifier="table") %}`
+
+	assertCompileOutput(t, "`project`.`dataset`.`table`", relation+`{{ relation.render() }}`)
+	assertCompileOutput(t, "`dataset`.`table`", relation+`{{ relation.include(database=False) }}`)
+	assertCompileOutput(t, "`table`", relation+`{{ relation.include(database=False, schema=False) }}`)
+	assertCompileOutput(t, "`project`.`other`.`table`", relation+`{{ relation.incorporate(path={"schema": "other"}) }}`)
+	assertCompileOutput(t, "table view", relation+`{{ relation.type }} {{ relation.incorporate(type="view").type }}`)
+	assertCompileOutput(t, "FALSE TRUE", relation+`{{ relation.is_view }} {{ relation.is_table }}`)
+}
+
+func TestGetRelationIsDeferredUntilExecution(t *testing.T) {
+	fileSystem, _, output := CompileFromRaw(t, `{% set relation = adapter.get_relation('project', 'dataset', 'other') %}SELECT 1`)
+	assert.Equal(t, "SELECT 1", output)
+	assert.True(t, fileSystem.Model("target_model").IsDynamicSQL(), "get_relation should mark the model to be recompiled at execution")
+}
+
+func TestDropRelationIsDeferredUntilExecution(t *testing.T) {
+	fileSystem, _, output := CompileFromRaw(t, "{% do adapter.drop_relation('`project`.`dataset`.`other`') %}SELECT 1")
+	assert.Equal(t, "SELECT 1", output)
+	assert.True(t, fileSystem.Model("target_model").IsDynamicSQL(), "drop_relation should mark the model to be recompiled at execution")
+}
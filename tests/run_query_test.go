@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamedListLookups(t *testing.T) {
+	assertCompileOutput(t, "1", `{{ table_row[0] }}`)
+	assertCompileOutput(t, "first", `{{ table_row['name'] }}`)
+	assertCompileOutput(t, "first", `{{ table_row.name }}`)
+	assertCompileOutput(t, "1,first,", `{% for value in table_row %}{{ value }},{% endfor %}`)
+
+	// Columns are found before the list's methods
+	assertCompileOutput(t, "3|3", `{{ order_row.items }}|{{ order_row['items'] }}`)
+}
+
+func TestRunQueryIsDeferredUntilExecution(t *testing.T) {
+	fileSystem, _, output := CompileFromRaw(t, `{% set results = run_query('SELECT 1') %}SELECT 1`)
+	assert.Equal(t, "SELECT 1", output)
+	assert.True(t, fileSystem.Model("target_model").IsDynamicSQL(), "run_query should mark the model to be recompiled at execution")
+}
+
+func TestStatementIsDeferredUntilExecution(t *testing.T) {
+	fileSystem, _, output := CompileFromRaw(
+		t,
+		`{% call statement('my_statement', fetch_result=True) %}SELECT 1{% endcall %}SELECT 2`,
+	)
+	assert.Equal(t, "SELECT 2", output)
+	assert.True(t, fileSystem.Model("target_model").IsDynamicSQL(), "statement should mark the model to be recompiled at execution")
+}
+
+func TestLoadResultOfUnknownStatement(t *testing.T) {
+	assertCompileOutput(t, "SELECT 1", `{{ load_result('missing') }}SELECT 1`)
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/compiler"
+	"ddbt/config"
+	"ddbt/fs"
+)
+
+func TestRefToSeed(t *testing.T) {
+	seedPath := filepath.Join(t.TempDir(), "my_seed.csv")
+	require.NoError(t, ioutil.WriteFile(seedPath, []byte("id,name\n1,a\n"), 0644))
+
+	fileSystem, err := fs.InMemoryFileSystem(
+		map[string]string{
+			"models/target_model.sql": "SELECT * FROM {{ ref('my_seed') }}",
+		},
+	)
+	require.NoError(t, err)
+
+	seed, err := fileSystem.AddSeedFile(seedPath)
+	require.NoError(t, err)
+
+	for _, file := range fileSystem.AllFiles() {
+		require.NoError(t, parseFile(file))
+	}
+
+	config.GlobalCfg = &config.Config{
+		Name: "Unit Test",
+		Target: &config.Target{
+			Name:      "unit_test",
+			ProjectID: "unit_test_project",
+			DataSet:   "unit_test_dataset",
+			Location:  "US",
+			Threads:   4,
+		},
+	}
+	gc, err := compiler.NewGlobalContext(config.GlobalCfg, fileSystem)
+	require.NoError(t, err)
+
+	model := fileSystem.Model("target_model")
+	require.NoError(t, compiler.CompileModel(model, gc, false))
+
+	assert.Equal(t, "SELECT * FROM `unit_test_project`.`unit_test_dataset`.`my_seed`", model.CompiledContents)
+	assert.Equal(t, []*fs.File{seed.File}, model.Upstreams(), "the seed should be upstream of the model in the DAG")
+
+	graph := fs.NewGraph()
+	require.NoError(t, graph.AddNodeAndUpstreams(model))
+	assert.True(t, graph.Contains(seed.File))
+}
+
+func TestSeedHashChangesWithContents(t *testing.T) {
+	config.GlobalCfg = &config.Config{Target: &config.Target{}}
+
+	seedPath := filepath.Join(t.TempDir(), "my_seed.csv")
+	require.NoError(t, ioutil.WriteFile(seedPath, []byte("id,name\n1,a\n"), 0644))
+
+	fileSystem, err := fs.InMemoryFileSystem(map[string]string{})
+	require.NoError(t, err)
+	seed, err := fileSystem.AddSeedFile(seedPath)
+	require.NoError(t, err)
+
+	original, err := seed.Hash()
+	require.NoError(t, err)
+
+	again, err := seed.Hash()
+	require.NoError(t, err)
+	assert.Equal(t, original, again)
+
+	require.NoError(t, ioutil.WriteFile(seedPath, []byte("id,name\n1,b\n"), 0644))
+	changed, err := seed.Hash()
+	require.NoError(t, err)
+	assert.NotEqual(t, original, changed)
+}
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/compiler"
+)
+
+func TestCompileModelWithRefOverrides(t *testing.T) {
+	fileSystem, gc, original := CompileFromRaw(t, "SELECT * FROM {{ ref('target_model') }}")
+
+	model := fileSystem.Model("target_model")
+	require.NotNil(t, model)
+
+	file := model.VirtualCopy()
+	require.NoError(
+		t,
+		compiler.CompileModelWithRefOverrides(file, gc, true, map[string]string{"target_model": "__dbt__fixture__target_model"}),
+	)
+	assert.Equal(t, "SELECT * FROM __dbt__fixture__target_model", file.CompiledContents)
+
+	// The original model should be unaffected
+	assert.Equal(t, original, model.CompiledContents)
+
+	// Refs without an override should error
+	require.Error(t, compiler.CompileModelWithRefOverrides(model.VirtualCopy(), gc, true, map[string]string{}))
+}
+
+func TestCompileModelWithRefReplacements(t *testing.T) {
+	fileSystem, gc, _ := CompileFromRaw(t, "SELECT * FROM {{ ref('target_model') }}")
+
+	model := fileSystem.Model("target_model")
+	require.NotNil(t, model)
+
+	file := model.VirtualCopy()
+	require.NoError(
+		t,
+		compiler.CompileModelWithRefReplacements(file, gc, true, map[string]string{"target_model": "`project`.`dataset`.`target_model__dbt_tmp`"}),
+	)
+	assert.Equal(t, "SELECT * FROM `project`.`dataset`.`target_model__dbt_tmp`", file.CompiledContents)
+
+	// Refs without a replacement are resolved as normal
+	file = model.VirtualCopy()
+	require.NoError(t, compiler.CompileModelWithRefReplacements(file, gc, true, map[string]string{"other_model": "other"}))
+	assert.Equal(t, "SELECT * FROM `unit_test_project`.`unit_test_dataset`.`target_model`", file.CompiledContents)
+	assert.Empty(t, file.Upstreams(), "copies should not be recorded in the graph")
+}
@@ -31,6 +31,14 @@ var testVariables = map[string]*compilerInterface.Value{
 			"key": {StringValue: "42"},
 		},
 	},
+	"table_row": compilerInterface.NewNamedList(
+		[]string{"id", "name"},
+		[]*compilerInterface.Value{compilerInterface.NewNumber(1), compilerInterface.NewString("first")},
+	),
+	"order_row": compilerInterface.NewNamedList(
+		[]string{"id", "items"},
+		[]*compilerInterface.Value{compilerInterface.NewNumber(7), compilerInterface.NewNumber(3)},
+	),
 	"list_object": {
 		ListValue: []*compilerInterface.Value{
 			{StringValue: "first option is string"},
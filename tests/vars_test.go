@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ddbt/config"
+)
+
+func compileWithVars(t *testing.T, projectVars, cliVars map[string]interface{}, raw string) (string, error) {
+	model, err := compileTargetModelWithConfig(
+		t,
+		&config.Config{Vars: projectVars, CLIVars: cliVars},
+		map[string]string{"models/target_model.sql": raw},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return model.CompiledContents, nil
+}
+
+func TestVars(t *testing.T) {
+	projectVars := map[string]interface{}{
+		"start_date": "2021-01-01",
+		"end_date":   "2021-02-01",
+		"enabled":    true,
+		"countries":  []interface{}{"GB", "US"},
+		"limits":     map[interface{}]interface{}{"rows": 10},
+	}
+	cliVars := map[string]interface{}{"end_date": "2021-03-01"}
+
+	output, err := compileWithVars(t, projectVars, cliVars, "{{ var('start_date') }} {{ var('end_date') }} {{ var('enabled') }}")
+	require.NoError(t, err)
+	assert.Equal(t, "2021-01-01 2021-03-01 TRUE", output, "vars given on the command line should override the project's")
+
+	output, err = compileWithVars(t, projectVars, cliVars, "{% for c in var('countries') %}{{ c }},{% endfor %}{{ var('limits').rows }}")
+	require.NoError(t, err)
+	assert.Equal(t, "GB,US,10", output)
+}
+
+func TestVarDefaults(t *testing.T) {
+	output, err := compileWithVars(t, nil, nil, "{{ var('missing', 'default') }}|{{ var('missing', none) }}|{{ var('set', 'default') }}")
+	require.NoError(t, err)
+	assert.Equal(t, "default||default", output, "a default of none should render as nothing")
+
+	output, err = compileWithVars(t, map[string]interface{}{"set": 1}, nil, "{{ var('set', 'default') }}")
+	require.NoError(t, err)
+	assert.Equal(t, "1", output)
+}
+
+func TestMissingVarIsAnError(t *testing.T) {
+	_, err := compileWithVars(t, nil, nil, "{{ var('missing') }}")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Required var `missing` not found")
+}
+
+func TestHasVar(t *testing.T) {
+	output, err := compileWithVars(
+		t,
+		map[string]interface{}{"project_var": 1},
+		map[string]interface{}{"cli_var": 2},
+		"{{ var.has_var('project_var') }} {{ var.has_var('cli_var') }} {{ var.has_var('missing') }}",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "TRUE TRUE FALSE", output)
+}